@@ -0,0 +1,97 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// InvalidAddr is returned for address which can not be parsed as host:port.
+type InvalidAddr struct {
+	Addr   string
+	Reason string
+}
+
+func (ia InvalidAddr) Error() string {
+	return fmt.Sprintf("redigo: invalid address %q: %s", ia.Addr, ia.Reason)
+}
+
+// NormalizeAddr returns addr in canonical host:port form used for all
+// addresses library handles: surrounding spaces are trimmed, hostnames are
+// lowercased, IPv6 literals are bracketed and ports are validated.
+func NormalizeAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if err != nil {
+		return "", InvalidAddr{Addr: addr, Reason: "expected host:port"}
+	}
+	host, port, err = normalizeHostPort(host, port)
+	if err != nil {
+		return "", InvalidAddr{Addr: addr, Reason: err.(InvalidAddr).Reason}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// joinAddr returns canonical address of host and port reported by Redis
+// or Sentinel.
+func joinAddr(host, port string) (string, error) {
+	host, port, err := normalizeHostPort(host, port)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// normalizeHostPort validates host and port and returns them in canonical
+// form, host without brackets.
+func normalizeHostPort(host, port string) (string, string, error) {
+	orig := host + " " + port
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if host == "" {
+		return "", "", InvalidAddr{Addr: orig, Reason: "empty host"}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	} else if !validHostname(host) {
+		return "", "", InvalidAddr{Addr: orig, Reason: "invalid host"}
+	} else {
+		host = strings.ToLower(host)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(port), 10, 16)
+	if err != nil || n == 0 {
+		return "", "", InvalidAddr{Addr: orig, Reason: "invalid port"}
+	}
+	return host, strconv.FormatUint(n, 10), nil
+}
+
+// validHostname reports whether host consists of letters, digits, dots,
+// hyphens and underscores only.
+func validHostname(host string) bool {
+	for _, r := range host {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeAddrs returns addrs in canonical form. Invalid addresses are kept
+// as they are, Validate reports them.
+func normalizeAddrs(addrs []string) []string {
+	normalized := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if n, err := NormalizeAddr(addr); err == nil {
+			addr = n
+		}
+		if !stringInSlice(addr, normalized) {
+			normalized = append(normalized, addr)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,37 @@
+package sentinel
+
+import "testing"
+
+func TestNormalizeAddr(t *testing.T) {
+	for addr, want := range map[string]string{
+		" 10.0.0.1:6379 ":         "10.0.0.1:6379",
+		"Redis-0.Redis.svc:06379": "redis-0.redis.svc:6379",
+		"[fe80:0::2]:26379":       "[fe80::2]:26379",
+		"localhost:1":             "localhost:1",
+	} {
+		got, err := NormalizeAddr(addr)
+		if err != nil || got != want {
+			t.Fatalf("%q: got %q, %v, expected %q", addr, got, err, want)
+		}
+	}
+	for _, addr := range []string{"", "10.0.0.1", "10.0.0.1:0", "10.0.0.1:65536", ":6379", "host/x:6379", "1:2:3"} {
+		if _, err := NormalizeAddr(addr); err == nil {
+			t.Fatalf("expected error for %q", addr)
+		} else if _, ok := err.(InvalidAddr); !ok {
+			t.Fatalf("unexpected error type %T for %q", err, addr)
+		}
+	}
+}
+
+func TestNormalizeAddrs(t *testing.T) {
+	got := normalizeAddrs([]string{"S1:26379", "s1:26379", "garbage", "s2:26379"})
+	want := []string{"s1:26379", "garbage", "s2:26379"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected %v", got)
+		}
+	}
+}
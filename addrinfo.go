@@ -0,0 +1,43 @@
+package sentinel
+
+import "time"
+
+// MasterAddrInfo is an address pool dials together with metadata callers
+// can use to judge how stale it may be.
+type MasterAddrInfo struct {
+	Addr string
+	// ResolvedAt is when address was last adopted or confirmed.
+	ResolvedAt time.Time
+	// Source is an address of Sentinel address was learned from, empty if
+	// it is not known, e.g. when it was imported with WithTopology.
+	Source string
+	// Verified is true if instance on Addr answered ROLE with master,
+	// which happens in strict consistency mode and on health checks.
+	Verified bool
+	// VerifiedAt is when master role was last verified.
+	VerifiedAt time.Time
+	// Generation is incremented every time pool switches to another
+	// address, starting at 1 for initially resolved one.
+	Generation uint64
+}
+
+// MasterAddrInfo returns address pool currently dials with its freshness
+// metadata. Addr is empty until first Get when pool was created with
+// WithLazyInit.
+func (p *SentinelPool) MasterAddrInfo() MasterAddrInfo {
+	p.mu.RLock()
+	info := MasterAddrInfo{
+		Addr:       p.curAddr,
+		ResolvedAt: p.resolvedAt,
+		Source:     p.masterSource,
+		Generation: p.generation,
+	}
+	p.mu.RUnlock()
+	p.roles.mu.Lock()
+	if info.Addr != "" && p.roles.addr == info.Addr {
+		info.Verified = true
+		info.VerifiedAt = p.roles.at
+	}
+	p.roles.mu.Unlock()
+	return info
+}
@@ -0,0 +1,30 @@
+package sentinel
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMasterAddrInfo(t *testing.T) {
+	s := &Sentinel{MasterName: "mymaster"}
+	s._setMaster("10.0.0.2:6379", "127.0.0.1:26379")
+	sp := &SentinelPool{
+		sntl: s,
+		mu:   &sync.RWMutex{},
+		opts: poolOptions{topologyLogLevel: LogOff},
+	}
+	sp._adoptMaster("10.0.0.1:6379", "initial")
+	sp._adoptMaster("10.0.0.2:6379", "switch-master")
+
+	info := sp.MasterAddrInfo()
+	if info.Addr != "10.0.0.2:6379" || info.Source != "127.0.0.1:26379" ||
+		info.Generation != 2 || info.ResolvedAt.IsZero() || info.Verified {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	sp.roles.store("10.0.0.2:6379", time.Now())
+	if info := sp.MasterAddrInfo(); !info.Verified || info.VerifiedAt.IsZero() {
+		t.Fatalf("unexpected info %+v", info)
+	}
+}
@@ -0,0 +1,64 @@
+package sentinel
+
+import "time"
+
+// Backoff controls delays between attempts to subscribe to master switch
+// events again after they failed, growing exponentially with consecutive
+// failures.
+type Backoff struct {
+	// Initial is a delay after the first failure, a second if zero, so that
+	// failing subscriptions are never retried in a busy loop.
+	Initial time.Duration
+	// Max is a limit delay grows up to.
+	Max time.Duration
+	// Multiplier is a factor delay grows by with every further failure,
+	// values below 1 keep it constant.
+	Multiplier float64
+	// Jitter is a fraction of delay by which every pause is randomly
+	// lengthened or shortened, e.g. 0.1 for +/-10%.
+	Jitter float64
+}
+
+// defaultMonitorBackoff is used by master switch monitor unless
+// WithMonitorBackoff is given.
+var defaultMonitorBackoff = Backoff{
+	Initial:    monitorRetryDelay,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     monitorRetryJitter,
+}
+
+// delay returns delay after given number of consecutive failures, not
+// counting jitter.
+func (b Backoff) delay(failures int) time.Duration {
+	d := b.Initial
+	if d <= 0 {
+		d = monitorRetryDelay
+	}
+	for i := 1; i < failures && d < b.Max && b.Multiplier > 1; i++ {
+		d = time.Duration(float64(d) * b.Multiplier)
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// monitorBackoff returns Backoff pool was configured with.
+func (sp *SentinelPool) monitorBackoff() Backoff {
+	if sp.opts.monitorBackoff != nil {
+		return *sp.opts.monitorBackoff
+	}
+	return defaultMonitorBackoff
+}
+
+// monitorFailed reports failure to subscribe to master switch events and
+// waits before the next attempt.
+func (sp *SentinelPool) monitorFailed(err error, failures int) {
+	b := sp.monitorBackoff()
+	d := sp.jitter(b.delay(failures), b.Jitter)
+	if f := sp.opts.hooks.OnMonitorError; f != nil {
+		f(err, d)
+	}
+	sp.sleep(d)
+}
@@ -0,0 +1,111 @@
+package sentinel
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+	for failures, want := range []time.Duration{time.Second, time.Second, 2 * time.Second,
+		4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if d := b.delay(failures); d != want {
+			t.Fatalf("delay after %d failures is %v, expected %v", failures, d, want)
+		}
+	}
+	if d := (Backoff{Initial: time.Second}).delay(10); d != time.Second {
+		t.Fatalf("delay without multiplier is %v", d)
+	}
+	// zero initial delay must not make monitor spin
+	b = Backoff{Max: 5 * time.Second, Multiplier: 2}
+	for failures, want := range []time.Duration{time.Second, time.Second, 2 * time.Second,
+		4 * time.Second, 5 * time.Second} {
+		if d := b.delay(failures); d != want {
+			t.Fatalf("delay after %d failures without initial one is %v, expected %v", failures, d, want)
+		}
+	}
+}
+
+func TestMonitorBackoff(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	delays := make(chan time.Duration, 16)
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithLazyInit(),
+		WithMonitorBackoff(Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Multiplier: 2}),
+		WithHooks(Hooks{OnMonitorError: func(err error, retryIn time.Duration) {
+			select {
+			case delays <- retryIn:
+			default:
+			}
+		}}))
+	defer sp.Close()
+	sp.sntl.SubscribeDial = func(string) (redis.Conn, error) {
+		return nil, errors.New("subscriptions are blocked")
+	}
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []time.Duration{time.Millisecond, 2 * time.Millisecond,
+		4 * time.Millisecond, 4 * time.Millisecond} {
+		select {
+		case d := <-delays:
+			if d != want {
+				t.Fatalf("unexpected delay %v, expected %v", d, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("monitor error was not reported")
+		}
+	}
+}
+
+// droppedConn is a subscription connection closed by peer right away.
+type droppedConn struct{ nopConn }
+
+func (droppedConn) Receive() (interface{}, error) { return nil, io.EOF }
+
+func TestMonitorBackoffShortSubscription(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	delays := make(chan time.Duration, 16)
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithLazyInit(),
+		WithMonitorBackoff(Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Multiplier: 2}),
+		WithHooks(Hooks{OnMonitorError: func(err error, retryIn time.Duration) {
+			select {
+			case delays <- retryIn:
+			default:
+			}
+		}}))
+	defer sp.Close()
+	sp.sntl.SubscribeDial = func(string) (redis.Conn, error) {
+		return droppedConn{}, nil
+	}
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	// subscriptions succeed, but are lost right away
+	for _, want := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond} {
+		select {
+		case d := <-delays:
+			if d != want {
+				t.Fatalf("unexpected delay %v, expected %v", d, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("lost subscription was not reported")
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+)
+
+// MonitorSeed is a master Sentinels are asked to monitor when they do not
+// know it yet.
+type MonitorSeed struct {
+	// Addr is an address of initial master.
+	Addr string
+	// Quorum is a number of Sentinels which need to agree master is down.
+	Quorum int
+}
+
+// Monitor issues SENTINEL MONITOR for MasterName with seed address and quorum
+// on every known Sentinel which does not monitor MasterName yet, so that
+// self-provisioned environments converge without manual steps. Sentinels
+// already monitoring MasterName are left untouched. Last error is returned
+// if some Sentinel could not be checked or configured. Remaining Sentinels
+// are not tried once ctx is done.
+func (s *Sentinel) Monitor(ctx context.Context, seed MonitorSeed) error {
+	addr, err := NormalizeAddr(seed.Addr)
+	if err != nil {
+		return err
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	masterName := s.masterName()
+	s.mu.RLock()
+	addrs := s.Addrs
+	s.mu.RUnlock()
+	if len(addrs) == 0 {
+		return NoSentinelsAvailable{}
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := s.getContext(ctx, addr)
+		if err != nil {
+			return err
+		}
+		_, err = queryForMasterState(conn, masterName)
+		if err != nil && isUnknownMaster(err) {
+			_, err = conn.Do("SENTINEL", "MONITOR", masterName, host, port, seed.Quorum)
+		}
+		conn.Close()
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,97 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelMonitor(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	// sentinels which know master under another name only
+	unknown, err := sentineltest.NewSentinel("other", cluster.Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+	down, err := sentineltest.NewSentinel("other", cluster.Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	down.Close()
+
+	host, port, _ := net.SplitHostPort(cluster.Master.Addr())
+	seed := MonitorSeed{Addr: cluster.Master.Addr(), Quorum: 2}
+	expected := []string{"mymaster " + host + " " + port + " 2"}
+
+	// master unknown to some sentinels, which are asked to monitor it
+	s := NewSentinel([]string{cluster.Sentinels[0].Addr(), unknown.Addr()}, "mymaster")
+	defer s.Close()
+	if err := s.Monitor(context.Background(), seed); err != nil {
+		t.Fatal(err)
+	}
+	if got := unknown.Monitored(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected monitor commands %v", got)
+	}
+	// already monitored everywhere, sentinels are left untouched
+	if err := s.Monitor(context.Background(), seed); err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Sentinels[0].Monitored()) != 0 || len(unknown.Monitored()) != 1 {
+		t.Fatalf("monitored master was monitored again: %v, %v",
+			cluster.Sentinels[0].Monitored(), unknown.Monitored())
+	}
+
+	// sentinel which cannot be reached does not stop others from being
+	// configured, but its error is returned
+	partial, err := sentineltest.NewSentinel("other", cluster.Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer partial.Close()
+	s2 := NewSentinel([]string{down.Addr(), partial.Addr()}, "mymaster")
+	defer s2.Close()
+	if err := s2.Monitor(context.Background(), seed); err == nil {
+		t.Fatal("expected error of unreachable sentinel")
+	}
+	if got := partial.Monitored(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected monitor commands after partial failure %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s2.Monitor(ctx, seed); err != context.Canceled {
+		t.Fatalf("expected canceled context error, got %v", err)
+	}
+}
+
+func TestSentinelBootstrap(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	unknown, err := sentineltest.NewSentinel("other", cluster.Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+
+	s := NewSentinel([]string{unknown.Addr()}, "mymaster")
+	defer s.Close()
+	if _, err := s.MasterAddr(); err == nil {
+		t.Fatal("expected error of unknown master without bootstrap")
+	}
+	s.Bootstrap = &MonitorSeed{Addr: cluster.Master.Addr(), Quorum: 1}
+	addr, err := s.MasterAddr()
+	if err != nil || addr != cluster.Master.Addr() {
+		t.Fatalf("unexpected master %q after bootstrap, %v", addr, err)
+	}
+}
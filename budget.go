@@ -0,0 +1,59 @@
+package sentinel
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDialBudgetExhausted is returned instead of dialing master when too many
+// dial attempts failed recently, see WithDialBudget.
+var ErrDialBudgetExhausted = errors.New("redigo: master dial budget exhausted")
+
+// tokenBucket limits rate of failed dial attempts. Every failure takes a
+// token and no attempts are allowed while bucket is empty.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perSecond float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// refill adds tokens accumulated since last refill.
+// Lock must be held by caller.
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// allow reports whether another attempt may be made at now.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= 1
+}
+
+// take records failed attempt at now.
+func (b *tokenBucket) take(now time.Time) {
+	b.mu.Lock()
+	b.refill(now)
+	if b.tokens > 0 {
+		b.tokens--
+	}
+	b.mu.Unlock()
+}
@@ -0,0 +1,24 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, 2)
+
+	for i := 0; i < 2; i++ {
+		if !b.allow(now) {
+			t.Fatalf("attempt %d should be allowed", i)
+		}
+		b.take(now)
+	}
+	if b.allow(now) {
+		t.Fatal("attempt should be refused with empty bucket")
+	}
+	if !b.allow(now.Add(500 * time.Millisecond)) {
+		t.Fatal("attempt should be allowed after refill")
+	}
+}
@@ -0,0 +1,93 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// defaultCatchupInterval is how often WaitForReplica polls replica when
+// interval is not given.
+const defaultCatchupInterval = 100 * time.Millisecond
+
+// ReplicaBehind is returned by WaitForReplica when replica did not reach
+// target replication offset before context was done.
+type ReplicaBehind struct {
+	Addr string
+	// Offset is the last offset replica reported.
+	Offset int64
+	Target int64
+	// Err is the reason waiting stopped, usually ctx.Err().
+	Err error
+}
+
+func (rb ReplicaBehind) Error() string {
+	return fmt.Sprintf("redigo: replica %s at offset %d has not reached %d: %v",
+		rb.Addr, rb.Offset, rb.Target, rb.Err)
+}
+
+func (rb ReplicaBehind) Unwrap() error {
+	return rb.Err
+}
+
+// ReplicationOffset returns replication offset of instance c is connected
+// to, as reported by ROLE. Reading it over master connection right after a
+// write gives offset replica has to reach to have that write applied, see
+// WaitForReplica.
+func ReplicationOffset(c redis.Conn) (int64, error) {
+	res, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return 0, err
+	}
+	if len(res) == 0 {
+		return 0, errors.New("redigo: empty ROLE reply")
+	}
+	role, err := redis.String(res[0], nil)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case role == "master" && len(res) >= 2:
+		return redis.Int64(res[1], nil)
+	case role == "slave" && len(res) >= 5:
+		return redis.Int64(res[4], nil)
+	}
+	return 0, fmt.Errorf("redigo: ROLE reply of %s has no replication offset", role)
+}
+
+// WaitForReplica polls replica on addr every interval until its replication
+// offset reaches target, e.g. one ReplicationOffset returned on master after
+// a write, so that write can be read from replica or it can be promoted
+// without losing it. It gives up with ReplicaBehind once ctx is done.
+// Options are passed to redis.Dial, e.g. redis.DialPassword.
+func (s *Sentinel) WaitForReplica(ctx context.Context, addr string, target int64,
+	interval time.Duration, options ...redis.DialOption) error {
+	if interval <= 0 {
+		interval = defaultCatchupInterval
+	}
+	c, err := s.dialNodeAs(ctx, addr, roleReplica, options)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for {
+		offset, err := ReplicationOffset(c)
+		if err != nil {
+			return err
+		}
+		if offset >= target {
+			return nil
+		}
+		t := time.NewTimer(interval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ReplicaBehind{Addr: addr, Offset: offset, Target: target, Err: ctx.Err()}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestWaitForReplica(t *testing.T) {
+	master, err := sentineltest.NewRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+	replica, err := sentineltest.NewRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+	replica.ReplicaOf(master)
+	master.SetReplOffset(100)
+	replica.SetReplOffset(40)
+
+	mc, err := dialNodeContext(context.Background(), master.Addr(), FamilyAny, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := ReplicationOffset(mc)
+	mc.Close()
+	if err != nil || target != 100 {
+		t.Fatalf("unexpected master offset %d, %v", target, err)
+	}
+
+	s := &Sentinel{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	err = s.WaitForReplica(ctx, replica.Addr(), target, 10*time.Millisecond)
+	cancel()
+	var rb ReplicaBehind
+	if !errors.As(err, &rb) || rb.Offset != 40 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		replica.SetReplOffset(100)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.WaitForReplica(ctx, replica.Addr(), target, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+}
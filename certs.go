@@ -0,0 +1,77 @@
+package sentinel
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// clientCertConfig returns copy of config, or new one if it is nil, which
+// presents certificate returned by provider in every handshake.
+func clientCertConfig(config *tls.Config, provider func() (*tls.Certificate, error)) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return provider()
+	}
+	return config
+}
+
+// KeyPairReloader loads client certificate from PEM files and loads it
+// again once either file is modified, so rotated certificates are used for
+// new connections without restarting pool. Use its Certificate method with
+// WithClientCertificate.
+type KeyPairReloader struct {
+	// Logger receives reload failures, nothing is logged if it is nil.
+	Logger Logger
+
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+// NewKeyPairReloader loads certificate from certFile and keyFile.
+func NewKeyPairReloader(certFile, keyFile string) (*KeyPairReloader, error) {
+	r := &KeyPairReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Certificate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Certificate returns current certificate, loading it again if files were
+// modified since it was last loaded. If they can not be loaded, previous
+// certificate is kept.
+func (r *KeyPairReloader) Certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	certInfo, certErr := os.Stat(r.certFile)
+	keyInfo, keyErr := os.Stat(r.keyFile)
+	if certErr == nil && keyErr == nil && r.cert != nil &&
+		certInfo.ModTime().Equal(r.certTime) && keyInfo.ModTime().Equal(r.keyTime) {
+		return r.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert == nil {
+			return nil, err
+		}
+		logFields(r.Logger, LogWarn, "client certificate reload failed",
+			"cert", r.certFile, "key", r.keyFile, "err", err)
+		return r.cert, nil
+	}
+	r.cert = &cert
+	if certErr == nil && keyErr == nil {
+		r.certTime, r.keyTime = certInfo.ModTime(), keyInfo.ModTime()
+	}
+	logFields(r.Logger, LogInfo, "client certificate loaded", "cert", r.certFile)
+	return r.cert, nil
+}
@@ -0,0 +1,66 @@
+package sentinel
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes cert and its key as PEM files with modification time
+// set to mtime.
+func writeKeyPair(t *testing.T, cert tls.Certificate, certFile, keyFile string, mtime time.Time) {
+	t.Helper()
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+	for file, data := range map[string][]byte{certFile: certPEM, keyFile: keyPEM} {
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestKeyPairReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	first, second := selfSignedCert(t, "client-1"), selfSignedCert(t, "client-2")
+	now := time.Now()
+	writeKeyPair(t, first, certFile, keyFile, now.Add(-time.Hour))
+
+	r, err := NewKeyPairReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := clientCertConfig(nil, r.Certificate)
+	got, err := config.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil || !bytes.Equal(got.Certificate[0], first.Certificate[0]) {
+		t.Fatalf("unexpected certificate, %v", err)
+	}
+
+	writeKeyPair(t, second, certFile, keyFile, now)
+	if got, err = r.Certificate(); err != nil || !bytes.Equal(got.Certificate[0], second.Certificate[0]) {
+		t.Fatalf("rotated certificate was not loaded, %v", err)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = r.Certificate(); err != nil || !bytes.Equal(got.Certificate[0], second.Certificate[0]) {
+		t.Fatalf("previous certificate must be kept on reload failure, %v", err)
+	}
+	if _, err := NewKeyPairReloader(certFile, keyFile); err == nil {
+		t.Fatal("expected error for invalid key")
+	}
+}
@@ -0,0 +1,29 @@
+package sentinel
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// ClientFlags are CLIENT connection options issued right after connection
+// is established. They require Redis >= 7.2 (NO-TOUCH) or >= 7.0 (NO-EVICT).
+type ClientFlags struct {
+	// NoEvict protects connection from maxmemory-clients eviction.
+	NoEvict bool
+	// NoTouch makes commands sent over connection not alter keys LRU/LFU.
+	NoTouch bool
+}
+
+// apply issues configured CLIENT commands on c.
+func (f ClientFlags) apply(c redis.Conn) error {
+	if f.NoEvict {
+		if _, err := c.Do("CLIENT", "NO-EVICT", "ON"); err != nil {
+			return err
+		}
+	}
+	if f.NoTouch {
+		if _, err := c.Do("CLIENT", "NO-TOUCH", "ON"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package sentinel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolClientFlags(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	master, replica := cluster.Master, cluster.Replicas[0]
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff),
+		WithClientFlags(ClientFlags{NoEvict: true}),
+		WithReplicaClientFlags(ClientFlags{NoTouch: true}),
+		WithProbeClientFlags(ClientFlags{NoEvict: true, NoTouch: true}))
+	defer sp.Close()
+
+	expectClient := func(r *sentineltest.Redis, expected ...string) {
+		t.Helper()
+		if got := r.ClientCommands(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("unexpected CLIENT commands on %s: %v, expected %v", r.Addr(), got, expected)
+		}
+	}
+
+	conn := sp.Get()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	expectClient(master, "CLIENT NO-EVICT ON")
+
+	conn = sp.GetReplica()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	expectClient(replica, "CLIENT NO-TOUCH ON")
+
+	probe, err := sp.dialMaster(context.Background(), master.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe.Close()
+	expectClient(master, "CLIENT NO-EVICT ON", "CLIENT NO-EVICT ON", "CLIENT NO-TOUCH ON")
+
+	if _, err := sp.probeOffset(replica.Addr(), roleReplica); err != nil {
+		t.Fatal(err)
+	}
+	expectClient(replica, "CLIENT NO-TOUCH ON", "CLIENT NO-EVICT ON", "CLIENT NO-TOUCH ON")
+}
@@ -0,0 +1,64 @@
+package sentinel
+
+import "time"
+
+// Clock is a source of time for SentinelPool retries, health checks, rate
+// limiting and timestamps. It can be replaced with WithClock so time
+// dependent behaviour is tested deterministically.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event timer, see time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker delivers ticks at intervals, see time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is Clock backed by time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// clock returns Clock pool was configured with.
+func (sp *SentinelPool) clock() Clock {
+	if sp.opts.clock != nil {
+		return sp.opts.clock
+	}
+	return realClock{}
+}
+
+// sleep waits for d unless pool is closed meanwhile and reports whether
+// whole duration passed.
+func (sp *SentinelPool) sleep(d time.Duration) bool {
+	t := sp.clock().NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return true
+	case <-sp.done:
+		return false
+	}
+}
@@ -0,0 +1,126 @@
+package sentinel
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time moves only when Advance is called.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	created chan struct{}
+}
+
+type fakeTimer struct {
+	c       chan time.Time
+	at      time.Time
+	period  time.Duration
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0), created: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeTimer {
+	c.mu.Lock()
+	t := &fakeTimer{c: make(chan time.Time, 1), at: c.now.Add(d), period: period}
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+	c.created <- struct{}{}
+	return t
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer { return fakeTimerRef{c, c.add(d, 0)} }
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return fakeTickerRef{fakeTimerRef{c, c.add(d, d)}}
+}
+
+// Advance moves time forward by d, firing due timers and tickers.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		for !t.stopped && !t.at.After(c.now) {
+			select {
+			case t.c <- c.now:
+			default:
+			}
+			if t.period == 0 {
+				t.stopped = true
+			} else {
+				t.at = t.at.Add(t.period)
+			}
+		}
+	}
+}
+
+type fakeTimerRef struct {
+	clock *fakeClock
+	t     *fakeTimer
+}
+
+func (r fakeTimerRef) C() <-chan time.Time { return r.t.c }
+
+func (r fakeTimerRef) Stop() bool {
+	r.clock.mu.Lock()
+	defer r.clock.mu.Unlock()
+	active := !r.t.stopped
+	r.t.stopped = true
+	return active
+}
+
+type fakeTickerRef struct{ fakeTimerRef }
+
+func (r fakeTickerRef) Stop() { r.fakeTimerRef.Stop() }
+
+func TestRunGuardedRestartDelay(t *testing.T) {
+	clock := newFakeClock()
+	sp := &SentinelPool{
+		mu:   &sync.RWMutex{},
+		done: make(chan struct{}),
+		opts: poolOptions{
+			clock:         clock,
+			restartPolicy: &RestartPolicy{MaxRestarts: -1, Delay: time.Hour},
+		},
+	}
+	runs := make(chan int, 3)
+	n := 0
+	finished := make(chan struct{})
+	go func() {
+		sp._runGuarded("test", func() {
+			n++
+			runs <- n
+			if n < 3 {
+				panic("restart me")
+			}
+		})
+		close(finished)
+	}()
+
+	for want := 1; want <= 3; want++ {
+		if got := <-runs; got != want {
+			t.Fatalf("expected run %d, got %d", want, got)
+		}
+		if want < 3 {
+			<-clock.created
+			select {
+			case <-runs:
+				t.Fatal("restarted before delay passed")
+			default:
+			}
+			clock.Advance(time.Hour)
+		}
+	}
+	<-finished
+}
@@ -0,0 +1,194 @@
+// Command sentinel-demo runs SentinelPool against a Sentinel cluster, for
+// example one started with docker-compose.yml next to this file. It keeps
+// writing and reading keys from several workers, triggers failover of the
+// master on request and prints how long it took the pool to notice the
+// switch and to serve commands again.
+//
+//	docker-compose -f cmd/sentinel-demo/docker-compose.yml up -d
+//	go run ./cmd/sentinel-demo -sentinels 127.0.0.1:26379,127.0.0.1:26380,127.0.0.1:26381
+//
+// Press Enter to trigger failover, or use -kill-after to trigger it on timer.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	sentinelAddrs = flag.String("sentinels", "127.0.0.1:26379", "comma separated sentinel addresses")
+	masterName    = flag.String("master", "mymaster", "name of master group")
+	password      = flag.String("password", "", "master password")
+	workers       = flag.Int("workers", 8, "number of concurrent workers")
+	duration      = flag.Duration("duration", time.Minute, "how long to run")
+	killAfter     = flag.Duration("kill-after", 0, "trigger failover after this long, 0 waits for Enter")
+	killMethod    = flag.String("kill-method", "failover", "how to kill master: failover, sleep or shutdown")
+)
+
+// counters of worker commands
+type counters struct {
+	ok     uint64
+	failed uint64
+	// first and last failure since failover was triggered, unix nanoseconds
+	firstFail int64
+	lastFail  int64
+}
+
+func (c *counters) fail(now time.Time) {
+	atomic.AddUint64(&c.failed, 1)
+	atomic.CompareAndSwapInt64(&c.firstFail, 0, now.UnixNano())
+	atomic.StoreInt64(&c.lastFail, now.UnixNano())
+}
+
+func main() {
+	flag.Parse()
+	addrs := strings.Split(*sentinelAddrs, ",")
+
+	pool := sentinel.NewSentinelPool(addrs, *masterName, 0, *password,
+		sentinel.WithTopologyLogLevel(sentinel.LogInfo),
+		sentinel.WithStrictConsistency(time.Second),
+		sentinel.WithHealthCheck(time.Second),
+		sentinel.WithFailoverTracing(printTrace))
+	defer pool.Close()
+	pool.OnStateChange(func(t sentinel.StateTransition) {
+		log.Printf("pool state %s -> %s (%s)", t.From, t.To, t.Reason)
+	})
+	log.Printf("master %s", pool.MasterAddr())
+
+	var cnt counters
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			work(pool, id, &cnt, stop)
+		}(i)
+	}
+
+	changes := pool.MasterChanges()
+	killed := make(chan time.Time, 1)
+	go func() {
+		waitKill()
+		start := time.Now()
+		atomic.StoreInt64(&cnt.firstFail, 0)
+		atomic.StoreInt64(&cnt.lastFail, 0)
+		if err := kill(pool, addrs); err != nil {
+			log.Printf("kill master: %v", err)
+			return
+		}
+		log.Printf("master %s killed with %s", pool.MasterAddr(), *killMethod)
+		killed <- start
+	}()
+
+	deadline := time.After(*duration)
+	var killedAt time.Time
+	for {
+		select {
+		case killedAt = <-killed:
+		case change := <-changes:
+			log.Printf("master switched %s -> %s (%s)", change.Old, change.New, change.Reason)
+			if !killedAt.IsZero() {
+				log.Printf("switch detected %v after kill", change.Time.Sub(killedAt))
+			}
+		case <-deadline:
+			close(stop)
+			wg.Wait()
+			report(&cnt, killedAt)
+			return
+		}
+	}
+}
+
+// work writes and reads keys until stop is closed.
+func work(pool *sentinel.SentinelPool, id int, cnt *counters, stop <-chan struct{}) {
+	key := fmt.Sprintf("sentinel-demo:%d", id)
+	for n := 0; ; n++ {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		_, err := sentinel.Do(pool, redis.String, "SET", key, n)
+		if err == nil {
+			_, err = sentinel.Do(pool, redis.Int, "GET", key)
+		}
+		if err != nil {
+			cnt.fail(time.Now())
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		atomic.AddUint64(&cnt.ok, 1)
+	}
+}
+
+// waitKill blocks until failover should be triggered.
+func waitKill() {
+	if *killAfter > 0 {
+		time.Sleep(*killAfter)
+		return
+	}
+	log.Print("press Enter to kill master")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+// kill makes master unavailable according to -kill-method.
+func kill(pool *sentinel.SentinelPool, addrs []string) error {
+	switch *killMethod {
+	case "failover":
+		c, err := redis.Dial("tcp", addrs[0])
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		_, err = c.Do("SENTINEL", "FAILOVER", *masterName)
+		return err
+	case "sleep", "shutdown":
+		c, err := redis.Dial("tcp", pool.MasterAddr(), redis.DialPassword(*password))
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		if *killMethod == "sleep" {
+			// keep master unresponsive longer than down-after-milliseconds
+			c.Send("DEBUG", "SLEEP", 30)
+			return c.Flush()
+		}
+		// connection is closed by server, so reply error is expected
+		c.Do("SHUTDOWN", "NOSAVE")
+		return nil
+	}
+	return fmt.Errorf("unknown kill method %q", *killMethod)
+}
+
+func printTrace(t sentinel.FailoverTrace) {
+	log.Printf("failover %s -> %s took %v", t.OldAddr, t.NewAddr, t.End.Sub(t.Start))
+	for _, span := range t.Spans {
+		log.Printf("  %-20s %v", span.Name, span.End.Sub(span.Start))
+	}
+}
+
+func report(cnt *counters, killedAt time.Time) {
+	fmt.Printf("commands ok: %d, failed: %d\n", atomic.LoadUint64(&cnt.ok), atomic.LoadUint64(&cnt.failed))
+	if killedAt.IsZero() {
+		return
+	}
+	first, last := atomic.LoadInt64(&cnt.firstFail), atomic.LoadInt64(&cnt.lastFail)
+	if first == 0 {
+		fmt.Println("no failed commands after kill")
+		return
+	}
+	fmt.Printf("first failure %v after kill\n", time.Unix(0, first).Sub(killedAt))
+	fmt.Printf("recovered %v after kill, unavailable for %v\n",
+		time.Unix(0, last).Sub(killedAt), time.Duration(last-first))
+}
@@ -0,0 +1,86 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrNoReplicas is returned when master has no replicas to connect to.
+var ErrNoReplicas = errors.New("redigo: no replicas available")
+
+// MasterConn resolves current master and dials it without any pool. Options
+// are passed to redis.Dial, so redis.DialPassword and redis.DialDatabase can
+// be used to AUTH and SELECT. Connection is verified to have master role.
+// It is meant for admin scripts and diagnostics; caller must close
+// returned connection.
+func (s *Sentinel) MasterConn(options ...redis.DialOption) (redis.Conn, error) {
+	addr, err := s.MasterAddr()
+	if err != nil {
+		return nil, err
+	}
+	return s.dialRole(addr, "master", options)
+}
+
+// ReplicaConn is like MasterConn but connects to first replica of current
+// master which accepts connection and has slave role.
+func (s *Sentinel) ReplicaConn(options ...redis.DialOption) (redis.Conn, error) {
+	addrs, err := s.SlaveAddrs()
+	if err != nil {
+		return nil, err
+	}
+	lastErr := ErrNoReplicas
+	for _, addr := range addrs {
+		c, err := s.dialRole(addr, "slave", options)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return c, nil
+	}
+	return nil, lastErr
+}
+
+// credentialsRole returns role passed to CredentialsFunc for instance
+// having ROLE reply role.
+func credentialsRole(role string) string {
+	if role == "slave" {
+		return roleReplica
+	}
+	return role
+}
+
+// dialNodeContext dials Redis instance on addr with default timeouts and
+// TLS if tlsConfig is not nil, which can be overridden by options.
+// Connection attempt is aborted once ctx is done.
+func dialNodeContext(ctx context.Context, addr string, family AddressFamily, tlsConfig *tls.Config, options []redis.DialOption) (redis.Conn, error) {
+	timeout := defaultTimeout * time.Second
+	options = append(append([]redis.DialOption{
+		redis.DialNetDial(netDialFunc(ctx, family, timeout)),
+		redis.DialReadTimeout(timeout),
+		redis.DialWriteTimeout(timeout),
+	}, tlsOptions(tlsConfig)...), options...)
+	return redis.Dial("tcp", addr, options...)
+}
+
+// dialRole dials addr and checks instance has expected role.
+func (s *Sentinel) dialRole(addr, expectedRole string, options []redis.DialOption) (redis.Conn, error) {
+	c, err := s.dialNodeAs(context.Background(), addr, credentialsRole(expectedRole), options)
+	if err != nil {
+		return nil, err
+	}
+	role, err := getRole(c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if role != expectedRole {
+		c.Close()
+		return nil, fmt.Errorf("redigo: %s has role %s, expected %s", addr, role, expectedRole)
+	}
+	return c, nil
+}
@@ -0,0 +1,80 @@
+package sentinel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelMasterConn(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.RequirePass("secret")
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	if _, err := s.MasterConn(); err == nil {
+		t.Fatal("expected error without password")
+	}
+	c, err := s.MasterConn(redis.DialPassword("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if v := cluster.Master.Get("key"); v != "value" {
+		t.Fatalf("write did not reach master, got %q", v)
+	}
+
+	// master sentinel reports was demoted behind its back
+	cluster.Master.ReplicaOf(cluster.Replicas[0])
+	if _, err := s.MasterConn(redis.DialPassword("secret")); err == nil ||
+		!strings.Contains(err.Error(), "expected master") {
+		t.Fatalf("expected role error, got %v", err)
+	}
+}
+
+func TestSentinelReplicaConn(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	// replica which is already promoted is skipped
+	cluster.Replicas[0].Promote()
+	c, err := s.ReplicaConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := getRole(c)
+	c.Close()
+	if err != nil || role != "slave" {
+		t.Fatalf("unexpected role %q, %v", role, err)
+	}
+
+	cluster.Replicas[1].Promote()
+	if _, err := s.ReplicaConn(); err == nil || !strings.Contains(err.Error(), "expected slave") {
+		t.Fatalf("expected role error, got %v", err)
+	}
+
+	single, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer single.Close()
+	s2 := NewSentinel(single.SentinelAddrs(), "mymaster")
+	defer s2.Close()
+	if _, err := s2.ReplicaConn(); err != ErrNoReplicas {
+		t.Fatalf("expected no replicas error, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package sentinel
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// getPooled gets connection from pool like pool.GetContext does. Redigo
+// panics when pool is closed while ctx is done and Get waits for vacant
+// connection, that is reported as ctx error instead.
+func getPooled(ctx context.Context, pool *redis.Pool) (c redis.Conn, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if ctx.Err() == nil {
+				panic(r)
+			}
+			c, err = errorConn{ctx.Err()}, ctx.Err()
+		}
+	}()
+	return pool.GetContext(ctx)
+}
+
+// getContext is like get, but fails once ctx is done. Commands sent over
+// returned connection are bounded by deadline of ctx.
+func (s *Sentinel) getContext(ctx context.Context, addr string) (redis.Conn, error) {
+	if ctx.Done() == nil {
+		return s.get(addr), nil
+	}
+	pool := s.poolForAddr(addr)
+	s.metricsFor(addr).countGet()
+	c, err := getPooled(ctx, pool)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// dial errors are reported by the first command, as with get
+		return c, nil
+	}
+	return contextConn{Conn: c, ctx: ctx}, nil
+}
+
+// closeContext returns context which is done once Sentinel is closed, for
+// dials made on behalf of Sentinel itself rather than a caller. Cancel must
+// be called once context is not needed.
+func (s *Sentinel) closeContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := s.closedChan()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// contextConn fails commands once ctx is done, aborting ones in progress.
+type contextConn struct {
+	redis.Conn
+	ctx context.Context
+}
+
+func (c contextConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.ctx.Done() == nil {
+		return c.Conn.Do(cmd, args...)
+	}
+	reply, err := redis.DoContext(c.Conn, c.ctx, cmd, args...)
+	if err != nil {
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if deadline, ok := c.ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			// read timed out just before ctx noticed deadline
+			return nil, context.DeadlineExceeded
+		}
+	}
+	return reply, err
+}
+
+// withContext returns c with commands aborted once ctx is done, e.g. to
+// bound handshake of connection being dialed, or c itself if it can not
+// abort them.
+func withContext(ctx context.Context, c redis.Conn) redis.Conn {
+	if _, ok := c.(redis.ConnWithContext); !ok || ctx.Done() == nil {
+		return c
+	}
+	return contextConn{Conn: c, ctx: ctx}
+}
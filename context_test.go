@@ -0,0 +1,175 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestMasterAddrContext(t *testing.T) {
+	// sentinel accepting connections but never replying
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	s := NewSentinel([]string{l.Addr().String()}, "mymaster")
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.MasterAddrContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("MasterAddrContext took %v", elapsed)
+	}
+	if node := s.Nodes()[0]; node.ConsecutiveFailures != 0 {
+		t.Fatalf("sentinel must not be blamed for deadline, got %+v", node)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := s.DiscoverContext(ctx); err != context.Canceled {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSentinelDialContext(t *testing.T) {
+	// sentinel accepting connections but never replying to AUTH
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	s := NewSentinel([]string{l.Addr().String()}, "mymaster")
+	s.SentinelPassword = "secret"
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := s.MasterAddrContext(ctx); err != context.Canceled {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dial took %v after ctx was cancelled", elapsed)
+	}
+}
+
+func TestSentinelPoolDialContext(t *testing.T) {
+	// master accepting connections but never replying to SELECT
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead.Close()
+
+	sp, err := NewSentinelPoolE([]string{dead.Addr().String()}, "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff),
+		WithTopology(Topology{MasterName: "mymaster", Master: l.Addr().String()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sp.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	c, err := sp.GetContext(ctx)
+	if err != context.Canceled || c.Err() != err {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dial took %v after ctx was cancelled", elapsed)
+	}
+}
+
+func TestSentinelPoolConnWithContext(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+
+	for _, c := range []redis.Conn{sp.Get(), sp.GetReplica()} {
+		if _, ok := c.(redis.ConnWithContext); !ok {
+			t.Fatalf("%T does not support context", c)
+		}
+		reply, err := redis.String(redis.DoContext(c, context.Background(), "PING"))
+		if err != nil || reply != "PONG" {
+			t.Fatalf("unexpected reply %q, %v", reply, err)
+		}
+		reply, err = redis.String(redis.DoWithTimeout(c, time.Second, "PING"))
+		if err != nil || reply != "PONG" {
+			t.Fatalf("unexpected reply %q, %v", reply, err)
+		}
+		c.Close()
+	}
+	if st := sp.Stats(); st.MasterCommands != 2 {
+		t.Fatalf("commands sent with context must be counted, got %d", st.MasterCommands)
+	}
+}
+
+func TestNewSentinelContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSentinelContext(ctx, []string{"127.0.0.1:26379"}, "mymaster")
+	cancel()
+	select {
+	case <-s.closedChan():
+	case <-time.After(time.Second):
+		t.Fatal("sentinel was not closed with context")
+	}
+
+	// closing sentinel first must not leak goroutine waiting for context
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		NewSentinelContext(context.Background(), []string{"127.0.0.1:26379"}, "mymaster").Close()
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
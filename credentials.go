@@ -0,0 +1,84 @@
+package sentinel
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const roleSentinel = "sentinel"
+
+// Credentials authenticate connection to Redis or Sentinel instance.
+// Username requires Redis 6 ACL and can be empty for password only AUTH.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialsFunc returns credentials for instance on addr having role
+// "master", "replica" or "sentinel", so deployments using distinct users or
+// passwords per role or per instance can be supported. Empty Credentials
+// mean no AUTH is sent.
+type CredentialsFunc func(addr, role string) Credentials
+
+// auth sends AUTH with credentials unless password is empty.
+func (cr Credentials) auth(c redis.Conn) error {
+	if cr.Password == "" {
+		return nil
+	}
+	var err error
+	if cr.Username != "" {
+		_, err = c.Do("AUTH", cr.Username, cr.Password)
+	} else {
+		_, err = c.Do("AUTH", cr.Password)
+	}
+	return err
+}
+
+// credentials returns credentials for instance on addr with role.
+func (s *Sentinel) credentials(addr, role string) Credentials {
+	var creds Credentials
+	if s.Credentials != nil {
+		creds = s.Credentials(addr, role)
+	}
+	if role == roleSentinel && creds.Password == "" {
+		creds = Credentials{Username: s.SentinelUsername, Password: s.SentinelPassword}
+	}
+	return creds
+}
+
+// credentials returns credentials for instance on addr with role, used as
+// Sentinel.Credentials of pool: ones returned by WithCredentials function
+// if it is set, or configured username and password for master and
+// replicas otherwise. Sentinels fall back to WithSentinelAuth ones.
+func (sp *SentinelPool) credentials(addr, role string) Credentials {
+	if sp.opts.credentials != nil {
+		return sp.opts.credentials(addr, role)
+	}
+	if role == roleSentinel {
+		return Credentials{}
+	}
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return Credentials{Username: sp.cfg.Username, Password: sp.cfg.Password}
+}
+
+// dialNodeAs dials Redis instance on addr like dialNodeContext,
+// authenticates with credentials for role, if any, and issues
+// NodeClientFlags.
+func (s *Sentinel) dialNodeAs(ctx context.Context, addr, role string, options []redis.DialOption) (redis.Conn, error) {
+	c, err := dialNodeContext(ctx, addr, s.Family, s.TLSConfig, options)
+	if err != nil {
+		return nil, err
+	}
+	hc := withContext(ctx, c)
+	if err := s.credentials(addr, role).auth(hc); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := s.NodeClientFlags.apply(hc); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,137 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NodeCheck is a result of checking single node by Diagnose.
+type NodeCheck struct {
+	// Addr is an address of node.
+	Addr string
+	// ExpectedRole is a role node should have: sentinel, master or slave.
+	ExpectedRole string
+	// Role is a role node reported, empty if it could not be queried.
+	Role string
+	// Latency is time it took to connect and query node.
+	Latency time.Duration
+	// Err is set if node is unreachable, rejected credentials or has
+	// unexpected role.
+	Err error
+}
+
+// OK reports whether node passed check.
+func (nc NodeCheck) OK() bool {
+	return nc.Err == nil
+}
+
+// DiagnosticReport describes reachability of every node in master group.
+type DiagnosticReport struct {
+	MasterName string
+	Time       time.Time
+	Sentinels  []NodeCheck
+	// Master is nil if master could not be resolved.
+	Master   *NodeCheck
+	Replicas []NodeCheck
+	// Err is set if master or replicas could not be resolved via sentinels.
+	Err error
+}
+
+// Healthy reports whether all nodes passed their checks.
+func (r *DiagnosticReport) Healthy() bool {
+	if r.Err != nil || r.Master == nil || !r.Master.OK() {
+		return false
+	}
+	for _, checks := range [][]NodeCheck{r.Sentinels, r.Replicas} {
+		for _, nc := range checks {
+			if !nc.OK() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Diagnose checks every known Sentinel, current master and its replicas:
+// it connects to each of them, verifies credentials passed in options (see
+// redis.DialPassword) and checks roles. Unlike other methods it reports all
+// problems found instead of stopping at the first one.
+func (s *Sentinel) Diagnose(ctx context.Context, options ...redis.DialOption) *DiagnosticReport {
+	report := &DiagnosticReport{
+		MasterName: s.masterName(),
+		Time:       time.Now(),
+	}
+	s.mu.RLock()
+	addrs := s.Addrs
+	s.mu.RUnlock()
+	for _, addr := range addrs {
+		report.Sentinels = append(report.Sentinels, s.checkSentinel(ctx, addr))
+	}
+
+	masterAddr, err := s.MasterAddr()
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	master := s.checkNode(ctx, masterAddr, "master", options)
+	report.Master = &master
+
+	// unhealthy replicas are checked too
+	slaves, err := s.SlavesContext(ctx)
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	for _, info := range slaves {
+		report.Replicas = append(report.Replicas, s.checkNode(ctx, info.Addr, "slave", options))
+	}
+	return report
+}
+
+// Diagnose checks sentinels, master and replicas of pool using pool
+// credentials, see Sentinel.Diagnose.
+func (p *SentinelPool) Diagnose(ctx context.Context) *DiagnosticReport {
+	return p.sntl.Diagnose(ctx, redis.DialDatabase(p.db))
+}
+
+// checkSentinel dials Sentinel on addr bypassing pool and checks with ROLE
+// it is a Sentinel, not e.g. Redis listening on its address.
+func (s *Sentinel) checkSentinel(ctx context.Context, addr string) NodeCheck {
+	nc := NodeCheck{Addr: addr, ExpectedRole: "sentinel"}
+	start := time.Now()
+	c, err := s.dial(ctx, addr)
+	if err != nil {
+		nc.Err = err
+		nc.Latency = time.Since(start)
+		return nc
+	}
+	defer c.Close()
+	nc.Role, nc.Err = getRole(withContext(ctx, c))
+	nc.Latency = time.Since(start)
+	if nc.Err == nil && nc.Role != nc.ExpectedRole {
+		nc.Err = fmt.Errorf("redigo: %s has role %s, expected %s", addr, nc.Role, nc.ExpectedRole)
+	}
+	return nc
+}
+
+// checkNode dials Redis instance on addr and checks its role.
+func (s *Sentinel) checkNode(ctx context.Context, addr, expectedRole string, options []redis.DialOption) NodeCheck {
+	nc := NodeCheck{Addr: addr, ExpectedRole: expectedRole}
+	start := time.Now()
+	c, err := s.dialNodeAs(ctx, addr, credentialsRole(expectedRole), options)
+	if err != nil {
+		nc.Err = err
+		nc.Latency = time.Since(start)
+		return nc
+	}
+	defer c.Close()
+	nc.Role, nc.Err = getRole(c)
+	nc.Latency = time.Since(start)
+	if nc.Err == nil && nc.Role != expectedRole {
+		nc.Err = fmt.Errorf("redigo: %s has role %s, expected %s", addr, nc.Role, expectedRole)
+	}
+	return nc
+}
@@ -0,0 +1,74 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// happyEyeballsDelay is how long connection attempt to the preferred
+// address family runs before attempt to the other family is raced with it.
+// It is the delay recommended by RFC 8305, shorter than the 300ms net.Dialer
+// uses by default.
+const happyEyeballsDelay = 250 * time.Millisecond
+
+// newDialer returns dialer used by default to connect to sentinels and Redis
+// instances. Racing IPv4 and IPv6 attempts when host resolves to both is
+// done by net.Dialer itself; only its fallback delay is set here.
+func newDialer(connectTimeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout:       connectTimeout,
+		KeepAlive:     5 * time.Minute,
+		FallbackDelay: happyEyeballsDelay,
+	}
+}
+
+// dialContext connects to Redis on addr like redis.DialTimeout does, but
+// connection attempt is also aborted once ctx is done. TLS is used if
+// tlsConfig is not nil. Extra options override default ones.
+func dialContext(ctx context.Context, addr string, family AddressFamily, tlsConfig *tls.Config,
+	connectTimeout, readTimeout, writeTimeout time.Duration, extra ...redis.DialOption) (redis.Conn, error) {
+	options := append([]redis.DialOption{
+		redis.DialNetDial(netDialFunc(ctx, family, connectTimeout)),
+		redis.DialReadTimeout(readTimeout),
+		redis.DialWriteTimeout(writeTimeout),
+	}, tlsOptions(tlsConfig)...)
+	return redis.Dial("tcp", addr, append(options, extra...)...)
+}
+
+// tlsOptions returns dial options enabling TLS with config, or none if it
+// is nil. Server name is set to host dialed unless config sets it.
+func tlsOptions(config *tls.Config) []redis.DialOption {
+	if config == nil {
+		return nil
+	}
+	return []redis.DialOption{redis.DialUseTLS(true), redis.DialTLSConfig(config)}
+}
+
+// dial connects to Sentinel on addr for queries using Dialer or
+// DialContext if set, or Dial otherwise.
+func (s *Sentinel) dial(ctx context.Context, addr string) (redis.Conn, error) {
+	var c redis.Conn
+	var err error
+	if s.Dialer != nil {
+		var dc Conn
+		if dc, err = s.Dialer.Dial(ctx, addr); err == nil {
+			c = unwrapConn(dc)
+		}
+	} else if s.DialContext != nil {
+		c, err = s.DialContext(ctx, addr)
+	} else {
+		c, err = s.Dial(addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.credentials(addr, roleSentinel).auth(withContext(ctx, c)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
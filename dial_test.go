@@ -0,0 +1,114 @@
+package sentinel
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// selfSignedCert returns certificate for name valid for an hour.
+func selfSignedCert(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestDialContextTLS(t *testing.T) {
+	serverNames := make(chan string, 1)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t, "redis.local")},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		// PING is sent as *1\r\n$4\r\nPING\r\n
+		for i := 0; i < 3; i++ {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+		}
+		c.Write([]byte("+PONG\r\n"))
+	}()
+
+	config := &tls.Config{ServerName: "redis.local", InsecureSkipVerify: true}
+	c, err := dialContext(context.Background(), l.Addr().String(), FamilyAny, config,
+		time.Second, time.Second, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if reply, err := c.Do("PING"); err != nil || reply != "PONG" {
+		t.Fatalf("unexpected reply %v, %v", reply, err)
+	}
+	if name := <-serverNames; name != "redis.local" {
+		t.Fatalf("unexpected SNI %q", name)
+	}
+}
+
+func TestSentinelPoolDialOptions(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	dialed := make(chan string, 8)
+	netDial := func(network, addr string) (net.Conn, error) {
+		dialed <- addr
+		return net.Dial(network, addr)
+	}
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithDialOptions(redis.DialNetDial(netDial)))
+	defer sp.Close()
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case addr := <-dialed:
+		if addr != cluster.Master.Addr() {
+			t.Fatalf("unexpected address %s dialed", addr)
+		}
+	default:
+		t.Fatal("master was not dialed with dial options")
+	}
+}
@@ -0,0 +1,41 @@
+package sentinel
+
+import (
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Do runs command on master connection taken from pool and converts reply
+// with conv, which can be any of redigo reply helpers:
+//
+//	name, err := sentinel.Do(pool, redis.String, "GET", "name")
+//	n, err := sentinel.Do(pool, redis.Int, "INCR", "counter")
+//	fields, err := sentinel.Do(pool, redis.StringMap, "HGETALL", "user:1")
+//
+// Command rejected with READONLY error, which means connection still points
+// to master demoted by failover, is retried once on a new connection, as
+// SentinelPool discards such connections instead of reusing them.
+func Do[T any](pool Pooler, conv func(interface{}, error) (T, error), cmd string, args ...interface{}) (T, error) {
+	return conv(do(pool, cmd, args...))
+}
+
+func do(pool Pooler, cmd string, args ...interface{}) (interface{}, error) {
+	var reply interface{}
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		c := pool.Get()
+		reply, err = c.Do(cmd, args...)
+		c.Close()
+		if !isReadOnly(err) {
+			break
+		}
+	}
+	return reply, err
+}
+
+// isReadOnly reports whether err is a reply of replica to write command.
+func isReadOnly(err error) bool {
+	rerr, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(rerr), "READONLY")
+}
@@ -0,0 +1,81 @@
+package sentinel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// replyConn replies to every command with next reply from replies.
+type replyConn struct {
+	nopConn
+	replies *[]interface{}
+}
+
+func (c replyConn) Do(string, ...interface{}) (interface{}, error) {
+	reply := (*c.replies)[0]
+	*c.replies = (*c.replies)[1:]
+	if err, ok := reply.(error); ok {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// fakePooler hands out connections replying from shared reply queue.
+type fakePooler struct {
+	replies []interface{}
+	gets    int
+}
+
+func (p *fakePooler) Get() redis.Conn {
+	p.gets++
+	return replyConn{replies: &p.replies}
+}
+func (p *fakePooler) GetContext(context.Context) (redis.Conn, error) { return p.Get(), nil }
+func (p *fakePooler) MasterAddr() string                             { return "10.0.0.1:6379" }
+func (p *fakePooler) Stats() PoolStats                               { return PoolStats{} }
+func (p *fakePooler) Close()                                         {}
+
+func TestDo(t *testing.T) {
+	pool := &fakePooler{replies: []interface{}{[]byte("value"), int64(42)}}
+	s, err := Do(pool, redis.String, "GET", "key")
+	if err != nil || s != "value" {
+		t.Fatalf("unexpected reply %q, %v", s, err)
+	}
+	n, err := Do(pool, redis.Int, "INCR", "counter")
+	if err != nil || n != 42 {
+		t.Fatalf("unexpected reply %d, %v", n, err)
+	}
+}
+
+func TestDoRetriesReadOnly(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+	waitWatching(t, sp)
+
+	// idle connection still points to master demoted below
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	promoted := cluster.Replicas[0]
+	cluster.Failover(promoted)
+	for deadline := time.Now().Add(5 * time.Second); sp.MasterAddr() != promoted.Addr(); {
+		if time.Now().After(deadline) {
+			t.Fatal("pool did not switch master")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s, err := Do(sp, redis.String, "SET", "key", "value")
+	if err != nil || s != "OK" {
+		t.Fatalf("expected retry on new connection, got %q, %v", s, err)
+	}
+}
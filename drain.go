@@ -0,0 +1,16 @@
+package sentinel
+
+// _drainPool replaces connection pool with an empty one and closes the old
+// one, so no connection to previous master is handed out again. Idle
+// connections are closed right away and borrowed ones when returned.
+// Lock must be held by caller.
+func (sp *SentinelPool) _drainPool() {
+	start := sp.clock().Now()
+	stale := sp.pool
+	sp.pool = sp._newPool()
+	idle, active := stale.IdleCount(), stale.ActiveCount()
+	stale.Close()
+	logFields(sp.logger(), LogDebug, "stale connections drained",
+		"master", sp.sntl.masterName(), "idle", idle, "borrowed", active-idle)
+	sp.tracer.span(SpanPoolInvalidate, start)
+}
@@ -0,0 +1,55 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolDrainOnSwitch(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithDrainOnSwitch())
+	defer sp.Close()
+	changes := sp.MasterChanges()
+	waitWatching(t, sp)
+
+	idle := sp.Get()
+	borrowed := sp.Get()
+	if _, err := idle.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := borrowed.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	idle.Close()
+	stale := sp.Pool()
+
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+	if sp.Pool() == stale {
+		t.Fatal("pool was not replaced on switch")
+	}
+	if n := stale.IdleCount(); n != 0 {
+		t.Fatalf("%d idle connections to old master left", n)
+	}
+	borrowed.Close()
+	if n := stale.ActiveCount(); n != 0 {
+		t.Fatalf("%d connections to old master left", n)
+	}
+	c := sp.Get()
+	defer c.Close()
+	if !TestRole(c, "master") {
+		t.Fatal("connection after switch is not to master")
+	}
+}
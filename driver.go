@@ -0,0 +1,192 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Conn is a connection to Sentinel for discovery queries, e.g. looking
+// master, replicas or sentinels up, small enough to be implemented with
+// Redis clients other than redigo. It does not cover pub/sub: subscriptions
+// to Sentinel events are always made with redigo, see Sentinel.SubscribeDial.
+// Replies must be converted to types redigo returns: []byte for bulk
+// strings, string for status replies, int64 for integers, []interface{}
+// for arrays, nil for nil replies and redis.Error for error replies.
+type Conn interface {
+	// Do sends command to Sentinel and returns its reply. It must give up
+	// once ctx is done.
+	Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error)
+	Close() error
+}
+
+// Dialer connects to Sentinel on addr for discovery queries. It is called
+// for new connections of Sentinel pools, which are authenticated and
+// configured by Sentinel.
+type Dialer interface {
+	Dial(ctx context.Context, addr string) (Conn, error)
+}
+
+// DialerFunc adapts function to Dialer.
+type DialerFunc func(ctx context.Context, addr string) (Conn, error)
+
+// Dial calls f.
+func (f DialerFunc) Dial(ctx context.Context, addr string) (Conn, error) {
+	return f(ctx, addr)
+}
+
+// RedigoDialer is Dialer connecting with redigo, the default backend Dial
+// and DialContext of Sentinel are built on.
+type RedigoDialer struct {
+	// Options are applied to every connection, e.g. timeouts.
+	Options []redis.DialOption
+}
+
+// Dial connects to Sentinel on addr.
+func (d RedigoDialer) Dial(ctx context.Context, addr string) (Conn, error) {
+	c, err := redis.DialContext(ctx, "tcp", addr, d.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return RedigoConn(c), nil
+}
+
+// RedigoConn adapts redigo connection to Conn.
+func RedigoConn(c redis.Conn) Conn {
+	return redigoConn{c}
+}
+
+type redigoConn struct {
+	c redis.Conn
+}
+
+func (c redigoConn) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if _, ok := c.c.(redis.ConnWithContext); ok {
+		return redis.DoContext(c.c, ctx, cmd, args...)
+	}
+	return c.c.Do(cmd, args...)
+}
+
+func (c redigoConn) Close() error {
+	return c.c.Close()
+}
+
+// errNoPending is returned by Receive of connection made with Dialer when
+// no command was sent, as Conn does not support pub/sub.
+var errNoPending = errors.New("redigo: Receive without pending command is not supported by Dialer connections")
+
+// pendingCommand is a command sent, but not executed yet.
+type pendingCommand struct {
+	cmd  string
+	args []interface{}
+}
+
+// driverConn adapts Conn made with Dialer to redis.Conn Sentinel pools
+// hold. Pipelining is emulated by executing sent commands one by one as
+// their replies are received.
+type driverConn struct {
+	conn    Conn
+	pending []pendingCommand
+	err     error
+}
+
+// unwrapConn returns redis.Conn c was made from with RedigoConn, or c
+// adapted to redis.Conn otherwise.
+func unwrapConn(c Conn) redis.Conn {
+	if rc, ok := c.(redigoConn); ok {
+		return rc.c
+	}
+	return &driverConn{conn: c}
+}
+
+func (c *driverConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.DoContext(context.Background(), cmd, args...)
+}
+
+func (c *driverConn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var reply interface{}
+	var err error
+	for len(c.pending) > 0 {
+		if reply, err = c.ReceiveContext(ctx); c.err != nil {
+			return nil, c.err
+		}
+	}
+	// empty command only flushes pending replies
+	if cmd == "" {
+		return reply, err
+	}
+	return c.exec(ctx, cmd, args)
+}
+
+func (c *driverConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.DoContext(ctx, cmd, args...)
+}
+
+// exec executes command, making connection unusable if it failed other
+// than with error reply.
+func (c *driverConn) exec(ctx context.Context, cmd string, args []interface{}) (interface{}, error) {
+	reply, err := c.conn.Do(ctx, cmd, args...)
+	if err != nil {
+		if _, ok := err.(redis.Error); !ok {
+			c.err = err
+		}
+	}
+	return reply, err
+}
+
+func (c *driverConn) Send(cmd string, args ...interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.pending = append(c.pending, pendingCommand{cmd, args})
+	return nil
+}
+
+func (c *driverConn) Flush() error {
+	return c.err
+}
+
+func (c *driverConn) Receive() (interface{}, error) {
+	return c.ReceiveContext(context.Background())
+}
+
+func (c *driverConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(c.pending) == 0 {
+		return nil, errNoPending
+	}
+	p := c.pending[0]
+	c.pending = c.pending[1:]
+	return c.exec(ctx, p.cmd, p.args)
+}
+
+func (c *driverConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.ReceiveContext(ctx)
+}
+
+func (c *driverConn) Err() error {
+	return c.err
+}
+
+func (c *driverConn) Close() error {
+	if c.err == nil {
+		c.err = errors.New("redigo: closed")
+	}
+	return c.conn.Close()
+}
+
+var (
+	_ redis.ConnWithTimeout = (*driverConn)(nil)
+	_ redis.ConnWithContext = (*driverConn)(nil)
+)
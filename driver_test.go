@@ -0,0 +1,196 @@
+package sentinel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// echoConn is Conn replying with command name, or with error reply to
+// command "ERR".
+type echoConn struct {
+	cmds   []string
+	closed bool
+}
+
+func (c *echoConn) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	c.cmds = append(c.cmds, cmd)
+	if cmd == "ERR" {
+		return nil, redis.Error("ERR failed")
+	}
+	return cmd, nil
+}
+
+func (c *echoConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestDriverConn(t *testing.T) {
+	ec := &echoConn{}
+	c := unwrapConn(ec)
+	c.Send("MULTI")
+	c.Send("ERR")
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := c.Receive(); reply != "MULTI" || err != nil {
+		t.Fatalf("unexpected reply %v, %v", reply, err)
+	}
+	if reply, err := c.Do("PING"); reply != "PING" || err != nil {
+		t.Fatalf("unexpected reply %v, %v", reply, err)
+	}
+	if len(ec.cmds) != 3 || ec.cmds[1] != "ERR" {
+		t.Fatalf("pending command was not executed before PING, got %v", ec.cmds)
+	}
+	if c.Err() != nil {
+		t.Fatalf("error reply must not break connection, got %v", c.Err())
+	}
+	if _, err := c.Receive(); err != errNoPending {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c.Send("EXEC")
+	if reply, err := c.Do(""); reply != "EXEC" || err != nil {
+		t.Fatalf("unexpected reply %v, %v", reply, err)
+	}
+	c.Close()
+	if !ec.closed || c.Err() == nil {
+		t.Fatal("connection was not closed")
+	}
+}
+
+// plainConn hides redigo connection behind Conn, like connection of
+// another client would be.
+type plainConn struct {
+	c redis.Conn
+}
+
+func (c plainConn) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoContext(c.c, ctx, cmd, args...)
+}
+
+func (c plainConn) Close() error {
+	return c.c.Close()
+}
+
+func TestSentinelDialer(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	var dials int32
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	s.Dialer = DialerFunc(func(ctx context.Context, addr string) (Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		c, err := RedigoDialer{}.Dial(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return plainConn{unwrapConn(c)}, nil
+	})
+	defer s.Close()
+	addr, err := s.MasterAddr()
+	if err != nil || addr != cluster.Master.Addr() {
+		t.Fatalf("unexpected master %q, %v", addr, err)
+	}
+	replicas, err := s.SlaveAddrs()
+	if err != nil || len(replicas) != 1 || replicas[0] != cluster.Replicas[0].Addr() {
+		t.Fatalf("unexpected replicas %v, %v", replicas, err)
+	}
+	if atomic.LoadInt32(&dials) == 0 {
+		t.Fatal("sentinel was not dialed with Dialer")
+	}
+}
+
+func TestSentinelDialerSubscribe(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	// without SubscribeDial connections pooled for Dialer must not be used
+	// for pub/sub
+	s := &Sentinel{
+		Addrs:      cluster.SentinelAddrs(),
+		MasterName: "mymaster",
+		Dialer: DialerFunc(func(ctx context.Context, addr string) (Conn, error) {
+			c, err := RedigoDialer{}.Dial(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			return plainConn{unwrapConn(c)}, nil
+		}),
+	}
+	defer s.Close()
+	ms, err := s.MasterSwitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	w, errc, _ := ms.WatchWithErrors()
+	// let sentinel process SUBSCRIBE before switch is published
+	time.Sleep(100 * time.Millisecond)
+	cluster.Failover(cluster.Replicas[0])
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case addr := <-w:
+			if addr == cluster.Master.Addr() {
+				return
+			}
+		case err := <-errc:
+			t.Fatalf("subscription failed: %v", err)
+		case <-timeout:
+			t.Fatal("master switch was not received")
+		}
+	}
+}
+
+func TestSentinelDialerClasses(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	var mu sync.Mutex
+	var dials []string
+	record := func(class string) {
+		mu.Lock()
+		dials = append(dials, class)
+		mu.Unlock()
+	}
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	s.Dialer = DialerFunc(func(ctx context.Context, addr string) (Conn, error) {
+		record("query")
+		return RedigoDialer{}.Dial(ctx, addr)
+	})
+	subscribe := s.SubscribeDialContext
+	s.SubscribeDialContext = func(ctx context.Context, addr string) (redis.Conn, error) {
+		record("subscribe")
+		return subscribe(ctx, addr)
+	}
+
+	if _, err := s.MasterAddr(); err != nil {
+		t.Fatal(err)
+	}
+	ms, err := s.MasterSwitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dials) != 2 || dials[0] != "query" || dials[1] != "subscribe" {
+		t.Fatalf("unexpected dials %v, expected query then subscribe", dials)
+	}
+}
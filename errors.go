@@ -0,0 +1,113 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// PoolExhausted is returned by SentinelPool.Get when pool reached its
+// MaxActive limit and no connection became available within wait timeout,
+// see WithWaitTimeout. It describes how saturated pool was, so callers can
+// decide whether to shed load.
+type PoolExhausted struct {
+	// Waited is how long Get waited for a connection.
+	Waited time.Duration
+	// ActiveCount is a number of connections allocated by pool.
+	ActiveCount int
+	// IdleCount is a number of idle connections in pool.
+	IdleCount int
+	// WaitQueue is a number of other Get calls waiting for a connection.
+	WaitQueue int
+	// DialFailureRate is a fraction of recent master dial attempts which
+	// failed, from 0 to 1.
+	DialFailureRate float64
+}
+
+func (pe PoolExhausted) Error() string {
+	return fmt.Sprintf("redigo: connection pool exhausted after waiting %s "+
+		"(active %d, idle %d, waiting %d, dial failures %.0f%%)",
+		pe.Waited, pe.ActiveCount, pe.IdleCount, pe.WaitQueue, pe.DialFailureRate*100)
+}
+
+// Unwrap makes errors.Is(err, redis.ErrPoolExhausted) hold for PoolExhausted.
+func (pe PoolExhausted) Unwrap() error {
+	return redis.ErrPoolExhausted
+}
+
+// errorConn is returned instead of connection which could not be obtained.
+type errorConn struct{ err error }
+
+func (ec errorConn) Do(string, ...interface{}) (interface{}, error) { return nil, ec.err }
+func (ec errorConn) DoWithTimeout(time.Duration, string, ...interface{}) (interface{}, error) {
+	return nil, ec.err
+}
+func (ec errorConn) Send(string, ...interface{}) error                     { return ec.err }
+func (ec errorConn) Err() error                                            { return ec.err }
+func (ec errorConn) Close() error                                          { return nil }
+func (ec errorConn) Flush() error                                          { return ec.err }
+func (ec errorConn) Receive() (interface{}, error)                         { return nil, ec.err }
+func (ec errorConn) ReceiveWithTimeout(time.Duration) (interface{}, error) { return nil, ec.err }
+func (ec errorConn) DoContext(context.Context, string, ...interface{}) (interface{}, error) {
+	return nil, ec.err
+}
+func (ec errorConn) ReceiveContext(context.Context) (interface{}, error) { return nil, ec.err }
+
+var (
+	_ redis.ConnWithTimeout = errorConn{}
+	_ redis.ConnWithContext = errorConn{}
+)
+
+// transientReplies are prefixes of Redis error replies which are expected to
+// go away on retry, mostly during failover.
+var transientReplies = []string{
+	"LOADING",
+	"READONLY",
+	"MASTERDOWN",
+	"TRYAGAIN",
+	"BUSY",
+}
+
+// IsTransient reports whether operation failed with err may succeed if
+// retried later, e.g. on network failures, timeouts, exhausted pool or while
+// failover is in progress. Errors caused by configuration, like failed
+// authentication or unknown master name, are permanent.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ns NoSentinelsAvailable
+	if errors.As(err, &ns) {
+		return ns.lastError == nil || IsTransient(ns.lastError)
+	}
+	var pe PoolExhausted
+	if errors.As(err, &pe) {
+		return true
+	}
+	switch {
+	case errors.Is(err, ErrUnknownMaster), errors.Is(err, ErrPoolClosed),
+		errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, redis.ErrPoolExhausted), errors.Is(err, ErrDialBudgetExhausted),
+		errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		for _, prefix := range transientReplies {
+			if strings.HasPrefix(string(rerr), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr)
+}
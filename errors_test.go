@@ -0,0 +1,35 @@
+package sentinel
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{redis.ErrPoolExhausted, true},
+		{PoolExhausted{}, true},
+		{ErrDialBudgetExhausted, true},
+		{redis.Error("LOADING Redis is loading the dataset in memory"), true},
+		{redis.Error("READONLY You can't write against a read only replica."), true},
+		{redis.Error("WRONGPASS invalid username-password pair"), false},
+		{redis.Error("NOAUTH Authentication required."), false},
+		{ErrUnknownMaster, false},
+		{NoSentinelsAvailable{lastError: ErrUnknownMaster}, false},
+		{NoSentinelsAvailable{lastError: &net.OpError{Op: "dial", Err: errors.New("timeout")}}, true},
+		{ErrPoolClosed, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.transient {
+			t.Errorf("IsTransient(%v) = %v, expected %v", tt.err, got, tt.transient)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Event is a message Sentinel published on one of its event channels.
+type Event struct {
+	// Channel is a name of event channel, e.g. +switch-master.
+	Channel string
+	// Payload is a raw event message.
+	Payload string
+	// Source is an address of Sentinel event was received from.
+	Source string
+	// Time is when event was received.
+	Time time.Time
+	// Generation is a number of master address changes observed by
+	// Sentinel, set for +switch-master events only.
+	Generation uint64
+	// Replay is true for synthetic +switch-master event carrying last known
+	// master address, which is delivered first to new subscribers.
+	Replay bool
+}
+
+// eventChannels returns channels to subscribe to for events.
+func (s *Sentinel) eventChannels() []string {
+	if len(s.EventChannels) == 0 {
+		return []string{ChannelSwitchMaster}
+	}
+	return s.EventChannels
+}
+
+// eventBufferSize is how many undelivered events are kept for each Events
+// subscriber before new ones are dropped.
+const eventBufferSize = 64
+
+// MasterSwitchEvent is a switch of master delivered by WatchSwitches and
+// WatchMaster.
+type MasterSwitchEvent struct {
+	MasterName string
+	OldAddr    string
+	NewAddr    string
+	// Source is an address of Sentinel event was received from.
+	Source string
+	// Time is when event was received.
+	Time time.Time
+	// Generation is a number of master address changes observed by
+	// Sentinel, see Event.
+	Generation uint64
+	// Replay is true for synthetic event carrying last known master
+	// address, which is delivered first to new subscribers. Both OldAddr
+	// and NewAddr are set to that address.
+	Replay bool
+}
+
+// watchSub is a Watch, WatchSwitches or WatchMaster subscriber of switches
+// of master with masterName, only one of channels is set.
+type watchSub struct {
+	masterName string
+	ch         chan string
+	switches   chan MasterSwitchEvent
+	dropped    uint64
+}
+
+// pending returns number of items waiting in subscriber's buffer.
+func (sub *watchSub) pending() int {
+	if sub.switches != nil {
+		return len(sub.switches)
+	}
+	return len(sub.ch)
+}
+
+// close closes subscriber's channel.
+func (sub *watchSub) close() {
+	if sub.switches != nil {
+		close(sub.switches)
+	} else {
+		close(sub.ch)
+	}
+}
+
+// deliverLatest sends v to ch with room for single item, replacing
+// undelivered one if there is one, and reports whether it was replaced.
+// Caller must be the only sender.
+func deliverLatest[T any](ch chan T, v T) (replaced bool) {
+	select {
+	case ch <- v:
+		return false
+	default:
+	}
+	select {
+	case <-ch:
+		replaced = true
+	default:
+	}
+	ch <- v
+	return replaced
+}
+
+// eventSub is an Events subscriber.
+type eventSub struct {
+	ch      chan Event
+	dropped uint64
+}
+
+// SubscriberStats contains delivery counters of a single subscriber.
+type SubscriberStats struct {
+	// Kind is "watch" for Watch subscribers and "events" for Events ones.
+	Kind string
+	// Pending is a number of items waiting in subscriber's buffer.
+	Pending int
+	// Dropped is a number of items discarded because subscriber did not
+	// keep up.
+	Dropped uint64
+}
+
+// Events returns channel receiving every event published on subscribed
+// channels, see Sentinel.EventChannels, or on any channel in Firehose mode,
+// raw as it was received. Up to 64 events are buffered for slow receiver,
+// further ones are dropped and counted in Stats. Channel is closed when
+// subscription is closed or fails.
+func (ms *MasterSentinel) Events() (<-chan Event, error) {
+	ch := make(chan Event, eventBufferSize)
+	if ev, ok := ms.replayEvent(); ok {
+		ch <- ev
+	}
+	ms.subMu.Lock()
+	if ms.finished {
+		close(ch)
+	} else {
+		ms.eventSubs = append(ms.eventSubs, &eventSub{ch: ch})
+	}
+	ms.subMu.Unlock()
+	ms.start()
+	return ch, nil
+}
+
+// InstanceEventChannels are Sentinel event channels reporting state of
+// master, its replicas and sentinels, which let applications react to
+// degradation before master is switched. Add them to EventChannels of
+// Sentinel to receive them with InstanceEvents.
+var InstanceEventChannels = []string{
+	ChannelSDown,
+	ChannelSDownCleared,
+	ChannelODown,
+	ChannelODownCleared,
+	ChannelSlave,
+	ChannelSentinel,
+	ChannelResetMaster,
+}
+
+// nonInstanceChannels are event channels which payload is not formatted as
+// InstanceEvent.
+var nonInstanceChannels = map[string]bool{
+	ChannelSwitchMaster:  true,
+	ChannelNewEpoch:      true,
+	ChannelVoteForLeader: true,
+	ChannelTilt:          true,
+	ChannelTiltCleared:   true,
+}
+
+// SentinelEvent is an event about master, one of its replicas or sentinels
+// delivered by InstanceEvents.
+type SentinelEvent struct {
+	// Channel is a name of event channel, e.g. +odown.
+	Channel string
+	InstanceEvent
+	// Source is an address of Sentinel event was received from.
+	Source string
+	// Time is when event was received.
+	Time time.Time
+}
+
+// InstanceEvents returns channel receiving events about master
+// subscription is for, its replicas and sentinels, with payload parsed.
+// Only channels subscription listens on are received, see
+// InstanceEventChannels. Events with malformed payload are skipped. Events
+// are buffered and dropped for slow receiver like with Events. Channel is
+// closed when subscription is closed or fails.
+func (ms *MasterSentinel) InstanceEvents() (<-chan SentinelEvent, error) {
+	events, err := ms.Events()
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan SentinelEvent, eventBufferSize)
+	go func() {
+		defer close(ch)
+		for ev := range events {
+			if nonInstanceChannels[ev.Channel] {
+				continue
+			}
+			e, err := ParseInstanceEvent(ev.Payload)
+			if err != nil || e.MasterName != ms.masterName {
+				continue
+			}
+			select {
+			case ch <- SentinelEvent{Channel: ev.Channel, InstanceEvent: e, Source: ev.Source, Time: ev.Time}:
+			default:
+				atomic.AddUint64(&ms.dropped, 1)
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// start starts receiving events unless it is already started or
+// subscription is closed.
+func (ms *MasterSentinel) start() {
+	ms.subMu.Lock()
+	defer ms.subMu.Unlock()
+	if ms.started || ms.finished {
+		return
+	}
+	ms.started = true
+	go ms.receive()
+}
+
+// receive reads subscription and dispatches events until it fails or is
+// unsubscribed.
+func (ms *MasterSentinel) receive() {
+	var err error
+	defer func() {
+		if v := recover(); v != nil {
+			reportPanic(ms.logger(), ms.onPanic, "watcher", v)
+			err = fmt.Errorf("redigo: event receive panic: %v", v)
+		}
+		ms.finish(err)
+		close(ms.watchExit)
+	}()
+	receive := ms.pubsub.Receive
+	if _, ok := ms.pubsub.Conn.(redis.ConnWithTimeout); ok && ms.keepalive > 0 {
+		go ms.ping()
+		// every PING is answered, so silence means connection is dead
+		receive = func() interface{} {
+			return ms.pubsub.ReceiveWithTimeout(2 * ms.keepalive)
+		}
+	}
+	for {
+		switch reply := receive().(type) {
+		case redis.Message:
+			ms.dispatch(Event{
+				Channel: reply.Channel,
+				Payload: string(reply.Data),
+				Source:  ms.source,
+				Time:    time.Now(),
+			})
+		case error:
+			logFields(ms.logger(), LogError, "event channel receive failed",
+				"master", ms.masterName, "sentinel", ms.source, "err", reply)
+			err = reply
+			return
+		case redis.Subscription:
+			if (reply.Kind == "unsubscribe" || reply.Kind == "punsubscribe") &&
+				reply.Count == 0 {
+				logFields(ms.logger(), LogDebug, "event channels unsubscribed",
+					"master", ms.masterName, "sentinel", ms.source)
+				return
+			}
+		}
+	}
+}
+
+// ping sends PING over subscription at keepalive interval until receive
+// loop exits.
+func (ms *MasterSentinel) ping() {
+	ticker := time.NewTicker(ms.keepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ms.watchExit:
+			return
+		case <-ticker.C:
+		}
+		// Close unsubscribes over the same connection
+		ms.mu.Lock()
+		if ms.closed {
+			ms.mu.Unlock()
+			return
+		}
+		err := ms.pubsub.Ping("")
+		ms.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// dispatch delivers event to subscribers.
+func (ms *MasterSentinel) dispatch(ev Event) {
+	var switched *MasterSwitchEvent
+	if ev.Channel == ChannelSwitchMaster {
+		sw, err := ParseSwitchMaster(ev.Payload)
+		if err == nil {
+			if sw.MasterName == ms.masterName {
+				ev.Generation = ms.setMaster(sw.NewAddr())
+			}
+			switched = &MasterSwitchEvent{
+				MasterName: sw.MasterName,
+				OldAddr:    sw.OldAddr(),
+				NewAddr:    sw.NewAddr(),
+				Source:     ev.Source,
+				Time:       ev.Time,
+				Generation: ev.Generation,
+			}
+		}
+	}
+
+	ms.subMu.Lock()
+	watchers := ms.watchers
+	eventSubs := ms.eventSubs
+	ms.subMu.Unlock()
+
+	// receive loop is the only sender, so delivery never blocks on slow
+	// subscribers and drops are counted instead
+	for _, sub := range eventSubs {
+		select {
+		case sub.ch <- ev:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+			atomic.AddUint64(&ms.dropped, 1)
+		}
+	}
+	if switched == nil {
+		return
+	}
+	for _, sub := range watchers {
+		if sub.masterName != switched.MasterName {
+			continue
+		}
+		// replace undelivered switch with the latest one
+		var replaced bool
+		if sub.switches != nil {
+			replaced = deliverLatest(sub.switches, *switched)
+		} else {
+			replaced = deliverLatest(sub.ch, switched.NewAddr)
+		}
+		if replaced {
+			atomic.AddUint64(&sub.dropped, 1)
+			atomic.AddUint64(&ms.dropped, 1)
+		}
+	}
+}
+
+// logger returns logger of Sentinel subscription was made with.
+func (ms *MasterSentinel) logger() Logger {
+	if ms.sntl == nil {
+		return nil
+	}
+	return ms.sntl.Logger
+}
+
+// Stats returns delivery counters of active subscribers.
+func (ms *MasterSentinel) Stats() []SubscriberStats {
+	ms.subMu.Lock()
+	defer ms.subMu.Unlock()
+	st := make([]SubscriberStats, 0, len(ms.watchers)+len(ms.eventSubs))
+	for _, sub := range ms.watchers {
+		st = append(st, SubscriberStats{
+			Kind:    "watch",
+			Pending: sub.pending(),
+			Dropped: atomic.LoadUint64(&sub.dropped),
+		})
+	}
+	for _, sub := range ms.eventSubs {
+		st = append(st, SubscriberStats{
+			Kind:    "events",
+			Pending: len(sub.ch),
+			Dropped: atomic.LoadUint64(&sub.dropped),
+		})
+	}
+	return st
+}
+
+// Dropped returns total number of items discarded by all subscribers,
+// including already closed ones.
+func (ms *MasterSentinel) Dropped() uint64 {
+	return atomic.LoadUint64(&ms.dropped)
+}
+
+// currentMaster returns last known master address and its generation.
+func (ms *MasterSentinel) currentMaster() (string, uint64) {
+	if ms.sntl == nil {
+		return "", 0
+	}
+	ms.sntl.mu.RLock()
+	defer ms.sntl.mu.RUnlock()
+	if ms.sntl.MasterName != ms.masterName {
+		return "", 0
+	}
+	return ms.sntl.master, ms.sntl.generation
+}
+
+// setMaster records master address switched to and returns its generation.
+func (ms *MasterSentinel) setMaster(addr string) uint64 {
+	if ms.sntl == nil {
+		return 0
+	}
+	ms.sntl.mu.Lock()
+	defer ms.sntl.mu.Unlock()
+	if ms.sntl.MasterName != ms.masterName {
+		return 0
+	}
+	ms.sntl._setMaster(addr, ms.source)
+	return ms.sntl.generation
+}
+
+// replayEvent returns synthetic +switch-master event with last known master
+// address, if any.
+func (ms *MasterSentinel) replayEvent() (Event, bool) {
+	addr, generation := ms.currentMaster()
+	if addr == "" {
+		return Event{}, false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return Event{}, false
+	}
+	return Event{
+		Channel:    ChannelSwitchMaster,
+		Payload:    strings.Join([]string{ms.masterName, host, port, host, port}, " "),
+		Source:     ms.source,
+		Time:       time.Now(),
+		Generation: generation,
+		Replay:     true,
+	}, true
+}
+
+// _setMaster records current master address and Sentinel it was learned
+// from, source is empty if it is not known.
+// Lock must be held by caller.
+func (s *Sentinel) _setMaster(addr, source string) {
+	s.masterSource = source
+	if addr != s.master {
+		s.master = addr
+		s.generation++
+	}
+}
+
+// masterSourceOf returns address of Sentinel addr was learned from, if addr
+// is last known master.
+func (s *Sentinel) masterSourceOf(addr string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if addr != s.master {
+		return ""
+	}
+	return s.masterSource
+}
+
+// finish closes channels of all subscribers. Non-nil err is the reason
+// subscription failed and is delivered to error subscribers first.
+func (ms *MasterSentinel) finish(err error) {
+	ms.subMu.Lock()
+	defer ms.subMu.Unlock()
+	if ms.finished {
+		return
+	}
+	ms.finished = true
+	ms.err = err
+	for _, sub := range ms.watchers {
+		sub.close()
+	}
+	for _, sub := range ms.eventSubs {
+		close(sub.ch)
+	}
+	for _, ch := range ms.errSubs {
+		if err != nil {
+			ch <- err
+		}
+		close(ch)
+	}
+	ms.watchers = nil
+	ms.eventSubs = nil
+	ms.errSubs = nil
+}
+
+// Err returns an error subscription failed with. It is nil while
+// subscription is active and after it was closed with Close.
+func (ms *MasterSentinel) Err() error {
+	ms.subMu.Lock()
+	defer ms.subMu.Unlock()
+	return ms.err
+}
@@ -0,0 +1,307 @@
+package sentinel
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// pubsubConn is a redis.Conn replying to Receive with queued pub/sub
+// replies, failing once queue is closed.
+type pubsubConn struct {
+	nopConn
+	replies chan interface{}
+}
+
+func (c *pubsubConn) Receive() (interface{}, error) {
+	reply, ok := <-c.replies
+	if !ok {
+		return nil, errors.New("connection closed")
+	}
+	return reply, nil
+}
+
+func (c *pubsubConn) message(channel, payload string) {
+	c.replies <- []interface{}{[]byte("message"), []byte(channel), []byte(payload)}
+}
+
+func newTestMasterSentinel(conn *pubsubConn) *MasterSentinel {
+	ms := &MasterSentinel{
+		masterName: "mymaster",
+		source:     "127.0.0.1:26379",
+		mu:         &sync.Mutex{},
+		watchExit:  make(chan struct{}),
+	}
+	ms.pubsub.Conn = conn
+	return ms
+}
+
+func TestMasterSentinelWatch(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, 4)}
+	ms := newTestMasterSentinel(conn)
+	w, _ := ms.Watch()
+	events, _ := ms.Events()
+
+	conn.message("+sdown", "master mymaster 10.0.0.1 6379")
+	conn.message("+switch-master", "othermaster 10.0.0.5 6379 10.0.0.6 6379")
+	conn.message("+switch-master", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+
+	for _, channel := range []string{"+sdown", "+switch-master", "+switch-master"} {
+		ev := <-events
+		if ev.Channel != channel || ev.Source != "127.0.0.1:26379" {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	}
+	if addr := <-w; addr != "10.0.0.2:6379" {
+		t.Fatalf("unexpected master address %s", addr)
+	}
+
+	close(conn.replies)
+	if _, ok := <-w; ok {
+		t.Fatal("watch channel must be closed after receive error")
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("events channel must be closed after receive error")
+	}
+}
+
+func TestMasterSentinelFirehose(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, 2)}
+	ms := newTestMasterSentinel(conn)
+	ms.pattern = true
+	events, _ := ms.Events()
+
+	conn.replies <- []interface{}{[]byte("pmessage"), []byte("*"), []byte("+tilt"), []byte("#tilt")}
+	if ev := <-events; ev.Channel != "+tilt" || ev.Payload != "#tilt" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	conn.replies <- []interface{}{[]byte("punsubscribe"), []byte("*"), int64(0)}
+	if _, ok := <-events; ok {
+		t.Fatal("events channel must be closed after unsubscribe")
+	}
+}
+
+func TestMasterSentinelWatchWithErrors(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{})}
+	ms := newTestMasterSentinel(conn)
+	w, errc, _ := ms.WatchWithErrors()
+	close(conn.replies)
+	if _, ok := <-w; ok {
+		t.Fatal("watch channel must be closed after receive error")
+	}
+	if err := <-errc; err == nil || err != ms.Err() {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	conn = &pubsubConn{replies: make(chan interface{}, 1)}
+	ms = newTestMasterSentinel(conn)
+	_, errc, _ = ms.WatchWithErrors()
+	conn.replies <- []interface{}{[]byte("unsubscribe"), []byte("+switch-master"), int64(0)}
+	if err, ok := <-errc; ok {
+		t.Fatalf("unexpected error %v after unsubscribe", err)
+	}
+	if ms.Err() != nil {
+		t.Fatalf("unexpected error %v after unsubscribe", ms.Err())
+	}
+}
+
+func TestMasterSentinelReplay(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, 1)}
+	ms := newTestMasterSentinel(conn)
+	ms.sntl = &Sentinel{MasterName: "mymaster"}
+	ms.sntl._setMaster("10.0.0.1:6379", "127.0.0.1:26379")
+
+	w, _ := ms.Watch()
+	if addr := <-w; addr != "10.0.0.1:6379" {
+		t.Fatalf("unexpected replayed address %s", addr)
+	}
+	conn.message("+switch-master", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	if addr := <-w; addr != "10.0.0.2:6379" {
+		t.Fatalf("unexpected master address %s", addr)
+	}
+
+	events, _ := ms.Events()
+	ev := <-events
+	sw, err := ParseSwitchMaster(ev.Payload)
+	if !ev.Replay || ev.Generation != 2 || err != nil || sw.NewAddr() != "10.0.0.2:6379" {
+		t.Fatalf("unexpected replayed event %+v", ev)
+	}
+}
+
+func TestMasterSentinelWatchSwitches(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, 2)}
+	ms := newTestMasterSentinel(conn)
+	ms.sntl = &Sentinel{MasterName: "mymaster"}
+	ms.sntl._setMaster("10.0.0.1:6379", "127.0.0.1:26379")
+
+	switches, _ := ms.WatchSwitches()
+	if sw := <-switches; !sw.Replay || sw.OldAddr != "10.0.0.1:6379" || sw.NewAddr != "10.0.0.1:6379" {
+		t.Fatalf("unexpected replayed switch %+v", sw)
+	}
+	conn.message("+switch-master", "othermaster 10.0.0.5 6379 10.0.0.6 6379")
+	conn.message("+switch-master", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	sw := <-switches
+	if sw.Replay || sw.MasterName != "mymaster" || sw.OldAddr != "10.0.0.1:6379" ||
+		sw.NewAddr != "10.0.0.2:6379" || sw.Source != "127.0.0.1:26379" ||
+		sw.Generation != 2 || sw.Time.IsZero() {
+		t.Fatalf("unexpected switch %+v", sw)
+	}
+	close(conn.replies)
+	if _, ok := <-switches; ok {
+		t.Fatal("switches channel must be closed after receive error")
+	}
+}
+
+func TestMasterSentinelWatchMaster(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, 3)}
+	ms := newTestMasterSentinel(conn)
+	ms.sntl = &Sentinel{MasterName: "mymaster"}
+	ms.sntl._setMaster("10.0.0.1:6379", "127.0.0.1:26379")
+
+	own, _ := ms.WatchMaster("mymaster")
+	if sw := <-own; !sw.Replay || sw.NewAddr != "10.0.0.1:6379" {
+		t.Fatalf("unexpected replayed switch %+v", sw)
+	}
+	other, _ := ms.WatchMaster("othermaster")
+	conn.message("+switch-master", "othermaster 10.0.0.5 6379 10.0.0.6 6379")
+	conn.message("+switch-master", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	sw := <-other
+	if sw.Replay || sw.MasterName != "othermaster" || sw.OldAddr != "10.0.0.5:6379" ||
+		sw.NewAddr != "10.0.0.6:6379" || sw.Generation != 0 {
+		t.Fatalf("unexpected switch %+v", sw)
+	}
+	if sw := <-own; sw.MasterName != "mymaster" || sw.NewAddr != "10.0.0.2:6379" || sw.Generation != 2 {
+		t.Fatalf("unexpected switch %+v", sw)
+	}
+	if addr, _ := ms.currentMaster(); addr != "10.0.0.2:6379" {
+		t.Fatalf("switch of another master must not change master, got %s", addr)
+	}
+	close(conn.replies)
+	if _, ok := <-other; ok {
+		t.Fatal("switches channel must be closed after receive error")
+	}
+}
+
+func TestMasterSentinelDrops(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, eventBufferSize+2)}
+	ms := newTestMasterSentinel(conn)
+	w, _ := ms.Watch()
+	events, _ := ms.Events()
+
+	for i := 0; i < eventBufferSize; i++ {
+		conn.message("+sdown", "master mymaster 10.0.0.1 6379")
+	}
+	conn.message("+switch-master", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	conn.message("+switch-master", "mymaster 10.0.0.2 6379 10.0.0.3 6379")
+	close(conn.replies)
+	<-ms.watchExit
+
+	if addr := <-w; addr != "10.0.0.3:6379" {
+		t.Fatalf("expected latest address, got %s", addr)
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	if n != eventBufferSize || ms.Dropped() != 3 {
+		t.Fatalf("unexpected delivered %d, dropped %d", n, ms.Dropped())
+	}
+}
+
+func TestMasterSentinelInstanceEvents(t *testing.T) {
+	conn := &pubsubConn{replies: make(chan interface{}, 6)}
+	ms := newTestMasterSentinel(conn)
+	events, _ := ms.InstanceEvents()
+
+	conn.message("+sdown", "slave 10.0.0.2:6379 10.0.0.2 6379 @ mymaster 10.0.0.1 6379")
+	conn.message("+switch-master", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	conn.message("+odown", "master othermaster 10.0.0.5 6379 #quorum 2/2")
+	conn.message("+odown", "master mymaster")
+	conn.message("+odown", "master mymaster 10.0.0.1 6379 #quorum 2/2")
+	close(conn.replies)
+
+	var got []SentinelEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != 2 {
+		t.Fatalf("unexpected events %+v", got)
+	}
+	if got[0].Channel != "+sdown" || got[0].Type != "slave" || got[0].Addr() != "10.0.0.2:6379" ||
+		got[0].Source != "127.0.0.1:26379" {
+		t.Fatalf("unexpected event %+v", got[0])
+	}
+	if got[1].Channel != "+odown" || got[1].Type != "master" || got[1].Extra != "#quorum 2/2" {
+		t.Fatalf("unexpected event %+v", got[1])
+	}
+}
+
+func TestMasterSentinelKeepalive(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	s.SubscriptionKeepalive = 10 * time.Millisecond
+	defer s.Close()
+
+	ms, err := s.MasterSwitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	w, errc, _ := ms.WatchWithErrors()
+	// several keepalive periods pass without events
+	time.Sleep(100 * time.Millisecond)
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case addr := <-w:
+		if addr != cluster.Master.Addr() {
+			t.Fatalf("unexpected master address %s", addr)
+		}
+	case err := <-errc:
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not received")
+	}
+
+	// connection which accepts commands but never replies
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, c)
+		}
+	}()
+	s.SubscribeDial = func(string) (redis.Conn, error) {
+		return redis.Dial("tcp", l.Addr().String())
+	}
+	dead, err := s.MasterSwitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dead.Close()
+	_, errc, _ = dead.WatchWithErrors()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("expected timeout error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead subscription was not detected")
+	}
+}
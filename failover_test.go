@@ -0,0 +1,537 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelPoolFailover(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	// strict mode discards idle connections to former master
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithStrictConsistency(time.Second))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+
+	if _, err := Do(sp, redis.String, "SET", "key", "before"); err != nil {
+		t.Fatal(err)
+	}
+	if v := cluster.Master.Get("key"); v != "before" {
+		t.Fatalf("unexpected value on master %q", v)
+	}
+
+	oldMaster := cluster.Master
+	newMaster := cluster.Replicas[0]
+	cluster.Failover(newMaster)
+
+	select {
+	case change := <-changes:
+		if change.Old != oldMaster.Addr() || change.New != newMaster.Addr() {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+	if addr := sp.MasterAddr(); addr != newMaster.Addr() {
+		t.Fatalf("pool dials %s, expected %s", addr, newMaster.Addr())
+	}
+	if st := sp.Stats(); st.MasterSwitches != 1 || st.LastMasterSwitch.IsZero() {
+		t.Fatalf("unexpected switch stats %d, %v", st.MasterSwitches, st.LastMasterSwitch)
+	}
+
+	if _, err := Do(sp, redis.String, "SET", "key", "after"); err != nil {
+		t.Fatal(err)
+	}
+	if v := newMaster.Get("key"); v != "after" {
+		t.Fatalf("unexpected value on new master %q", v)
+	}
+}
+
+func TestSentinelPoolCredentials(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.RequirePass("master-secret")
+	cluster.Replicas[0].RequirePass("replica-secret")
+
+	creds := func(addr, role string) Credentials {
+		switch role {
+		case "master":
+			return Credentials{Password: "master-secret"}
+		case "replica":
+			return Credentials{Password: "replica-secret"}
+		}
+		return Credentials{}
+	}
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "wrong",
+		WithTopologyLogLevel(LogOff), WithCredentials(creds))
+	defer sp.Close()
+
+	if _, err := Do(sp, redis.String, "SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if report := sp.Diagnose(context.Background()); !report.Healthy() {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestDiagnoseSentinelRole(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	// Redis answers PING like Sentinel would
+	s := NewSentinel([]string{cluster.Sentinels[0].Addr(), cluster.Master.Addr()}, "mymaster")
+	defer s.Close()
+	report := s.Diagnose(context.Background())
+	if report.Healthy() {
+		t.Fatal("Redis listed as sentinel must fail diagnosis")
+	}
+	if nc := report.Sentinels[0]; !nc.OK() || nc.Role != "sentinel" {
+		t.Fatalf("unexpected sentinel check %+v", nc)
+	}
+	if nc := report.Sentinels[1]; nc.OK() || nc.Role != "master" {
+		t.Fatalf("unexpected check of Redis listed as sentinel %+v", nc)
+	}
+}
+
+func TestSentinelPoolWithPool(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	dials := 0
+	var built *redis.Pool
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff),
+		WithPool(func(dial func() (redis.Conn, error)) *redis.Pool {
+			built = &redis.Pool{
+				MaxIdle: 1,
+				Dial: func() (redis.Conn, error) {
+					dials++
+					return dial()
+				},
+			}
+			return built
+		}))
+	defer sp.Close()
+
+	if sp.Pool() != built {
+		t.Fatal("Pool must return pool built by factory")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := Do(sp, redis.String, "SET", "key", "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dials != 1 || built.IdleCount() != 1 {
+		t.Fatalf("unexpected %d dials, %d idle", dials, built.IdleCount())
+	}
+}
+
+func TestSentinelPoolGetReplica(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+
+	for i := 0; i < 4; i++ {
+		c := sp.GetReplica()
+		role, err := getRole(c)
+		c.Close()
+		if err != nil || role != "slave" {
+			t.Fatalf("unexpected role %q, %v", role, err)
+		}
+	}
+	st := sp.Stats()
+	if st.MasterCommands != 0 || st.ReplicaCommands != 4 ||
+		st.AddrCommands[cluster.Replicas[0].Addr()] != 2 ||
+		st.AddrCommands[cluster.Replicas[1].Addr()] != 2 {
+		t.Fatalf("replicas are not used round-robin: %+v", st)
+	}
+
+	oldMaster := cluster.Master
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+	for i := 0; i < 2; i++ {
+		c := sp.GetSlave()
+		role, err := getRole(c)
+		c.Close()
+		if err != nil || role != "slave" {
+			t.Fatalf("unexpected role %q after failover, %v", role, err)
+		}
+	}
+	if st := sp.Stats(); st.MasterCommands != 0 || st.AddrCommands[oldMaster.Addr()] != 1 {
+		t.Fatalf("replicas were not looked up after failover: %+v", st)
+	}
+}
+
+func TestSentinelPoolGetReplicaLookupError(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithLazyInit())
+	defer sp.Close()
+	cluster.Sentinels[0].Close()
+
+	_, err = sp.GetReplicaContext(context.Background())
+	if err == nil || err == ErrNoReplicas {
+		t.Fatalf("expected sentinel error, got %v", err)
+	}
+}
+
+// heldConn holds SENTINEL subcommand query until release is closed,
+// counting them on queries.
+type heldConn struct {
+	Conn
+	query   string
+	queries *int32
+	release chan struct{}
+}
+
+func (c heldConn) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "SENTINEL" && args[0] == c.query {
+		atomic.AddInt32(c.queries, 1)
+		<-c.release
+	}
+	return c.Conn.Do(ctx, cmd, args...)
+}
+
+func TestSentinelPoolReplicaLookup(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithLazyInit())
+	defer sp.Close()
+	var queries int32
+	release := make(chan struct{})
+	sp.sntl.Dialer = DialerFunc(func(ctx context.Context, addr string) (Conn, error) {
+		c, err := RedigoDialer{}.Dial(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return heldConn{Conn: c, query: "slaves", queries: &queries, release: release}, nil
+	})
+
+	roles := make(chan string, 3)
+	for i := 0; i < cap(roles); i++ {
+		go func() {
+			c := sp.GetReplica()
+			defer c.Close()
+			role, _ := getRole(c)
+			roles <- role
+		}()
+	}
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&queries) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("replicas were not looked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// replica set is not locked while sentinels are asked
+	lagging := make(chan bool, 1)
+	go func() {
+		lagging <- sp.replicas.anyLagging()
+	}()
+	select {
+	case <-lagging:
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("replica set was locked during lookup")
+	}
+	close(release)
+	for i := 0; i < cap(roles); i++ {
+		if role := <-roles; role != "slave" {
+			t.Fatalf("unexpected role %q", role)
+		}
+	}
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Fatalf("concurrent callers made %d lookups", n)
+	}
+}
+
+func TestNewSentinelPoolE(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	if _, err := NewSentinelPoolE(cluster.SentinelAddrs(), "othermaster", 0, "",
+		WithTopologyLogLevel(LogOff)); err == nil {
+		t.Fatal("expected error for unknown master")
+	}
+	sp, err := NewSentinelPoolE(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sp.Close()
+	if addr := sp.MasterAddr(); addr != cluster.Master.Addr() {
+		t.Fatalf("unexpected master %s", addr)
+	}
+}
+
+func TestSentinelPoolLazyInitResolvesOnce(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithLazyInit())
+	defer sp.Close()
+	var queries int32
+	release := make(chan struct{})
+	sp.sntl.Dialer = DialerFunc(func(ctx context.Context, addr string) (Conn, error) {
+		c, err := RedigoDialer{}.Dial(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return heldConn{Conn: c, query: "get-master-addr-by-name", queries: &queries, release: release}, nil
+	})
+
+	errs := make(chan error, 5)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			c := sp.Get()
+			defer c.Close()
+			_, err := c.Do("PING")
+			errs <- err
+		}()
+	}
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&queries) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("master was not resolved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// give other callers time to ask sentinels too if they did not wait
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Fatalf("concurrent first Get resolved master %d times", n)
+	}
+	if addr := sp.MasterAddr(); addr != cluster.Master.Addr() {
+		t.Fatalf("unexpected master %s", addr)
+	}
+}
+
+func TestSentinelPoolSentinelAuth(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Sentinels[0].RequireAuth("watcher", "sentinel-secret")
+
+	if _, err := NewSentinelPoolE(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff)); err == nil {
+		t.Fatal("expected error without sentinel credentials")
+	}
+	sp, err := NewSentinelPoolE(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithSentinelAuth("watcher", "sentinel-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sp.Close()
+	changes := sp.MasterChanges()
+
+	// switch is only seen if subscription authenticated too
+	waitWatching(t, sp)
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case change := <-changes:
+		if change.New != cluster.Master.Addr() {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+}
+
+// waitWatching waits until pool subscribed to master switch events.
+func waitWatching(t *testing.T, sp *SentinelPool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		sp.mu.RLock()
+		watching := sp.masterWatcher != nil
+		sp.mu.RUnlock()
+		if watching {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("pool did not subscribe to master switch")
+}
+
+func TestSentinelPoolUsername(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.RequireAuth("app", "secret")
+	cluster.Replicas[0].RequireAuth("app", "secret")
+	cluster.Sentinels[0].RequireAuth("", "sentinel-secret")
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "secret",
+		WithTopologyLogLevel(LogOff), WithUsername("app"), WithSentinelAuth("", "sentinel-secret"))
+	defer sp.Close()
+
+	if _, err := Do(sp, redis.String, "SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	c := sp.GetReplica()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	if report := sp.Diagnose(context.Background()); !report.Healthy() {
+		t.Fatalf("unexpected diagnostic report %+v", report)
+	}
+}
+
+func TestSentinelPoolSetMasterName(t *testing.T) {
+	oldGroup, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oldGroup.Close()
+	newGroup, err := sentineltest.NewCluster("newmaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer newGroup.Close()
+
+	sp := NewSentinelPool(append(oldGroup.SentinelAddrs(), newGroup.SentinelAddrs()...), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+	waitWatching(t, sp)
+	conn := sp.Get()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	oldPool := sp.Pool()
+	sp.mu.RLock()
+	oldWatcher := sp.masterWatcher
+	sp.mu.RUnlock()
+
+	if err := sp.SetMasterName("unknown"); err == nil {
+		t.Fatal("expected error of unknown master name")
+	}
+	if sp.MasterAddr() != oldGroup.Master.Addr() || sp.Pool() != oldPool {
+		t.Fatal("pool target changed although master name could not be resolved")
+	}
+
+	changes := sp.MasterChanges()
+	if err := sp.SetMasterName("newmaster"); err != nil {
+		t.Fatal(err)
+	}
+	if addr := sp.MasterAddr(); addr != newGroup.Master.Addr() {
+		t.Fatalf("master was not resolved again, got %s", addr)
+	}
+	select {
+	case change := <-changes:
+		if change.MasterName != "newmaster" || change.New != newGroup.Master.Addr() {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master name change was not reported")
+	}
+	// connections to previous master are closed, idle ones right away and
+	// borrowed ones on return
+	conn.Close()
+	if sp.Pool() == oldPool || oldPool.ActiveCount() != 0 {
+		t.Fatalf("connections to previous master were kept, %d active", oldPool.ActiveCount())
+	}
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	if st := sp.Stats(); st.AddrCommands[newGroup.Master.Addr()] != 1 {
+		t.Fatalf("new master was not used: %+v", st)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		sp.mu.RLock()
+		watcher := sp.masterWatcher
+		sp.mu.RUnlock()
+		if watcher != nil && watcher != oldWatcher {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pool did not subscribe again")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// switch of previous master is published before every switch of the
+	// new one on the same subscription, so by the time the latter is
+	// applied the former was ignored
+	hostPort := func(addr string) string {
+		host, port, _ := net.SplitHostPort(addr)
+		return host + " " + port
+	}
+	stale := "mymaster " + hostPort(oldGroup.Master.Addr()) + " " + hostPort(oldGroup.Replicas[0].Addr())
+	next := "newmaster " + hostPort(newGroup.Master.Addr()) + " " + hostPort(newGroup.Replicas[0].Addr())
+	for deadline := time.Now().Add(5 * time.Second); sp.MasterAddr() != newGroup.Replicas[0].Addr(); {
+		if time.Now().After(deadline) {
+			t.Fatal("switch of new master was not applied")
+		}
+		for _, s := range append(oldGroup.Sentinels, newGroup.Sentinels...) {
+			s.Publish("+switch-master", stale)
+			s.Publish("+switch-master", next)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for {
+		select {
+		case change := <-changes:
+			if change.New == oldGroup.Replicas[0].Addr() {
+				t.Fatalf("switch of previous master was applied: %+v", change)
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrFailoverInProgress is returned by Get when failover of master did not
+// finish within wait set with WithFailoverWait.
+var ErrFailoverInProgress = errors.New("redigo: master failover in progress")
+
+// failoverEventChannels are Sentinel event channels which start or abort
+// failover of master, subscribed to with WithFailoverWait.
+var failoverEventChannels = []string{
+	ChannelODown,
+	ChannelODownCleared,
+	ChannelNoGoodSlave,
+	ChannelFailoverEndTimeout,
+}
+
+// failoverGate is open unless failover of master is in progress.
+type failoverGate struct {
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+// close makes waiters block until open is called.
+func (g *failoverGate) close() {
+	g.mu.Lock()
+	if g.done == nil {
+		g.done = make(chan struct{})
+	}
+	g.mu.Unlock()
+}
+
+// open releases waiters.
+func (g *failoverGate) open() {
+	g.mu.Lock()
+	if g.done != nil {
+		close(g.done)
+		g.done = nil
+	}
+	g.mu.Unlock()
+}
+
+// waitFailover blocks while failover of master is in progress, from master
+// reported down or switch event received until new master is adopted, for
+// at most wait set with WithFailoverWait.
+func (p *SentinelPool) waitFailover(ctx context.Context) error {
+	if p.opts.failoverWait <= 0 {
+		return nil
+	}
+	p.gate.mu.Lock()
+	done := p.gate.done
+	p.gate.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	t := p.clock().NewTimer(p.opts.failoverWait)
+	defer t.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-t.C():
+		return ErrFailoverInProgress
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return ErrPoolClosed
+	}
+}
+
+// failoverEvent closes gate when master is reported objectively down and
+// opens it again when failover is aborted.
+func (sp *SentinelPool) failoverEvent(ev Event) {
+	e, err := ParseInstanceEvent(ev.Payload)
+	if err != nil || e.Type != "master" || e.Name != sp.sntl.masterName() {
+		return
+	}
+	switch ev.Channel {
+	case ChannelODown:
+		logFields(sp.logger(), LogDebug, "master down, holding connections",
+			"master", e.Name, "addr", e.Addr(), "wait", sp.opts.failoverWait)
+		sp.gate.close()
+	case ChannelODownCleared, ChannelNoGoodSlave, ChannelFailoverEndTimeout:
+		sp.gate.open()
+	}
+}
@@ -0,0 +1,121 @@
+package sentinel
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// waitGate waits until failover gate of sp is closed or open.
+func waitGate(t *testing.T, sp *SentinelPool, closed bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		sp.gate.mu.Lock()
+		done := sp.gate.done
+		sp.gate.mu.Unlock()
+		if (done != nil) == closed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("failover gate closed state did not become %v", closed)
+}
+
+func TestSentinelPoolFailoverWait(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithFailoverWait(5*time.Second))
+	defer sp.Close()
+	waitWatching(t, sp)
+
+	sntl := cluster.Sentinels[0]
+	down := "master mymaster " + strings.Replace(cluster.Master.Addr(), ":", " ", 1) + " #quorum 1/1"
+	sntl.Publish(ChannelODown, down)
+	waitGate(t, sp, true)
+
+	got := make(chan redis.Conn, 1)
+	go func() {
+		got <- sp.Get()
+	}()
+	select {
+	case c := <-got:
+		c.Close()
+		t.Fatal("Get must block while failover is in progress")
+	case <-time.After(100 * time.Millisecond):
+	}
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case c := <-got:
+		defer c.Close()
+		if !TestRole(c, "master") {
+			t.Fatal("connection after failover is not to master")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get was not released after failover")
+	}
+
+	// aborted failover releases waiters
+	down = "master mymaster " + strings.Replace(cluster.Master.Addr(), ":", " ", 1) + " #quorum 1/1"
+	sntl.Publish(ChannelODown, down)
+	waitGate(t, sp, true)
+	sntl.Publish(ChannelODownCleared, down)
+	waitGate(t, sp, false)
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSentinelPoolFailoverWaitSameMaster(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithFailoverWait(5*time.Second))
+	defer sp.Close()
+	waitWatching(t, sp)
+
+	sntl := cluster.Sentinels[0]
+	down := "master mymaster " + strings.Replace(cluster.Master.Addr(), ":", " ", 1) + " #quorum 1/1"
+	sntl.Publish(ChannelODown, down)
+	waitGate(t, sp, true)
+	// failover ended with the same master
+	addr := strings.Replace(cluster.Master.Addr(), ":", " ", 1)
+	sntl.Publish(ChannelSwitchMaster, "mymaster "+addr+" "+addr)
+	waitGate(t, sp, false)
+}
+
+func TestSentinelPoolFailoverWaitTimeout(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithFailoverWait(50*time.Millisecond))
+	defer sp.Close()
+	waitWatching(t, sp)
+
+	cluster.Sentinels[0].Publish(ChannelODown, "master mymaster 10.0.0.1 6379 #quorum 1/1")
+	waitGate(t, sp, true)
+	c := sp.Get()
+	defer c.Close()
+	if err := c.Err(); err != ErrFailoverInProgress {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := sp.GetContext(context.Background()); err != ErrFailoverInProgress {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// AddressFamily selects IP address families used to connect to hosts
+// resolving to both IPv4 and IPv6 addresses.
+type AddressFamily int
+
+const (
+	// FamilyAny races both families, preferring the one resolved first.
+	FamilyAny AddressFamily = iota
+	// PreferIPv4 tries IPv4 first and races IPv6 only if IPv4 attempt
+	// fails or is slow.
+	PreferIPv4
+	// PreferIPv6 is like PreferIPv4 with families swapped.
+	PreferIPv6
+	// IPv4Only never uses IPv6.
+	IPv4Only
+	// IPv6Only never uses IPv4.
+	IPv6Only
+)
+
+// netDialFunc returns function dialing over families allowed by family,
+// aborting once ctx is done.
+func netDialFunc(ctx context.Context, family AddressFamily, connectTimeout time.Duration) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+		d := newDialer(connectTimeout)
+		switch family {
+		case IPv4Only:
+			return d.DialContext(ctx, "tcp4", addr)
+		case IPv6Only:
+			return d.DialContext(ctx, "tcp6", addr)
+		case PreferIPv4:
+			return dialPreferred(ctx, d, addr, "tcp4", "tcp6")
+		case PreferIPv6:
+			return dialPreferred(ctx, d, addr, "tcp6", "tcp4")
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// dialPreferred dials addr over preferred network, racing attempt over
+// fallback network once preferred one fails or takes longer than
+// happyEyeballsDelay. First established connection wins.
+func dialPreferred(ctx context.Context, d *net.Dialer, addr, preferred, fallback string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		c       net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan result, 2)
+	dial := func(network string, primary bool) {
+		c, err := d.DialContext(ctx, network, addr)
+		results <- result{c, err, primary}
+	}
+	go dial(preferred, true)
+	timer := time.NewTimer(happyEyeballsDelay)
+	defer timer.Stop()
+
+	pending, fallbackStarted := 1, false
+	startFallback := func() {
+		if !fallbackStarted {
+			fallbackStarted = true
+			pending++
+			go dial(fallback, false)
+		}
+	}
+	var primaryErr, fallbackErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			startFallback()
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// losing attempt is canceled, close it if it still won
+				go func(n int) {
+					for i := 0; i < n; i++ {
+						if r := <-results; r.c != nil {
+							r.c.Close()
+						}
+					}
+				}(pending)
+				return r.c, nil
+			}
+			if r.primary {
+				primaryErr = r.err
+			} else {
+				fallbackErr = r.err
+			}
+			startFallback()
+		}
+	}
+	if primaryErr != nil {
+		return nil, primaryErr
+	}
+	return nil, fallbackErr
+}
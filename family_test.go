@@ -0,0 +1,38 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNetDialFamily(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	addr := net.JoinHostPort("127.0.0.1", port)
+
+	for _, family := range []AddressFamily{FamilyAny, PreferIPv4, PreferIPv6, IPv4Only} {
+		c, err := netDialFunc(context.Background(), family, time.Second)("tcp", addr)
+		if err != nil {
+			t.Fatalf("family %d: %v", family, err)
+		}
+		c.Close()
+	}
+	if _, err := netDialFunc(context.Background(), IPv6Only, time.Second)("tcp", addr); err == nil {
+		t.Fatal("expected IPv6Only to refuse IPv4 address")
+	}
+}
@@ -0,0 +1,92 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NodeErrors collects errors of operation run against several nodes, keyed
+// by node address.
+type NodeErrors map[string]error
+
+func (ne NodeErrors) Error() string {
+	addrs := make([]string, 0, len(ne))
+	for addr := range ne {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	msgs := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", addr, ne[addr]))
+	}
+	return "redigo: " + strings.Join(msgs, "; ")
+}
+
+// ForEachSentinel calls f with connection to every known Sentinel, running
+// at most concurrency calls at once. Errors of individual calls are returned
+// as NodeErrors.
+func (s *Sentinel) ForEachSentinel(concurrency int, f func(addr string, c redis.Conn) error) error {
+	s.mu.RLock()
+	addrs := s.Addrs
+	s.mu.RUnlock()
+	return forEachAddr(addrs, concurrency, func(addr string) error {
+		c := s.get(addr)
+		defer c.Close()
+		return f(addr, c)
+	})
+}
+
+// ForEachReplica calls f with connection to every replica of current master,
+// running at most concurrency calls at once. Options are passed to
+// redis.Dial, e.g. redis.DialPassword. Errors of individual calls, including
+// failed dials, are returned as NodeErrors.
+func (s *Sentinel) ForEachReplica(concurrency int, f func(addr string, c redis.Conn) error, options ...redis.DialOption) error {
+	addrs, err := s.SlaveAddrs()
+	if err != nil {
+		return err
+	}
+	return forEachAddr(addrs, concurrency, func(addr string) error {
+		c, err := s.dialNodeAs(context.Background(), addr, roleReplica, options)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return f(addr, c)
+	})
+}
+
+// forEachAddr runs f for every address with bounded concurrency.
+func forEachAddr(addrs []string, concurrency int, f func(addr string) error) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	errs := make(NodeErrors)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(addr string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := f(addr); err != nil {
+				mu.Lock()
+				errs[addr] = err
+				mu.Unlock()
+			}
+		}(addr)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
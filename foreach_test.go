@@ -0,0 +1,149 @@
+package sentinel
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestForEachAddr(t *testing.T) {
+	var running, maxRunning int32
+	addrs := []string{"a:1", "b:1", "c:1", "d:1"}
+	err := forEachAddr(addrs, 2, func(addr string) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		defer atomic.AddInt32(&running, -1)
+		if addr == "c:1" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if maxRunning > 2 {
+		t.Fatalf("expected at most 2 concurrent calls, got %d", maxRunning)
+	}
+	ne, ok := err.(NodeErrors)
+	if !ok || len(ne) != 1 || ne["c:1"] == nil {
+		t.Fatalf("expected error for c:1 only, got %v", err)
+	}
+}
+
+// visits records addresses calls were made for and most calls running at
+// once.
+type visits struct {
+	mu         sync.Mutex
+	addrs      []string
+	running    int
+	maxRunning int
+}
+
+func (v *visits) visit(addr string, f func() error) error {
+	v.mu.Lock()
+	v.addrs = append(v.addrs, addr)
+	v.running++
+	if v.running > v.maxRunning {
+		v.maxRunning = v.running
+	}
+	v.mu.Unlock()
+	defer func() {
+		v.mu.Lock()
+		v.running--
+		v.mu.Unlock()
+	}()
+	// overlap with other calls
+	time.Sleep(20 * time.Millisecond)
+	return f()
+}
+
+func (v *visits) expect(t *testing.T, addrs []string, maxRunning int) {
+	t.Helper()
+	got := append([]string(nil), v.addrs...)
+	expected := append([]string(nil), addrs...)
+	sort.Strings(got)
+	sort.Strings(expected)
+	if len(got) != len(expected) {
+		t.Fatalf("visited %v, expected %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("visited %v, expected %v", got, expected)
+		}
+	}
+	if v.maxRunning != maxRunning {
+		t.Fatalf("expected %d concurrent calls, got %d", maxRunning, v.maxRunning)
+	}
+}
+
+func TestForEachSentinel(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	var v visits
+	err = s.ForEachSentinel(2, func(addr string, c redis.Conn) error {
+		return v.visit(addr, func() error {
+			role, err := getRole(c)
+			if err == nil && role != "sentinel" {
+				err = errors.New("unexpected role " + role)
+			}
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.expect(t, cluster.SentinelAddrs(), 2)
+}
+
+func TestForEachReplica(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	var addrs []string
+	for _, r := range cluster.Replicas {
+		r.RequirePass("secret")
+		addrs = append(addrs, r.Addr())
+	}
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	var v visits
+	err = s.ForEachReplica(2, func(addr string, c redis.Conn) error {
+		return v.visit(addr, func() error {
+			role, err := getRole(c)
+			if err == nil && role != "slave" {
+				err = errors.New("unexpected role " + role)
+			}
+			return err
+		})
+	}, redis.DialPassword("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.expect(t, addrs, 2)
+
+	// without password passed as dial option replicas reject commands
+	err = s.ForEachReplica(3, func(addr string, c redis.Conn) error {
+		_, err := c.Do("PING")
+		return err
+	})
+	if ne, ok := err.(NodeErrors); !ok || len(ne) != len(addrs) {
+		t.Fatalf("expected error of every replica, got %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/RivenZoo/go-sentinel
+
+go 1.21
+
+require github.com/gomodule/redigo v1.9.3
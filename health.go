@@ -0,0 +1,93 @@
+package sentinel
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// _checkHealth periodically verifies address pool dials still belongs to
+// master until pool is closed.
+func (sp *SentinelPool) _checkHealth() {
+	ticker := sp.clock().NewTicker(sp.opts.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sp.done:
+			return
+		case <-ticker.C():
+		}
+		sp.checkMasterRole()
+	}
+}
+
+// checkMasterRole queries role of address pool dials, bypassing the pool,
+// and re-resolves master if it is no longer one.
+func (sp *SentinelPool) checkMasterRole() {
+	sp.mu.RLock()
+	addr := sp.curAddr
+	sp.mu.RUnlock()
+	if addr == "" {
+		return
+	}
+	c, err := sp.dialMaster(context.Background(), addr)
+	if err != nil {
+		logFields(sp.logger(), LogWarn, "master health check failed",
+			"master", sp.sntl.masterName(), "addr", addr, "err", err)
+		sp.setState(StateDegraded, "health check failed")
+		return
+	}
+	role, err := getRole(c)
+	c.Close()
+	if err != nil {
+		logFields(sp.logger(), LogWarn, "master health check failed",
+			"master", sp.sntl.masterName(), "addr", addr, "err", err)
+		sp.setState(StateDegraded, "health check failed")
+		return
+	}
+	if role != "master" {
+		sp.correctDemotion(addr, role)
+		return
+	}
+	sp.roles.store(addr, sp.clock().Now())
+	sp.setState(StateHealthy, "health check")
+}
+
+// dialMaster dials master on addr bypassing the pool, authenticating the
+// same way pooled connections do. Options override default ones and those
+// set with WithDialOptions.
+func (sp *SentinelPool) dialMaster(ctx context.Context, addr string, options ...redis.DialOption) (redis.Conn, error) {
+	options = append(append([]redis.DialOption(nil), sp.opts.dialOptions...), options...)
+	return sp.sntl.dialNodeAs(ctx, addr, roleMaster, options)
+}
+
+// correctDemotion asks sentinels for master after addr was found to have
+// role other than master without switch-master event being received, e.g.
+// after manual SLAVEOF or when event was missed.
+func (sp *SentinelPool) correctDemotion(addr, role string) {
+	logFields(sp.logger(), LogWarn, "master demoted without switch event",
+		"master", sp.sntl.masterName(), "addr", addr, "role", role)
+	newAddr, err := sp.sntl.MasterAddr()
+	if err != nil {
+		logFields(sp.logger(), LogError, "resolve master after demotion failed",
+			"master", sp.sntl.masterName(), "err", err)
+		sp.setState(StateDegraded, "demoted")
+		return
+	}
+	if newAddr == addr {
+		logFields(sp.logger(), LogWarn, "sentinels still report demoted master",
+			"master", sp.sntl.masterName(), "addr", addr)
+		sp.setState(StateDegraded, "demoted")
+		return
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	switch {
+	case sp.curAddr != addr:
+		// switch event arrived meanwhile
+	case sp.paused:
+		sp.pendingAddr = newAddr
+	default:
+		sp._adoptMaster(newAddr, "demotion", "role", role)
+	}
+}
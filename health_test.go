@@ -0,0 +1,51 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolHealthCheckDemotion(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	// polling rarely, so only health check can notice demotion
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithPolling(time.Hour),
+		WithHealthCheck(10*time.Millisecond))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+	oldMaster, newMaster := cluster.Master, cluster.Replicas[0]
+
+	// sentinels still report demoted master, so pool keeps it
+	newMaster.Promote()
+	oldMaster.ReplicaOf(newMaster)
+	for deadline := time.Now().Add(5 * time.Second); sp.PoolState().Reason != "demoted"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("demotion was not detected, state %+v", sp.PoolState())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if addr := sp.MasterAddr(); addr != oldMaster.Addr() {
+		t.Fatalf("switched to %s sentinels did not report", addr)
+	}
+
+	cluster.Failover(newMaster)
+	select {
+	case change := <-changes:
+		if change.Old != oldMaster.Addr() || change.New != newMaster.Addr() ||
+			change.Reason != "demotion" {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("demotion was not corrected")
+	}
+	if addr := sp.MasterAddr(); addr != newMaster.Addr() {
+		t.Fatalf("unexpected master %s", addr)
+	}
+}
@@ -0,0 +1,73 @@
+package sentinel
+
+import "time"
+
+// Hooks are callbacks notified of lifecycle events, e.g. to raise alerts or
+// invalidate caches without polling MasterAddr. Any of them can be nil.
+type Hooks struct {
+	// OnMasterSwitch is called after SentinelPool switched from one master
+	// to another, with the same change MasterChanges delivers. It is called
+	// from a separate goroutine, one change at a time in order they
+	// happened.
+	OnMasterSwitch func(MasterChange)
+
+	// OnSentinelDown is called when Sentinel on addr failed to answer query,
+	// subscription or ping and was moved to the bottom of address list.
+	OnSentinelDown func(addr string, err error)
+
+	// OnDialError is called when dialing Sentinel, master or replica on
+	// addr fails.
+	OnDialError func(addr string, err error)
+
+	// OnMonitorError is called when SentinelPool failed to subscribe to
+	// master switch events, with delay before it tries again, see
+	// WithMonitorBackoff.
+	OnMonitorError func(err error, retryIn time.Duration)
+}
+
+// sentinelDown calls OnSentinelDown hook if it is set. Lock must not be
+// held by caller, so hook can use Sentinel.
+func (s *Sentinel) sentinelDown(addr string, err error) {
+	if f := s.Hooks.OnSentinelDown; f != nil {
+		f(addr, err)
+	}
+}
+
+// dialFailed calls OnDialError hook if it is set.
+func (s *Sentinel) dialFailed(addr string, err error) {
+	if f := s.Hooks.OnDialError; f != nil {
+		f(addr, err)
+	}
+}
+
+// _queueMasterSwitch queues call of OnMasterSwitch hook with change.
+// Lock must be held by caller.
+func (sp *SentinelPool) _queueMasterSwitch(change MasterChange) {
+	if sp.opts.hooks.OnMasterSwitch == nil {
+		return
+	}
+	sp.switchQueue = append(sp.switchQueue, change)
+	if !sp.switchRunning {
+		sp.switchRunning = true
+		go sp.notifyMasterSwitches()
+	}
+}
+
+// notifyMasterSwitches calls OnMasterSwitch hook with queued changes until
+// queue is empty.
+func (sp *SentinelPool) notifyMasterSwitches() {
+	for {
+		sp.mu.Lock()
+		if len(sp.switchQueue) == 0 {
+			sp.switchRunning = false
+			sp.mu.Unlock()
+			return
+		}
+		change := sp.switchQueue[0]
+		sp.switchQueue = sp.switchQueue[1:]
+		sp.mu.Unlock()
+		sp._runRecovered("master switch hook", func() {
+			sp.opts.hooks.OnMasterSwitch(change)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package sentinel
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolHooks(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+
+	var mu sync.Mutex
+	var down, dialErrors []string
+	switches := make(chan MasterChange, 1)
+	hooks := Hooks{
+		OnMasterSwitch: func(change MasterChange) {
+			switches <- change
+		},
+		OnSentinelDown: func(addr string, err error) {
+			mu.Lock()
+			down = append(down, addr)
+			mu.Unlock()
+		},
+		OnDialError: func(addr string, err error) {
+			mu.Lock()
+			dialErrors = append(dialErrors, addr)
+			mu.Unlock()
+		},
+	}
+	sp := NewSentinelPool(append([]string{deadAddr}, cluster.SentinelAddrs()...), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithHooks(hooks))
+	defer sp.Close()
+
+	mu.Lock()
+	if len(down) != 1 || down[0] != deadAddr || len(dialErrors) != 1 || dialErrors[0] != deadAddr {
+		t.Fatalf("unexpected hook calls: down %v, dial errors %v", down, dialErrors)
+	}
+	mu.Unlock()
+
+	waitWatching(t, sp)
+	oldAddr := cluster.Master.Addr()
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case change := <-switches:
+		if change.Old != oldAddr || change.New != cluster.Master.Addr() {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch hook was not called")
+	}
+}
@@ -0,0 +1,211 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// KeyspaceEvent is a keyspace notification received from master.
+type KeyspaceEvent struct {
+	// Pattern is a subscribed pattern notification matched.
+	Pattern string
+	// Channel is e.g. __keyspace@0__:mykey or __keyevent@0__:del.
+	Channel string
+	// Payload is an event name on __keyspace@ channels and a key name on
+	// __keyevent@ ones.
+	Payload string
+	// Master is an address of master notification came from.
+	Master string
+	Time   time.Time
+}
+
+// KeyspaceWatcher receives keyspace notifications from current master of
+// SentinelPool, see SentinelPool.WatchKeyspace.
+type KeyspaceWatcher struct {
+	sp       *SentinelPool
+	patterns []string
+	events   chan KeyspaceEvent
+	dropped  uint64
+
+	closeOnce sync.Once
+	done      chan struct{}
+	exited    chan struct{}
+}
+
+// WatchKeyspace subscribes to keyspace notifications matching patterns, e.g.
+// "__keyevent@0__:expired", on current master and moves subscription to new
+// master after every failover. Notifications published while subscription
+// moves are lost. Redis publishes nothing unless notify-keyspace-events is
+// set, and the setting is not replicated, so a warning is logged whenever
+// master watched has it disabled.
+func (sp *SentinelPool) WatchKeyspace(patterns ...string) (*KeyspaceWatcher, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("redigo: no keyspace patterns to watch")
+	}
+	sp.mu.RLock()
+	closed := sp.closed
+	sp.mu.RUnlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+	kw := &KeyspaceWatcher{
+		sp:       sp,
+		patterns: append([]string(nil), patterns...),
+		events:   make(chan KeyspaceEvent, eventBufferSize),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	changes := sp.MasterChanges()
+	go func() {
+		defer close(kw.exited)
+		defer close(kw.events)
+		defer sp.removeChangeSub(changes)
+		sp._runRecovered("keyspace", func() { kw.run(changes) })
+	}()
+	return kw, nil
+}
+
+// Events returns channel receiving notifications. Up to 64 of them are
+// buffered for slow receiver, further ones are dropped and counted in
+// Dropped. Channel is closed once watcher or pool is closed.
+func (kw *KeyspaceWatcher) Events() <-chan KeyspaceEvent {
+	return kw.events
+}
+
+// Dropped returns number of notifications discarded because receiver did
+// not keep up.
+func (kw *KeyspaceWatcher) Dropped() uint64 {
+	return atomic.LoadUint64(&kw.dropped)
+}
+
+// Close unsubscribes from notifications and closes Events channel.
+func (kw *KeyspaceWatcher) Close() {
+	kw.closeOnce.Do(func() { close(kw.done) })
+	<-kw.exited
+}
+
+// run keeps subscription on current master until watcher or pool is
+// closed, resubscribing whenever master changes or connection fails.
+func (kw *KeyspaceWatcher) run(changes <-chan MasterChange) {
+	for {
+		// address resolved below already reflects pending change
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		default:
+		}
+		addr, err := kw.sp._resolveMaster(context.Background())
+		var psc redis.PubSubConn
+		if err == nil {
+			psc, err = kw.subscribe(addr)
+		}
+		if err != nil {
+			logFields(kw.sp.logger(), LogError, "keyspace subscribe failed",
+				"master", kw.sp.sntl.masterName(), "addr", addr, "err", err)
+			if !kw.wait(kw.sp.jitter(monitorRetryDelay, monitorRetryJitter)) {
+				return
+			}
+			continue
+		}
+
+		failed := make(chan struct{})
+		go func() {
+			kw.receive(psc, addr)
+			close(failed)
+		}()
+		stop, moved := false, false
+		select {
+		case _, ok := <-changes:
+			stop, moved = !ok, ok
+		case <-failed:
+		case <-kw.done:
+			stop = true
+		}
+		psc.Close()
+		<-failed
+		switch {
+		case stop:
+			return
+		case moved:
+			logFields(kw.sp.logger(), LogInfo, "keyspace subscription moving to new master",
+				"master", kw.sp.sntl.masterName(), "addr", addr)
+		case !kw.wait(kw.sp.jitter(monitorRetryDelay, monitorRetryJitter)):
+			return
+		}
+	}
+}
+
+// subscribe connects to master on addr and subscribes to patterns.
+func (kw *KeyspaceWatcher) subscribe(addr string) (redis.PubSubConn, error) {
+	// notifications can be rare, so reads never time out
+	c, err := kw.sp.dialMaster(context.Background(), addr, redis.DialReadTimeout(0))
+	if err != nil {
+		return redis.PubSubConn{}, err
+	}
+	kw.checkConfig(c, addr)
+	psc := redis.PubSubConn{Conn: c}
+	if err := psc.PSubscribe(redis.Args{}.AddFlat(kw.patterns)...); err != nil {
+		c.Close()
+		return redis.PubSubConn{}, err
+	}
+	return psc, nil
+}
+
+// checkConfig warns if master on addr has keyspace notifications disabled.
+func (kw *KeyspaceWatcher) checkConfig(c redis.Conn, addr string) {
+	res, err := redis.Strings(c.Do("CONFIG", "GET", "notify-keyspace-events"))
+	if err != nil {
+		// CONFIG is often renamed or denied by ACL
+		logFields(kw.sp.logger(), LogDebug, "keyspace notification config check failed",
+			"master", kw.sp.sntl.masterName(), "addr", addr, "err", err)
+		return
+	}
+	if len(res) < 2 || res[1] == "" {
+		logFields(kw.sp.logger(), LogWarn, "keyspace notifications disabled on master",
+			"master", kw.sp.sntl.masterName(), "addr", addr)
+	}
+}
+
+// receive delivers notifications until subscription fails or is closed.
+func (kw *KeyspaceWatcher) receive(psc redis.PubSubConn, addr string) {
+	for {
+		var ev KeyspaceEvent
+		switch reply := psc.Receive().(type) {
+		case redis.Message:
+			ev = KeyspaceEvent{Pattern: reply.Pattern, Channel: reply.Channel, Payload: string(reply.Data)}
+		case error:
+			return
+		default:
+			continue
+		}
+		ev.Master = addr
+		ev.Time = kw.sp.clock().Now()
+		select {
+		case kw.events <- ev:
+		default:
+			atomic.AddUint64(&kw.dropped, 1)
+		}
+	}
+}
+
+// wait pauses for d unless watcher or pool is closed meanwhile and reports
+// whether whole duration passed.
+func (kw *KeyspaceWatcher) wait(d time.Duration) bool {
+	t := kw.sp.clock().NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return true
+	case <-kw.done:
+		return false
+	case <-kw.sp.done:
+		return false
+	}
+}
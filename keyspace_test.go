@@ -0,0 +1,64 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestWatchKeyspaceFailover(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.SetConfig("notify-keyspace-events", "E$")
+	cluster.Replicas[0].SetConfig("notify-keyspace-events", "E$")
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithStrictConsistency(time.Second))
+	defer sp.Close()
+	kw, err := sp.WatchKeyspace("__keyevent@0__:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kw.Close()
+
+	expect := func(key, master string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			// subscription may not be established yet
+			if _, err := Do(sp, redis.String, "SET", key, "v"); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case ev := <-kw.Events():
+				if ev.Payload != key {
+					// earlier key set repeatedly
+					continue
+				}
+				if ev.Channel != "__keyevent@0__:set" || ev.Master != master {
+					t.Fatalf("unexpected event %+v", ev)
+				}
+				return
+			case <-time.After(20 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("no notification for %s from %s", key, master)
+			}
+		}
+	}
+	expect("before", cluster.Master.Addr())
+
+	changes := sp.MasterChanges()
+	cluster.Failover(cluster.Replicas[0])
+	<-changes
+	expect("after", cluster.Master.Addr())
+
+	kw.Close()
+	if _, ok := <-kw.Events(); ok {
+		t.Fatal("events channel must be closed after Close")
+	}
+}
@@ -0,0 +1,115 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// replicaLagInterval is how often replicas are checked for replication lag,
+// see WithReplicaLagGuard.
+const replicaLagInterval = time.Second
+
+// ErrReplicasLagging is returned by GetReplica when every replica lags
+// behind master more than WithReplicaLagGuard allows.
+var ErrReplicasLagging = errors.New("redigo: all replicas lag behind master")
+
+// startLagCheck starts checking replicas for replication lag if
+// WithReplicaLagGuard is used. It is called on first replica use, so lazily
+// initialized pool does not contact sentinels before it is used.
+func (sp *SentinelPool) startLagCheck() {
+	if sp.opts.maxLagBytes <= 0 && sp.opts.maxLinkDown <= 0 {
+		return
+	}
+	sp.lagOnce.Do(func() {
+		go sp._runGuarded("replica lag", sp._checkReplicaLag)
+	})
+}
+
+// _checkReplicaLag periodically finds replicas lagging behind master until
+// pool is closed.
+func (sp *SentinelPool) _checkReplicaLag() {
+	ticker := sp.clock().NewTicker(replicaLagInterval)
+	defer ticker.Stop()
+	for {
+		sp.checkReplicaLag()
+		select {
+		case <-sp.done:
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// checkReplicaLag compares replication offset of every known replica with
+// master one and looks up how long their links to master are down, then
+// records which replicas must be skipped. Replicas which can not be asked
+// are left to dial failure handling of GetReplica.
+func (sp *SentinelPool) checkReplicaLag() {
+	addrs, _, _ := sp.lookupReplicas()
+	if len(addrs) == 0 {
+		return
+	}
+	lagging := make(map[string]bool)
+	if max := sp.opts.maxLagBytes; max > 0 {
+		masterAddr, err := sp._resolveMaster(context.Background())
+		var masterOffset int64
+		if err == nil {
+			masterOffset, err = sp.probeOffset(masterAddr, roleMaster)
+		}
+		if err != nil {
+			logFields(sp.logger(), LogWarn, "master replication offset check failed",
+				"master", sp.sntl.masterName(), "err", err)
+			return
+		}
+		for _, addr := range addrs {
+			offset, err := sp.probeOffset(addr, roleReplica)
+			if err == nil && masterOffset-offset > max {
+				lagging[addr] = true
+			}
+		}
+	}
+	if max := sp.opts.maxLinkDown; max > 0 {
+		slaves, err := sp.sntl.Slaves()
+		if err != nil {
+			logFields(sp.logger(), LogWarn, "replica link check failed",
+				"master", sp.sntl.masterName(), "err", err)
+			return
+		}
+		for _, info := range slaves {
+			if info.MasterLinkDown > max {
+				lagging[info.Addr] = true
+			}
+		}
+	}
+
+	rs := &sp.replicas
+	rs.mu.Lock()
+	prev := rs.lagging
+	rs.lagging = lagging
+	rs.mu.Unlock()
+	for addr := range lagging {
+		if !prev[addr] {
+			logFields(sp.logger(), LogWarn, "replica lags behind master",
+				"master", sp.sntl.masterName(), "addr", addr)
+		}
+	}
+	for addr := range prev {
+		if !lagging[addr] {
+			logFields(sp.logger(), LogInfo, "replica caught up with master",
+				"master", sp.sntl.masterName(), "addr", addr)
+		}
+	}
+}
+
+// probeOffset returns replication offset of instance with role on addr.
+// Instance is dialed bypassing pools, so probes are not counted as commands
+// issued by application.
+func (sp *SentinelPool) probeOffset(addr, role string) (int64, error) {
+	c, err := sp.sntl.dialNodeAs(context.Background(), addr, role, sp.opts.dialOptions)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	return ReplicationOffset(c)
+}
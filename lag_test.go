@@ -0,0 +1,72 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolReplicaLagGuard(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.SetReplOffset(1000)
+	cluster.Replicas[0].SetReplOffset(950)
+	cluster.Replicas[1].SetReplOffset(10)
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithReplicaLagGuard(100, 0))
+	defer sp.Close()
+	sp.checkReplicaLag()
+	// probes are not commands of application
+	if st := sp.Stats(); st.MasterCommands != 0 || st.ReplicaCommands != 0 || len(st.AddrCommands) != 0 {
+		t.Fatalf("lag check was counted: %+v", st)
+	}
+
+	if addrs, _, _ := sp.replicaPools(); len(addrs) != 1 || addrs[0] != cluster.Replicas[0].Addr() {
+		t.Fatalf("lagging replica must be skipped, got %v", addrs)
+	}
+	c := sp.GetReplica()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	cluster.Master.SetReplOffset(2000)
+	sp.checkReplicaLag()
+	if err := sp.GetReplica().Err(); err != ErrReplicasLagging {
+		t.Fatalf("expected ErrReplicasLagging, got %v", err)
+	}
+
+	cluster.Replicas[1].SetReplOffset(2000)
+	sp.checkReplicaLag()
+	c = sp.GetReplica()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatalf("replica which caught up must be used, got %v", err)
+	}
+}
+
+func TestSentinelPoolReplicaLagGuardLazy(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithLazyInit(), WithReplicaLagGuard(100, time.Second))
+	defer sp.Close()
+	time.Sleep(50 * time.Millisecond)
+	if st := sp.sntl.PoolStats(); len(st) != 0 {
+		t.Fatalf("sentinels contacted before pool was used: %+v", st)
+	}
+	c := sp.GetReplica()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+package sentinel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logger receives messages Sentinel and SentinelPool log, formatted as
+// message followed by key=value pairs. Debug and info messages are passed
+// to Debugf, warnings and errors to Errorf, unless logger also has Infof or
+// Warnf method, which is then used for its level. Nothing is logged by
+// default.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type infoLogger interface {
+	Infof(format string, args ...interface{})
+}
+
+type warnLogger interface {
+	Warnf(format string, args ...interface{})
+}
+
+// fieldLogger is implemented by loggers taking key=value pairs as
+// structured fields instead of formatted message, see SlogLogger.
+type fieldLogger interface {
+	logFields(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LogLevel is a level topology changes are logged at.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+	// LogOff disables logging.
+	LogOff
+)
+
+// logFields logs msg followed by key=value pairs at level with logger,
+// which can be nil.
+func logFields(logger Logger, level LogLevel, msg string, keyvals ...interface{}) {
+	if logger == nil || level >= LogOff {
+		return
+	}
+	if l, ok := logger.(fieldLogger); ok {
+		l.logFields(level, msg, keyvals...)
+		return
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+	}
+	switch level {
+	case LogDebug:
+		logger.Debugf("%s", b.String())
+	case LogInfo:
+		if l, ok := logger.(infoLogger); ok {
+			l.Infof("%s", b.String())
+		} else {
+			logger.Debugf("%s", b.String())
+		}
+	case LogWarn:
+		if l, ok := logger.(warnLogger); ok {
+			l.Warnf("%s", b.String())
+		} else {
+			logger.Errorf("%s", b.String())
+		}
+	default:
+		logger.Errorf("%s", b.String())
+	}
+}
+
+// logger returns logger of pool, see WithLogger.
+func (sp *SentinelPool) logger() Logger {
+	if sp.sntl == nil {
+		return nil
+	}
+	return sp.sntl.Logger
+}
+
+// _adoptMaster makes pool dial addr from now on, logging the change.
+// Lock must be held by caller.
+func (sp *SentinelPool) _adoptMaster(addr, reason string, keyvals ...interface{}) {
+	old := sp.curAddr
+	sp.curAddr = addr
+	sp.gate.open()
+	sp.resolvedAt = sp.clock().Now()
+	sp.masterSource = sp.sntl.masterSourceOf(addr)
+	if old == addr {
+		return
+	}
+	sp.generation++
+	sp.replicas.invalidate()
+	if old == "" {
+		sp._setState(StateHealthy, reason)
+	} else {
+		sp.switches++
+		sp.lastSwitch = sp.clock().Now()
+		sp._setState(StateFailoverInProgress, reason)
+		if sp.opts.drainOnSwitch && sp.pool != nil {
+			sp._drainPool()
+		}
+	}
+	keyvals = append([]interface{}{
+		"master", sp.sntl.masterName(),
+		"old", old,
+		"new", addr,
+		"reason", reason,
+	}, keyvals...)
+	logFields(sp.logger(), sp.opts.topologyLogLevel, "master adopted", keyvals...)
+	if old != "" {
+		sp._notifyChange(MasterChange{
+			MasterName: sp.sntl.masterName(),
+			Old:        old,
+			New:        addr,
+			Reason:     reason,
+			Time:       sp.clock().Now(),
+		})
+	}
+}
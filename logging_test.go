@@ -0,0 +1,85 @@
+package sentinel
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recordingLogger records messages prefixed with method they were logged
+// with.
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "debug: "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "error: "+fmt.Sprintf(format, args...))
+}
+
+// leveledLogger has methods for every level.
+type leveledLogger struct {
+	recordingLogger
+}
+
+func (l *leveledLogger) Infof(format string, args ...interface{}) {
+	l.lines = append(l.lines, "info: "+fmt.Sprintf(format, args...))
+}
+
+func (l *leveledLogger) Warnf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "warn: "+fmt.Sprintf(format, args...))
+}
+
+func TestLogFields(t *testing.T) {
+	logAll := func(logger Logger) {
+		for _, level := range []LogLevel{LogDebug, LogInfo, LogWarn, LogError, LogOff} {
+			logFields(logger, level, "100% done", "addr", "10.0.0.1:6379")
+		}
+	}
+	logAll(nil)
+
+	basic := &recordingLogger{}
+	logAll(basic)
+	want := []string{
+		"debug: 100% done addr=10.0.0.1:6379",
+		"debug: 100% done addr=10.0.0.1:6379",
+		"error: 100% done addr=10.0.0.1:6379",
+		"error: 100% done addr=10.0.0.1:6379",
+	}
+	if fmt.Sprint(basic.lines) != fmt.Sprint(want) {
+		t.Fatalf("unexpected lines %q", basic.lines)
+	}
+
+	leveled := &leveledLogger{}
+	logAll(leveled)
+	want = []string{
+		"debug: 100% done addr=10.0.0.1:6379",
+		"info: 100% done addr=10.0.0.1:6379",
+		"warn: 100% done addr=10.0.0.1:6379",
+		"error: 100% done addr=10.0.0.1:6379",
+	}
+	if fmt.Sprint(leveled.lines) != fmt.Sprint(want) {
+		t.Fatalf("unexpected lines %q", leveled.lines)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := SlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	logFields(logger, LogInfo, "master adopted",
+		"master", "mymaster", "old", "10.0.0.1:6379", "new", "10.0.0.2:6379", "reason", "switch-master")
+	line := buf.String()
+	for _, want := range []string{
+		"level=INFO", `msg="master adopted"`, "master_name=mymaster",
+		"old_addr=10.0.0.1:6379", "new_addr=10.0.0.2:6379", "reason=switch-master",
+	} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("%q missing in %q", want, line)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// MasterState is state of master as reported by SENTINEL MASTER, or an
+// entry of SENTINEL MASTERS reply. Numeric fields missing from reply are
+// left zero.
+type MasterState struct {
+	Name string
+	Addr string
+	// Flags are comma separated flags of master, e.g. "master" or
+	// "s_down,o_down,master".
+	Flags             string
+	NumSlaves         int
+	NumOtherSentinels int
+	Quorum            int
+	ConfigEpoch       int64
+	// LastOKPing is how long ago master last replied to PING.
+	LastOKPing      time.Duration
+	DownAfter       time.Duration
+	FailoverTimeout time.Duration
+	// Fields are all fields of reply as they were received, including
+	// ones not parsed above.
+	Fields map[string]string
+}
+
+// MasterState returns state of MasterName as known to the first Sentinel
+// which answers, e.g. for health dashboards and preflight checks.
+func (s *Sentinel) MasterState() (MasterState, error) {
+	return s.MasterStateContext(context.Background())
+}
+
+// MasterStateContext is like MasterState, but gives up once ctx is done.
+func (s *Sentinel) MasterStateContext(ctx context.Context) (MasterState, error) {
+	masterName := s.masterName()
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForMasterState(c, masterName)
+	})
+	if err != nil {
+		return MasterState{}, err
+	}
+	state := res.(map[string]string)
+	s.recordEpoch(masterName, state)
+	return parseMasterState(state)
+}
+
+// Masters returns state of all masters Sentinels monitor, in order they
+// were listed, so operators can enumerate everything a sentinel cluster
+// manages.
+func (s *Sentinel) Masters() ([]MasterState, error) {
+	return s.MastersContext(context.Background())
+}
+
+// MastersContext is like Masters, but gives up once ctx is done.
+func (s *Sentinel) MastersContext(ctx context.Context) ([]MasterState, error) {
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForMasterStates(c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]MasterState), nil
+}
+
+func queryForMasterStates(conn redis.Conn) ([]MasterState, error) {
+	res, err := redis.Values(conn.Do("SENTINEL", "masters"))
+	if err != nil {
+		return nil, err
+	}
+	masters := make([]MasterState, 0, len(res))
+	for _, a := range res {
+		sm, err := redis.StringMap(a, nil)
+		if err != nil {
+			return nil, err
+		}
+		ms, err := parseMasterState(sm)
+		if err != nil {
+			return nil, err
+		}
+		masters = append(masters, ms)
+	}
+	return masters, nil
+}
+
+// parseMasterState parses SENTINEL MASTER reply or entry of SENTINEL
+// MASTERS one.
+func parseMasterState(state map[string]string) (MasterState, error) {
+	addr, err := joinAddr(state["ip"], state["port"])
+	if err != nil {
+		return MasterState{}, err
+	}
+	p := stateParser{state: state}
+	ms := MasterState{
+		Name:              state["name"],
+		Addr:              addr,
+		Flags:             state["flags"],
+		NumSlaves:         int(p.int("num-slaves")),
+		NumOtherSentinels: int(p.int("num-other-sentinels")),
+		Quorum:            int(p.int("quorum")),
+		ConfigEpoch:       p.int("config-epoch"),
+		LastOKPing:        p.millis("last-ok-ping"),
+		DownAfter:         p.millis("down-after-milliseconds"),
+		FailoverTimeout:   p.millis("failover-timeout"),
+		Fields:            state,
+	}
+	if p.err != nil {
+		return MasterState{}, p.err
+	}
+	return ms, nil
+}
+
+// stateParser parses numeric fields of Sentinel state reply, remembering
+// the first malformed one. Missing fields are parsed as zero.
+type stateParser struct {
+	state map[string]string
+	err   error
+}
+
+func (p *stateParser) int(name string) int64 {
+	v, ok := p.state[name]
+	if !ok || p.err != nil {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		p.err = fmt.Errorf("redigo: unexpected %s %q in sentinel reply", name, v)
+	}
+	return n
+}
+
+// millis parses field holding number of milliseconds.
+func (p *stateParser) millis(name string) time.Duration {
+	return time.Duration(p.int(name)) * time.Millisecond
+}
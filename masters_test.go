@@ -0,0 +1,148 @@
+package sentinel
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestQueryForMasterStates(t *testing.T) {
+	replies := []interface{}{[]interface{}{
+		[]interface{}{
+			[]byte("name"), []byte("mymaster"), []byte("ip"), []byte("10.0.0.1"),
+			[]byte("port"), []byte("6379"), []byte("flags"), []byte("s_down,master"),
+			[]byte("num-slaves"), []byte("2"), []byte("quorum"), []byte("2"),
+			[]byte("last-ok-ping"), []byte("150"),
+		},
+		[]interface{}{
+			[]byte("name"), []byte("other"), []byte("ip"), []byte("10.0.0.5"),
+			[]byte("port"), []byte("6380"), []byte("flags"), []byte("master"),
+		},
+	}}
+	masters, err := queryForMasterStates(replyConn{replies: &replies})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MasterState{
+		{Name: "mymaster", Addr: "10.0.0.1:6379", Flags: "s_down,master",
+			NumSlaves: 2, Quorum: 2, LastOKPing: 150 * time.Millisecond},
+		{Name: "other", Addr: "10.0.0.5:6380", Flags: "master"},
+	}
+	for i := range masters {
+		masters[i].Fields = nil
+	}
+	if !reflect.DeepEqual(masters, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, masters)
+	}
+
+	replies = []interface{}{[]interface{}{
+		[]interface{}{
+			[]byte("name"), []byte("mymaster"), []byte("ip"), []byte("10.0.0.1"),
+			[]byte("port"), []byte("6379"), []byte("quorum"), []byte("two"),
+		},
+	}}
+	if _, err := queryForMasterStates(replyConn{replies: &replies}); err == nil {
+		t.Fatal("malformed quorum must fail")
+	}
+}
+
+func TestSentinelMasters(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	masters, err := s.Masters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(masters) != 1 || masters[0].Name != "mymaster" ||
+		masters[0].Addr != cluster.Master.Addr() || masters[0].NumSlaves != 2 ||
+		masters[0].Quorum != 2 || masters[0].Flags != "master" {
+		t.Fatalf("unexpected masters %+v", masters)
+	}
+}
+
+func TestSentinelMasterAddrs(t *testing.T) {
+	replies := []interface{}{[]interface{}{
+		[]interface{}{
+			[]byte("name"), []byte("mymaster"), []byte("ip"), []byte("10.0.0.1"),
+			[]byte("port"), []byte("6379"), []byte("flags"), []byte("master"),
+		},
+		[]interface{}{
+			[]byte("name"), []byte("other"), []byte("ip"), []byte("10.0.0.5"),
+			[]byte("port"), []byte("6380"), []byte("flags"), []byte("master"),
+		},
+	}}
+	masters, err := queryForMasters(replyConn{replies: &replies})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"mymaster": "10.0.0.1:6379", "other": "10.0.0.5:6380"}
+	if !reflect.DeepEqual(masters, expected) {
+		t.Fatalf("expected %v, got %v", expected, masters)
+	}
+
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	// name sentinel is created with does not limit masters returned
+	s := NewSentinel(cluster.SentinelAddrs(), "unknown")
+	defer s.Close()
+	cluster.Failover(cluster.Replicas[0])
+	masters, err = s.MasterAddrs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]string{"mymaster": cluster.Master.Addr()}
+	if !reflect.DeepEqual(masters, expected) {
+		t.Fatalf("expected %v, got %v", expected, masters)
+	}
+}
+
+func TestSentinelMasterState(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	state, err := s.MasterState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Name != "mymaster" || state.Addr != cluster.Master.Addr() ||
+		state.NumSlaves != 1 || state.NumOtherSentinels != 1 || state.Fields["runid"] == "" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	s = NewSentinel(cluster.SentinelAddrs(), "unknown")
+	defer s.Close()
+	if _, err := s.MasterState(); !isUnknownMaster(err) {
+		t.Fatalf("expected unknown master error, got %v", err)
+	}
+}
+
+func TestParseMasterState(t *testing.T) {
+	state, err := parseMasterState(map[string]string{
+		"name": "mymaster", "ip": "10.0.0.1", "port": "6379",
+		"config-epoch": "7", "down-after-milliseconds": "5000", "failover-timeout": "60000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.ConfigEpoch != 7 || state.DownAfter != 5*time.Second ||
+		state.FailoverTimeout != time.Minute {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
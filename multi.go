@@ -0,0 +1,229 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// MultiSentinelPool manages SentinelPool for each of several masters
+// monitored by the same sentinels. Master switches and replica changes of
+// all masters are watched with a single subscription instead of one per
+// master.
+type MultiSentinelPool struct {
+	pools map[string]*SentinelPool
+	// first is pool whose Sentinel events are subscribed with.
+	first *SentinelPool
+
+	mu      sync.Mutex
+	watcher *MasterSentinel
+	closed  bool
+	done    chan struct{}
+}
+
+// NewMultiSentinelPool creates pool of connections to every master in
+// masterNames, all configured with the same options. Pools of masters
+// resolved so far are closed if one fails to resolve, see
+// NewSentinelPoolE.
+func NewMultiSentinelPool(addrs []string, masterNames []string,
+	defaultDb int, password string, options ...PoolOption) (*MultiSentinelPool, error) {
+	if len(masterNames) == 0 {
+		return nil, errors.New("redigo: no master names configured")
+	}
+	m := &MultiSentinelPool{
+		pools: make(map[string]*SentinelPool, len(masterNames)),
+		done:  make(chan struct{}),
+	}
+	options = append(options[:len(options):len(options)], func(o *poolOptions) {
+		o.sharedMonitor = true
+	})
+	for _, name := range masterNames {
+		if _, ok := m.pools[name]; ok {
+			continue
+		}
+		sp, err := NewSentinelPoolE(addrs, name, defaultDb, password, options...)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.pools[name] = sp
+		if m.first == nil {
+			m.first = sp
+		}
+	}
+	go m.first._runGuarded("multi monitor", m.watch)
+	return m, nil
+}
+
+// Get gets connection to master with masterName. If pool does not manage
+// such master, connection fails with ErrUnknownMaster.
+// redis.Conn must Close after use
+func (m *MultiSentinelPool) Get(masterName string) redis.Conn {
+	sp, ok := m.pools[masterName]
+	if !ok {
+		return errorConn{ErrUnknownMaster}
+	}
+	return sp.Get()
+}
+
+// GetContext is like Get, but waits for connection at most until ctx is
+// done, see SentinelPool.GetContext.
+func (m *MultiSentinelPool) GetContext(ctx context.Context, masterName string) (redis.Conn, error) {
+	sp, ok := m.pools[masterName]
+	if !ok {
+		return errorConn{ErrUnknownMaster}, ErrUnknownMaster
+	}
+	return sp.GetContext(ctx)
+}
+
+// Pool returns pool of master with masterName, or nil if it is not
+// managed. It must not be closed by caller.
+func (m *MultiSentinelPool) Pool(masterName string) *SentinelPool {
+	return m.pools[masterName]
+}
+
+// MasterNames returns names of managed masters in alphabetical order.
+func (m *MultiSentinelPool) MasterNames() []string {
+	names := make([]string, 0, len(m.pools))
+	for name := range m.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close closes pools of all masters and the shared subscription.
+func (m *MultiSentinelPool) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.done)
+	watcher := m.watcher
+	m.mu.Unlock()
+	if watcher != nil {
+		watcher.Close()
+	}
+	for _, sp := range m.pools {
+		sp.Close()
+	}
+}
+
+// watch subscribes to events of all masters and dispatches them to their
+// pools until closed. After subscription was lost every pool resolves its
+// master again, as switches may have been missed meanwhile.
+func (m *MultiSentinelPool) watch() {
+	sntl := m.first.sntl
+	failures := 0
+	for resubscribed := false; ; resubscribed = true {
+		select {
+		case <-m.done:
+			return
+		default:
+		}
+		ms, err := sntl.subscribe(sntl.eventChannels(), false)
+		if err != nil {
+			logFields(sntl.Logger, LogError, "subscribe to master switches failed",
+				"masters", len(m.pools), "err", err)
+			failures++
+			m.first.monitorFailed(err, failures)
+			continue
+		}
+		subscribed := m.first.clock().Now()
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			ms.Close()
+			return
+		}
+		m.watcher = ms
+		m.mu.Unlock()
+		var wg sync.WaitGroup
+		for name, sp := range m.pools {
+			switches, _ := ms.WatchMaster(name)
+			wg.Add(1)
+			go func(sp *SentinelPool) {
+				defer wg.Done()
+				sp._runGuarded("multi switches", func() {
+					m.follow(sp, switches)
+				})
+			}(sp)
+		}
+		if resubscribed {
+			m.resync()
+		}
+		if events, err := ms.Events(); err == nil {
+			for ev := range events {
+				m.dispatch(ev)
+			}
+		}
+		err = ms.Err()
+		ms.Close()
+		wg.Wait()
+		// failover can not be followed without subscription
+		for _, sp := range m.pools {
+			sp.gate.open()
+		}
+		m.mu.Lock()
+		m.watcher = nil
+		closed := m.closed
+		m.mu.Unlock()
+		if closed {
+			return
+		}
+		if m.first.clock().Now().Sub(subscribed) >= stableSubscription {
+			failures = 0
+		} else {
+			if err == nil {
+				err = errSubscriptionLost
+			}
+			failures++
+			m.first.monitorFailed(err, failures)
+		}
+	}
+}
+
+// follow switches sp to masters reported on switches until subscription
+// ends. Every pool follows its own switches, so verifying master of one
+// does not hold back the others.
+func (m *MultiSentinelPool) follow(sp *SentinelPool, switches <-chan MasterSwitchEvent) {
+	for e := range switches {
+		// pools not resolved yet look master up on first use
+		if e.Replay || sp.MasterAddr() == "" {
+			continue
+		}
+		sp.masterReported(e.NewAddr, "switch-master", e.Time)
+	}
+}
+
+// dispatch delivers instance event to every pool. Master switches are
+// followed by pools themselves, see follow.
+func (m *MultiSentinelPool) dispatch(ev Event) {
+	if ev.Replay || ev.Channel == ChannelSwitchMaster {
+		return
+	}
+	for _, sp := range m.pools {
+		sp.instanceEvent(ev)
+	}
+}
+
+// resync makes every pool switch to master sentinels currently report.
+func (m *MultiSentinelPool) resync() {
+	for _, sp := range m.pools {
+		if sp.MasterAddr() == "" {
+			continue
+		}
+		addr, err := sp.sntl.MasterAddr()
+		if err != nil {
+			logFields(sp.logger(), LogWarn, "resolve master after resubscribe failed",
+				"master", sp.sntl.masterName(), "err", err)
+			continue
+		}
+		sp.masterReported(addr, "resubscribe", sp.clock().Now())
+	}
+}
@@ -0,0 +1,117 @@
+package sentinel
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestMultiSentinelPool(t *testing.T) {
+	first, err := sentineltest.NewCluster("first", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := sentineltest.NewCluster("second", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	addrs := append(first.SentinelAddrs(), second.SentinelAddrs()...)
+	m, err := NewMultiSentinelPool(addrs, []string{"second", "first"}, 0, "",
+		WithTopologyLogLevel(LogOff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if names := m.MasterNames(); len(names) != 2 || names[0] != "first" || names[1] != "second" {
+		t.Fatalf("unexpected master names %v", names)
+	}
+	if _, err := redis.String(m.Get("third").Do("PING")); err != ErrUnknownMaster {
+		t.Fatalf("expected ErrUnknownMaster, got %v", err)
+	}
+	for _, name := range m.MasterNames() {
+		c := m.Get(name)
+		if _, err := redis.String(c.Do("PING")); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		m.Pool(name).mu.RLock()
+		watcher := m.Pool(name).masterWatcher
+		m.Pool(name).mu.RUnlock()
+		if watcher != nil {
+			t.Fatalf("pool of %s must not subscribe on its own", name)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		m.mu.Lock()
+		watching := m.watcher != nil
+		m.mu.Unlock()
+		if watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pool did not subscribe to master switches")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// fake sentinels monitor one master each, so switch is announced by
+	// all of them as if they monitored both
+	promoted := second.Replicas[0]
+	promoted.Promote()
+	oldHost, oldPort, _ := net.SplitHostPort(second.Master.Addr())
+	newHost, newPort, _ := net.SplitHostPort(promoted.Addr())
+	payload := strings.Join([]string{"second", oldHost, oldPort, newHost, newPort}, " ")
+	for _, s := range append(first.Sentinels, second.Sentinels...) {
+		s.Publish(ChannelSwitchMaster, payload)
+	}
+	for deadline := time.Now().Add(5 * time.Second); m.Pool("second").MasterAddr() != promoted.Addr(); {
+		if time.Now().After(deadline) {
+			t.Fatal("master switch was not dispatched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if addr := m.Pool("first").MasterAddr(); addr != first.Master.Addr() {
+		t.Fatalf("switch of another master moved pool to %s", addr)
+	}
+}
+
+func TestMultiSentinelPoolSubscriptionLost(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	m, err := NewMultiSentinelPool(cluster.SentinelAddrs(), []string{"mymaster"}, 0, "",
+		WithTopologyLogLevel(LogOff), WithFailoverWait(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	sp := m.Pool("mymaster")
+
+	var watcher *MasterSentinel
+	for deadline := time.Now().Add(5 * time.Second); watcher == nil; {
+		m.mu.Lock()
+		watcher = m.watcher
+		m.mu.Unlock()
+		if time.Now().After(deadline) {
+			t.Fatal("pool did not subscribe to master switches")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	down := "master mymaster " + strings.Replace(cluster.Master.Addr(), ":", " ", 1) + " #quorum 1/1"
+	cluster.Sentinels[0].Publish(ChannelODown, down)
+	waitGate(t, sp, true)
+
+	// failover can not be followed once subscription is lost
+	watcher.Close()
+	waitGate(t, sp, false)
+}
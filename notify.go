@@ -0,0 +1,66 @@
+package sentinel
+
+import "time"
+
+// MasterChange describes switch of address SentinelPool dials.
+type MasterChange struct {
+	MasterName string
+	Old        string
+	New        string
+	// Reason is what caused the change, e.g. switch-master or resume.
+	Reason string
+	Time   time.Time
+}
+
+// MasterChanges returns channel receiving a MasterChange every time address
+// pool dials changes from one master to another. Resolving the initial
+// master is not reported. Channel holds only the latest undelivered change,
+// older ones are discarded. Channel is closed when pool is closed.
+func (p *SentinelPool) MasterChanges() <-chan MasterChange {
+	ch := make(chan MasterChange, 1)
+	p.mu.Lock()
+	if p.closed {
+		close(ch)
+	} else {
+		p.changeSubs = append(p.changeSubs, ch)
+	}
+	p.mu.Unlock()
+	return ch
+}
+
+// removeChangeSub stops delivering changes to ch returned by MasterChanges.
+func (sp *SentinelPool) removeChangeSub(ch <-chan MasterChange) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for i, sub := range sp.changeSubs {
+		if (<-chan MasterChange)(sub) == ch {
+			sp.changeSubs = append(sp.changeSubs[:i], sp.changeSubs[i+1:]...)
+			return
+		}
+	}
+}
+
+// _notifyChange delivers change to MasterChanges subscribers without
+// blocking, replacing undelivered change if there is one, and queues it for
+// OnMasterSwitch hook.
+// Lock must be held by caller.
+func (sp *SentinelPool) _notifyChange(change MasterChange) {
+	sp._queueMasterSwitch(change)
+	for _, ch := range sp.changeSubs {
+		// only senders hold the lock, so channel has room once drained
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- change
+	}
+}
+
+// _closeChangeSubs closes channels of MasterChanges subscribers.
+// Lock must be held by caller.
+func (sp *SentinelPool) _closeChangeSubs() {
+	for _, ch := range sp.changeSubs {
+		close(ch)
+	}
+	sp.changeSubs = nil
+}
@@ -0,0 +1,34 @@
+package sentinel
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMasterChanges(t *testing.T) {
+	sp := &SentinelPool{
+		sntl:    &Sentinel{MasterName: "mymaster"},
+		mu:      &sync.RWMutex{},
+		opts:    poolOptions{topologyLogLevel: LogOff},
+		curAddr: "10.0.0.1:6379",
+	}
+	changes := sp.MasterChanges()
+
+	sp.mu.Lock()
+	sp._adoptMaster("10.0.0.1:6379", "switch-master")
+	sp._adoptMaster("10.0.0.2:6379", "switch-master")
+	sp._adoptMaster("10.0.0.3:6379", "resume")
+	sp.mu.Unlock()
+
+	change := <-changes
+	if change.Old != "10.0.0.2:6379" || change.New != "10.0.0.3:6379" || change.Reason != "resume" {
+		t.Fatalf("unexpected change %+v", change)
+	}
+
+	sp.mu.Lock()
+	sp._closeChangeSubs()
+	sp.mu.Unlock()
+	if _, ok := <-changes; ok {
+		t.Fatal("changes channel must be closed")
+	}
+}
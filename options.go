@@ -0,0 +1,542 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/tls"
+	"math/rand"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// PoolOption configures optional behaviour of SentinelPool.
+type PoolOption func(*poolOptions)
+
+type poolOptions struct {
+	lazyInit            bool
+	clientFlags         ClientFlags
+	replicaClientFlags  ClientFlags
+	probeClientFlags    ClientFlags
+	sentinelClientFlags ClientFlags
+	bootstrap           *MonitorSeed
+	sentinelTiers       [][]string
+	waitTimeout         time.Duration
+	dialBudget          float64
+	dialBurst           int
+	topologyLogLevel    LogLevel
+	failoverTrace       func(FailoverTrace)
+	restartPolicy       *RestartPolicy
+	onPanic             func(goroutine string, v interface{})
+	ctx                 context.Context
+	topology            *Topology
+	strict              bool
+	strictInterval      time.Duration
+	healthInterval      time.Duration
+	clock               Clock
+	credentials         CredentialsFunc
+	family              AddressFamily
+	rand                *rand.Rand
+	sentinelPing        time.Duration
+	idleReaper          IdleReaper
+	sentinelIdleReaper  IdleReaper
+	poolFactory         func(dial func() (redis.Conn, error)) *redis.Pool
+	maxActive           int
+	wait                bool
+	dialTimeout         time.Duration
+	testOnBorrow        func(c redis.Conn, t time.Time) error
+	sentinelAuth        Credentials
+	username            string
+	tlsConfig           *tls.Config
+	clientCert          func() (*tls.Certificate, error)
+	logger              Logger
+	tracer              Tracer
+	hooks               Hooks
+	pollInterval        time.Duration
+	pollHybrid          bool
+	monitorBackoff      *Backoff
+	keepalive           time.Duration
+	verifyTimeout       time.Duration
+	drainOnSwitch       bool
+	roleOnBorrow        bool
+	failoverWait        time.Duration
+	maxConnLifetime     time.Duration
+	dialOptions         []redis.DialOption
+	sharedMonitor       bool
+	replicaSelector     Selector
+	maxLagBytes         int64
+	maxLinkDown         time.Duration
+}
+
+// WithLazyInit makes NewSentinelPool return without contacting sentinels.
+// Master address is resolved on first Get instead, so application does not
+// depend on sentinels being up at startup.
+func WithLazyInit() PoolOption {
+	return func(o *poolOptions) {
+		o.lazyInit = true
+	}
+}
+
+// WithClientFlags sets CLIENT options issued on every new master connection.
+func WithClientFlags(flags ClientFlags) PoolOption {
+	return func(o *poolOptions) {
+		o.clientFlags = flags
+	}
+}
+
+// WithReplicaClientFlags sets CLIENT options issued on every new connection
+// to replicas, e.g. NoTouch so that reads do not alter keys LRU.
+func WithReplicaClientFlags(flags ClientFlags) PoolOption {
+	return func(o *poolOptions) {
+		o.replicaClientFlags = flags
+	}
+}
+
+// WithProbeClientFlags sets CLIENT options issued on connections pool
+// dials to masters and replicas for its own checks, like role verification,
+// health checks and replica lag probes.
+func WithProbeClientFlags(flags ClientFlags) PoolOption {
+	return func(o *poolOptions) {
+		o.probeClientFlags = flags
+	}
+}
+
+// WithSentinelClientFlags sets CLIENT options issued on every new connection
+// to sentinels, including the one subscribed to master switch events.
+func WithSentinelClientFlags(flags ClientFlags) PoolOption {
+	return func(o *poolOptions) {
+		o.sentinelClientFlags = flags
+	}
+}
+
+// WithBootstrap makes sentinels monitor seed master if they do not know
+// master name pool was created for. See Sentinel.Monitor.
+func WithBootstrap(seed MonitorSeed) PoolOption {
+	return func(o *poolOptions) {
+		o.bootstrap = &seed
+	}
+}
+
+// WithSentinelTiers sets priority tiers of sentinel addresses, see
+// Sentinel.Tiers. Addresses from tiers missing in pool addresses are added.
+func WithSentinelTiers(tiers ...[]string) PoolOption {
+	return func(o *poolOptions) {
+		o.sentinelTiers = tiers
+	}
+}
+
+// WithWaitTimeout makes Get wait for a connection when pool is at MaxActive
+// limit, but no longer than d. Get returns connection failing with
+// PoolExhausted error if none became available in time.
+func WithWaitTimeout(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.waitTimeout = d
+	}
+}
+
+// WithDialBudget limits failed master dial attempts to perSecond on average
+// with bursts of up to burst attempts. When budget is spent Get fails fast
+// with ErrDialBudgetExhausted instead of dialing unreachable master.
+func WithDialBudget(perSecond float64, burst int) PoolOption {
+	return func(o *poolOptions) {
+		o.dialBudget = perSecond
+		o.dialBurst = burst
+	}
+}
+
+// WithLogger sets logger pool and its Sentinel log to, see Logger.
+// Nothing is logged by default.
+func WithLogger(logger Logger) PoolOption {
+	return func(o *poolOptions) {
+		o.logger = logger
+	}
+}
+
+// WithHooks sets callbacks pool and its Sentinel notify of lifecycle
+// events, see Hooks.
+func WithHooks(hooks Hooks) PoolOption {
+	return func(o *poolOptions) {
+		o.hooks = hooks
+	}
+}
+
+// WithTracer sets tracer pool and its Sentinel start spans with, see
+// Tracer. Nothing is traced by default.
+func WithTracer(tracer Tracer) PoolOption {
+	return func(o *poolOptions) {
+		o.tracer = tracer
+	}
+}
+
+// WithTopologyLogLevel sets level master changes, sentinel reordering and
+// replica membership changes are logged at. Debug is used by default.
+func WithTopologyLogLevel(level LogLevel) PoolOption {
+	return func(o *poolOptions) {
+		o.topologyLogLevel = level
+	}
+}
+
+// WithFailoverTracing enables tracing of failover handling. Trace of every
+// failover is passed to report once pool connected to new master.
+func WithFailoverTracing(report func(FailoverTrace)) PoolOption {
+	return func(o *poolOptions) {
+		o.failoverTrace = report
+	}
+}
+
+// WithRestartPolicy sets how background goroutines watching for master
+// switch are restarted after panic. By default they are restarted forever
+// with one second delay.
+func WithRestartPolicy(policy RestartPolicy) PoolOption {
+	return func(o *poolOptions) {
+		o.restartPolicy = &policy
+	}
+}
+
+// WithMonitorBackoff sets how long pool waits before subscribing to master
+// switch events again after subscription failed. By default delay starts at
+// a second and doubles up to 30 seconds, with +/-20% jitter.
+func WithMonitorBackoff(backoff Backoff) PoolOption {
+	return func(o *poolOptions) {
+		o.monitorBackoff = &backoff
+	}
+}
+
+// WithSubscriptionKeepalive makes pool PING its subscription to master
+// switch events at interval, so that dead connection to Sentinel is noticed
+// and subscription is made again, see Sentinel.SubscriptionKeepalive.
+func WithSubscriptionKeepalive(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.keepalive = interval
+	}
+}
+
+// WithPanicHandler sets function called with value recovered from panic in
+// background goroutine, in addition to it being logged.
+func WithPanicHandler(handler func(goroutine string, v interface{})) PoolOption {
+	return func(o *poolOptions) {
+		o.onPanic = handler
+	}
+}
+
+// WithContext binds pool lifetime to ctx: once ctx is done pool is closed,
+// stopping background goroutines and closing subscriptions and connections.
+func WithContext(ctx context.Context) PoolOption {
+	return func(o *poolOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithStrictConsistency makes pool verify every connection it hands out is
+// connected to a master, so no command is ever sent to a stale one. Master
+// role is queried with ROLE at most once per interval, connections that fail
+// verification are closed and Get returns NotMaster error.
+func WithStrictConsistency(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.strict = true
+		o.strictInterval = interval
+	}
+}
+
+// WithHealthCheck makes pool check every interval that address it dials
+// still has master role. If it was demoted without pool receiving
+// switch-master event, master is resolved through sentinels again and
+// change is reported like a switch with reason "demotion".
+func WithHealthCheck(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.healthInterval = interval
+	}
+}
+
+// WithMasterVerification makes pool query ROLE of new master reported by
+// sentinels, retrying while it is still loading or not yet promoted, before
+// switching to it. If master role is not verified within timeout, pool
+// switches anyway, as sentinels agreed on it, and logs a warning. Switches
+// noticed by subscription or polling are delayed by verification, initial
+// resolve is not.
+func WithMasterVerification(timeout time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.verifyTimeout = timeout
+	}
+}
+
+// WithDrainOnSwitch makes pool close its connections to previous master
+// once it switches to another one, instead of leaving idle ones to expire
+// with IdleTimeout, so every Get after switch hits new master. Idle
+// connections are closed right away and borrowed ones when they are
+// returned.
+func WithDrainOnSwitch() PoolOption {
+	return func(o *poolOptions) {
+		o.drainOnSwitch = true
+	}
+}
+
+// WithFailoverWait makes Get and GetContext block while failover of master
+// is in progress instead of handing out connections to master which is
+// down. Failover is in progress from master reported objectively down or
+// switch event received until new master is adopted, after its role is
+// verified if WithMasterVerification is used. If it does not finish within
+// wait, Get fails with ErrFailoverInProgress.
+func WithFailoverWait(wait time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.failoverWait = wait
+	}
+}
+
+// WithPolling makes pool detect failover by asking sentinels for master
+// address every interval instead of subscribing to switch-master events,
+// for networks which drop long-lived pub/sub connections. Switches are
+// noticed up to interval late and reported with reason "poll". Replicas
+// are only looked up again after master changes.
+func WithPolling(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.pollInterval = interval
+		o.pollHybrid = false
+	}
+}
+
+// WithHybridPolling makes pool both subscribe to switch-master events and
+// ask sentinels for master address every interval, as a safety net for
+// missed events, e.g. when subscription is silently dead. Interval can be
+// long as events still switch pool immediately. As a single poll may be
+// answered by sentinel not aware of failover yet, poll disagreeing with
+// subscription switches pool only once it is repeated on consecutive polls
+// or sentinels report newer configuration epoch. Switches only noticed by
+// polling are logged as warnings and reported with reason "poll".
+func WithHybridPolling(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.pollInterval = interval
+		o.pollHybrid = true
+	}
+}
+
+// WithSentinelHealthCheck makes pool PING every known Sentinel at interval,
+// so sentinels which went down are noticed and tried last before a query
+// fails on them. Results are reported in Stats. See Sentinel.PingSentinels.
+func WithSentinelHealthCheck(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.sentinelPing = interval
+	}
+}
+
+// WithIdleReaper sets how many idle master connections pool keeps and for
+// how long, by default 16 for 240 seconds. Both can later be changed with
+// Reload.
+func WithIdleReaper(r IdleReaper) PoolOption {
+	return func(o *poolOptions) {
+		o.idleReaper = r
+	}
+}
+
+// WithMaxIdle sets maximum number of idle master connections pool keeps,
+// 16 by default. See WithIdleReaper.
+func WithMaxIdle(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.idleReaper.MaxIdle = n
+	}
+}
+
+// WithIdleTimeout closes master connections which remained idle longer than
+// d, 240 seconds by default. Zero keeps them forever. See WithIdleReaper.
+func WithIdleTimeout(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		if d <= 0 {
+			d = -1
+		}
+		o.idleReaper.MaxIdleAge = d
+	}
+}
+
+// WithMaxActive limits number of connections pool allocates to master at a
+// given time. There is no limit by default. It can later be changed with
+// Reload.
+func WithMaxActive(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.maxActive = n
+	}
+}
+
+// WithMaxConnLifetime makes pool recycle master and replica connections
+// older than d, closing them when they are returned or taken from pool, so
+// long-lived connections are spread over time and one left connected to a
+// stale master after topology change lingers no longer than d. Connections
+// are kept regardless of age by default. It can later be changed with
+// Reload.
+func WithMaxConnLifetime(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.maxConnLifetime = d
+	}
+}
+
+// WithWait makes Get wait for a connection when pool is at MaxActive limit
+// instead of failing right away. See WithWaitTimeout to bound waiting.
+func WithWait(wait bool) PoolOption {
+	return func(o *poolOptions) {
+		o.wait = wait
+	}
+}
+
+// WithDialTimeout sets timeout of connecting to master and replicas, 10
+// seconds by default.
+func WithDialTimeout(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.dialTimeout = d
+	}
+}
+
+// WithDialOptions sets redigo dial options applied when dialing master,
+// e.g. redis.DialReadTimeout or redis.DialTLSSkipVerify. They override
+// options pool sets itself, including 10 second read and write timeouts and
+// TLS settings derived from WithTLS. Pool dials TCP connections itself, so
+// redis.DialConnectTimeout and redis.DialKeepAlive have no effect unless
+// redis.DialNetDial is given too, which replaces WithDialTimeout and
+// WithAddressFamily. Authentication and database selection are still done
+// by pool.
+func WithDialOptions(options ...redis.DialOption) PoolOption {
+	return func(o *poolOptions) {
+		o.dialOptions = append(o.dialOptions, options...)
+	}
+}
+
+// WithTestOnBorrow sets function checking health of idle connection before
+// it is handed out, like redis.Pool TestOnBorrow. Connection is closed and
+// another one is taken if it returns error. It applies to master and
+// replica connections.
+func WithTestOnBorrow(test func(c redis.Conn, t time.Time) error) PoolOption {
+	return func(o *poolOptions) {
+		o.testOnBorrow = test
+	}
+}
+
+// WithRoleCheckOnBorrow makes pool query ROLE of every idle master
+// connection before it is handed out, and discard it if instance is no
+// longer a master, e.g. when connection survived failover. Unlike
+// WithStrictConsistency, role is queried on every borrow and freshly dialed
+// connections are not checked.
+func WithRoleCheckOnBorrow() PoolOption {
+	return func(o *poolOptions) {
+		o.roleOnBorrow = true
+	}
+}
+
+// WithReplicaSelector sets Selector picking replica GetReplica connects
+// to, RoundRobinSelector by default.
+func WithReplicaSelector(selector Selector) PoolOption {
+	return func(o *poolOptions) {
+		o.replicaSelector = selector
+	}
+}
+
+// WithReplicaLagGuard makes GetReplica skip replicas whose replication
+// offset is more than maxBytes behind master one, or whose link to master is
+// down for longer than maxLinkDown as Sentinel reports, to avoid badly stale
+// reads. Zero disables either check. Replicas are checked every second
+// once GetReplica is first called, over connections not counted in Stats.
+func WithReplicaLagGuard(maxBytes int64, maxLinkDown time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.maxLagBytes = maxBytes
+		o.maxLinkDown = maxLinkDown
+	}
+}
+
+// WithSentinelIdleReaper sets how many idle connections pool keeps to every
+// Sentinel and for how long, by default 3 for 240 seconds.
+func WithSentinelIdleReaper(r IdleReaper) PoolOption {
+	return func(o *poolOptions) {
+		o.sentinelIdleReaper = r
+	}
+}
+
+// WithPool makes pool hand out connections from pool built by factory, so
+// its tuning and instrumentation can be kept. Factory must set Dial of
+// returned pool to dial, which connects to current master; it can be
+// wrapped, e.g. to measure dials. Sizes from PoolConfig are not applied
+// to pools factory builds, and WithWaitTimeout has effect only if factory
+// sets Wait. Dials made with dial are not aborted by ctx of GetContext.
+// Factory is called again whenever pool has to be replaced, see
+// SentinelPool.Pool.
+func WithPool(factory func(dial func() (redis.Conn, error)) *redis.Pool) PoolOption {
+	return func(o *poolOptions) {
+		o.poolFactory = factory
+	}
+}
+
+// WithClock sets Clock pool uses for retry delays, health checks, dial
+// budget and timestamps it reports. Real time is used by default.
+func WithClock(c Clock) PoolOption {
+	return func(o *poolOptions) {
+		o.clock = c
+	}
+}
+
+// WithRandSource sets source of randomness pool uses for retry jitter,
+// RandomSelector and other random choices. Pools sharing a seed behave
+// reproducibly, e.g. in tests, while distinct seeds decorrelate instances.
+// Global math/rand source is used by default.
+func WithRandSource(src rand.Source) PoolOption {
+	return func(o *poolOptions) {
+		o.rand = rand.New(&lockedSource{src: src})
+	}
+}
+
+// WithCredentials sets function returning credentials for every master,
+// replica and sentinel connection pool makes. Password passed to
+// NewSentinelPool is not used when it is set.
+func WithCredentials(f CredentialsFunc) PoolOption {
+	return func(o *poolOptions) {
+		o.credentials = f
+	}
+}
+
+// WithUsername sets ACL user new master and replica connections are
+// authenticated as, together with password passed to NewSentinelPool.
+// Sentinels are authenticated separately, see WithSentinelAuth.
+func WithUsername(username string) PoolOption {
+	return func(o *poolOptions) {
+		o.username = username
+	}
+}
+
+// WithSentinelAuth makes pool AUTH every connection to sentinels with
+// password, and with username unless it is empty, see
+// Sentinel.SentinelPassword.
+func WithSentinelAuth(username, password string) PoolOption {
+	return func(o *poolOptions) {
+		o.sentinelAuth = Credentials{Username: username, Password: password}
+	}
+}
+
+// WithAddressFamily sets which address families pool uses to reach
+// sentinels, master and replicas, see AddressFamily.
+func WithAddressFamily(family AddressFamily) PoolOption {
+	return func(o *poolOptions) {
+		o.family = family
+	}
+}
+
+// WithTLS makes pool connect to sentinels, master and replicas over TLS
+// with config, see Sentinel.TLSConfig.
+func WithTLS(config *tls.Config) PoolOption {
+	return func(o *poolOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithClientCertificate makes pool present certificate returned by provider
+// in TLS handshakes with sentinels, master and replicas. Provider is called
+// for every new connection, so rotated certificates are picked up without
+// restarting pool, see KeyPairReloader. It implies WithTLS, with default
+// config unless one is given.
+func WithClientCertificate(provider func() (*tls.Certificate, error)) PoolOption {
+	return func(o *poolOptions) {
+		o.clientCert = provider
+	}
+}
+
+// WithTopology starts pool with topology exported by another process, see
+// Sentinel.Export. If it contains master of pool, pool uses it right away
+// instead of asking sentinels.
+func WithTopology(t Topology) PoolOption {
+	return func(o *poolOptions) {
+		o.topology = &t
+	}
+}
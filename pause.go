@@ -0,0 +1,29 @@
+package sentinel
+
+// Pause freezes automatic master switching: pool keeps dialing current
+// master even if sentinels announce a new one, e.g. during controlled data
+// migration. Latest announced master is remembered and applied on Resume.
+func (p *SentinelPool) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+}
+
+// Resume continues automatic master switching paused by Pause, switching
+// to master announced while paused, if any.
+func (p *SentinelPool) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	if p.pendingAddr != "" {
+		p._adoptMaster(p.pendingAddr, "resume")
+		p.pendingAddr = ""
+	}
+	p.mu.Unlock()
+}
+
+// Paused reports whether master switching is paused.
+func (p *SentinelPool) Paused() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.paused
+}
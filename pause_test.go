@@ -0,0 +1,71 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolPause(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+	waitWatching(t, sp)
+
+	oldAddr, newAddr := cluster.Master.Addr(), cluster.Replicas[0].Addr()
+	sp.Pause()
+	if !sp.Paused() || !sp.Stats().MonitorPaused {
+		t.Fatal("pool is not paused")
+	}
+	cluster.Failover(cluster.Replicas[0])
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		sp.mu.RLock()
+		pending := sp.pendingAddr
+		sp.mu.RUnlock()
+		if pending == newAddr {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("master switch was not noticed while paused")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if addr := sp.MasterAddr(); addr != oldAddr {
+		t.Fatalf("paused pool switched to %s", addr)
+	}
+	select {
+	case change := <-changes:
+		t.Fatalf("unexpected change while paused %+v", change)
+	default:
+	}
+
+	sp.Resume()
+	if sp.Paused() {
+		t.Fatal("pool is still paused")
+	}
+	if addr := sp.MasterAddr(); addr != newAddr {
+		t.Fatalf("announced master not adopted on resume, got %s", addr)
+	}
+	select {
+	case change := <-changes:
+		if change.Old != oldAddr || change.New != newAddr {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master change was not reported on resume")
+	}
+	// nothing is pending after resume
+	sp.Pause()
+	sp.Resume()
+	if addr := sp.MasterAddr(); addr != newAddr {
+		t.Fatalf("unexpected master %s", addr)
+	}
+}
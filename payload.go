@@ -0,0 +1,179 @@
+package sentinel
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Sentinel event channel names.
+const (
+	ChannelResetMaster         = "+reset-master"
+	ChannelSlave               = "+slave"
+	ChannelFailoverStateReconf = "+failover-state-reconf-slaves"
+	ChannelFailoverDetected    = "+failover-detected"
+	ChannelSlaveReconfSent     = "+slave-reconf-sent"
+	ChannelSlaveReconfInprog   = "+slave-reconf-inprog"
+	ChannelSlaveReconfDone     = "+slave-reconf-done"
+	ChannelDupSentinel         = "-dup-sentinel"
+	ChannelSentinel            = "+sentinel"
+	ChannelSDown               = "+sdown"
+	ChannelSDownCleared        = "-sdown"
+	ChannelODown               = "+odown"
+	ChannelODownCleared        = "-odown"
+	ChannelNewEpoch            = "+new-epoch"
+	ChannelTryFailover         = "+try-failover"
+	ChannelVoteForLeader       = "+vote-for-leader"
+	ChannelElectedLeader       = "+elected-leader"
+	ChannelSelectSlave         = "+failover-state-select-slave"
+	ChannelNoGoodSlave         = "-failover-abort-no-good-slave"
+	ChannelSelectedSlave       = "+selected-slave"
+	ChannelSendSlaveofNoone    = "+failover-state-send-slaveof-noone"
+	ChannelFailoverEndTimeout  = "+failover-end-for-timeout"
+	ChannelFailoverEnd         = "+failover-end"
+	ChannelSwitchMaster        = "+switch-master"
+	ChannelConvertToSlave      = "+convert-to-slave"
+	ChannelTilt                = "+tilt"
+	ChannelTiltCleared         = "-tilt"
+)
+
+// MalformedPayload is returned by event payload parsers when payload does not
+// match expected format.
+type MalformedPayload struct {
+	Payload string
+}
+
+func (mp MalformedPayload) Error() string {
+	return fmt.Sprintf("redigo: malformed sentinel event payload %q", mp.Payload)
+}
+
+// InstanceEvent is a payload of events about a single instance, formatted as
+// "<instance type> <name> <ip> <port> @ <master name> <master ip> <master port>".
+// The part starting with "@" is present only when instance is not a master.
+type InstanceEvent struct {
+	// Type is an instance type: master, slave or sentinel.
+	Type string
+	Name string
+	IP   string
+	Port string
+
+	MasterName string
+	MasterIP   string
+	MasterPort string
+
+	// Extra is an event specific remainder of payload, e.g. "#quorum 2/2"
+	// in +odown.
+	Extra string
+}
+
+// Addr returns an address of instance.
+func (e InstanceEvent) Addr() string {
+	return net.JoinHostPort(e.IP, e.Port)
+}
+
+// MasterAddr returns an address of master instance belongs to, or of
+// instance itself when it is a master.
+func (e InstanceEvent) MasterAddr() string {
+	if e.Type == "master" {
+		return e.Addr()
+	}
+	return net.JoinHostPort(e.MasterIP, e.MasterPort)
+}
+
+// ParseInstanceEvent parses payload of instance events such as +sdown,
+// +odown, +slave or +sentinel.
+func ParseInstanceEvent(payload string) (InstanceEvent, error) {
+	f := strings.Fields(payload)
+	if len(f) < 4 {
+		return InstanceEvent{}, MalformedPayload{payload}
+	}
+	e := InstanceEvent{Type: f[0], Name: f[1]}
+	var ok bool
+	if e.IP, e.Port, ok = parseHostPort(f[2], f[3]); !ok {
+		return InstanceEvent{}, MalformedPayload{payload}
+	}
+	rest := f[4:]
+	if len(rest) > 0 && rest[0] == "@" {
+		if len(rest) < 4 {
+			return InstanceEvent{}, MalformedPayload{payload}
+		}
+		e.MasterName = rest[1]
+		if e.MasterIP, e.MasterPort, ok = parseHostPort(rest[2], rest[3]); !ok {
+			return InstanceEvent{}, MalformedPayload{payload}
+		}
+		rest = rest[4:]
+	} else if e.Type == "master" {
+		e.MasterName, e.MasterIP, e.MasterPort = e.Name, e.IP, e.Port
+	}
+	e.Extra = strings.Join(rest, " ")
+	return e, nil
+}
+
+// SwitchMasterEvent is a payload of +switch-master, formatted as
+// "<master name> <old ip> <old port> <new ip> <new port>". Addresses can be
+// hostnames when sentinels run with announce-hostnames, or IPv6 literals.
+type SwitchMasterEvent struct {
+	MasterName string
+	OldIP      string
+	OldPort    string
+	NewIP      string
+	NewPort    string
+	// Extra holds unexpected trailing fields, if any.
+	Extra string
+}
+
+// OldAddr returns an address of former master.
+func (e SwitchMasterEvent) OldAddr() string {
+	return net.JoinHostPort(e.OldIP, e.OldPort)
+}
+
+// NewAddr returns an address of new master.
+func (e SwitchMasterEvent) NewAddr() string {
+	return net.JoinHostPort(e.NewIP, e.NewPort)
+}
+
+// ParseSwitchMaster parses payload of +switch-master. Fields after the
+// expected five are tolerated and kept in Extra.
+func ParseSwitchMaster(payload string) (SwitchMasterEvent, error) {
+	f := strings.Fields(payload)
+	if len(f) < 5 {
+		return SwitchMasterEvent{}, MalformedPayload{payload}
+	}
+	e := SwitchMasterEvent{MasterName: f[0], Extra: strings.Join(f[5:], " ")}
+	var ok bool
+	if e.OldIP, e.OldPort, ok = parseHostPort(f[1], f[2]); !ok {
+		return SwitchMasterEvent{}, MalformedPayload{payload}
+	}
+	if e.NewIP, e.NewPort, ok = parseHostPort(f[3], f[4]); !ok {
+		return SwitchMasterEvent{}, MalformedPayload{payload}
+	}
+	return e, nil
+}
+
+// parseHostPort validates host and port fields of event payload and
+// normalizes them, see NormalizeAddr.
+func parseHostPort(host, port string) (string, string, bool) {
+	host, port, err := normalizeHostPort(host, port)
+	return host, port, err == nil
+}
+
+// VoteForLeaderEvent is a payload of +vote-for-leader, formatted as
+// "<leader run id> <leader epoch>".
+type VoteForLeaderEvent struct {
+	LeaderRunID string
+	Epoch       uint64
+}
+
+// ParseVoteForLeader parses payload of +vote-for-leader.
+func ParseVoteForLeader(payload string) (VoteForLeaderEvent, error) {
+	f := strings.Fields(payload)
+	if len(f) != 2 {
+		return VoteForLeaderEvent{}, MalformedPayload{payload}
+	}
+	epoch, err := strconv.ParseUint(f[1], 10, 64)
+	if err != nil {
+		return VoteForLeaderEvent{}, MalformedPayload{payload}
+	}
+	return VoteForLeaderEvent{LeaderRunID: f[0], Epoch: epoch}, nil
+}
@@ -0,0 +1,107 @@
+package sentinel
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseInstanceEvent(t *testing.T) {
+	e, err := ParseInstanceEvent("slave 10.0.0.2:6379 10.0.0.2 6379 @ mymaster 10.0.0.1 6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "slave" || e.Addr() != "10.0.0.2:6379" || e.MasterName != "mymaster" ||
+		e.MasterAddr() != "10.0.0.1:6379" || e.Extra != "" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	e, err = ParseInstanceEvent("master mymaster 10.0.0.1 6379 #quorum 2/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.MasterName != "mymaster" || e.MasterAddr() != "10.0.0.1:6379" || e.Extra != "#quorum 2/2" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	for _, payload := range []string{"", "master mymaster 10.0.0.1", "slave a 10.0.0.2 6379 @ mymaster"} {
+		if _, err := ParseInstanceEvent(payload); err == nil {
+			t.Fatalf("expected error for %q", payload)
+		}
+	}
+}
+
+func TestParseSwitchMaster(t *testing.T) {
+	e, err := ParseSwitchMaster("mymaster 10.0.0.1 6379 10.0.0.2 6380")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.MasterName != "mymaster" || e.OldAddr() != "10.0.0.1:6379" || e.NewAddr() != "10.0.0.2:6380" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if _, err := ParseSwitchMaster("mymaster 10.0.0.1 6379"); err == nil {
+		t.Fatal("expected error for short payload")
+	}
+}
+
+func TestParseVoteForLeader(t *testing.T) {
+	e, err := ParseVoteForLeader("b6e2bc2d3e1a0a4b 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.LeaderRunID != "b6e2bc2d3e1a0a4b" || e.Epoch != 42 {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if _, err := ParseVoteForLeader("b6e2bc2d3e1a0a4b epoch"); err == nil {
+		t.Fatal("expected error for invalid epoch")
+	}
+}
+
+func TestParseSwitchMasterHostnames(t *testing.T) {
+	for payload, want := range map[string]string{
+		"mymaster redis-0.redis 6379 redis-1.redis 6379":  "redis-1.redis:6379",
+		"mymaster ::1 6379 fe80::2 6380":                  "[fe80::2]:6380",
+		"mymaster [::1] 6379 [fe80::2] 6380":              "[fe80::2]:6380",
+		"  mymaster 10.0.0.1  6379 10.0.0.2 6380 extra  ": "10.0.0.2:6380",
+	} {
+		e, err := ParseSwitchMaster(payload)
+		if err != nil {
+			t.Fatalf("%q: %v", payload, err)
+		}
+		if e.MasterName != "mymaster" || e.NewAddr() != want {
+			t.Fatalf("%q: unexpected event %+v", payload, e)
+		}
+	}
+	for _, payload := range []string{
+		"mymaster 10.0.0.1 6379 10.0.0.2 port",
+		"mymaster 10.0.0.1 6379 10.0.0.2 70000",
+		"mymaster 10.0.0.1 6379 1:2:x 6379",
+		"mymaster 10.0.0.1 6379 [10.0.0.2 6379",
+	} {
+		if _, err := ParseSwitchMaster(payload); err == nil {
+			t.Fatalf("expected error for %q", payload)
+		}
+	}
+}
+
+func FuzzParseSwitchMaster(f *testing.F) {
+	f.Add("mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	f.Add("mymaster redis-0.redis 6379 redis-1.redis 6379")
+	f.Add("mymaster ::1 6379 [fe80::2] 6380 extra")
+	f.Fuzz(func(t *testing.T, payload string) {
+		e, err := ParseSwitchMaster(payload)
+		if err != nil {
+			return
+		}
+		for _, addr := range []string{e.OldAddr(), e.NewAddr()} {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				t.Fatalf("%q: invalid address %q: %v", payload, addr, err)
+			}
+		}
+		again, err := ParseSwitchMaster(strings.Join([]string{
+			e.MasterName, e.OldIP, e.OldPort, e.NewIP, e.NewPort, e.Extra}, " "))
+		if err != nil || again != e {
+			t.Fatalf("%q: reparsed as %+v, %v", payload, again, err)
+		}
+	})
+}
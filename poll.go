@@ -0,0 +1,73 @@
+package sentinel
+
+// hybridPollConfirmations is how many consecutive polls have to disagree
+// with subscription before hybrid polling switches pool, see
+// WithHybridPolling.
+const hybridPollConfirmations = 3
+
+// _pollMaster asks sentinels for master address at poll interval and
+// switches pool when it changes, until pool is closed. In hybrid mode
+// changes are expected to be delivered by subscription first, see
+// confirmPoll.
+func (sp *SentinelPool) _pollMaster() {
+	ticker := sp.clock().NewTicker(sp.opts.pollInterval)
+	defer ticker.Stop()
+	if sp.opts.pollHybrid {
+		// configuration epoch polls are compared with
+		sp.sntl.MasterState()
+	}
+	disagreements := 0
+	for {
+		select {
+		case <-sp.done:
+			return
+		case <-ticker.C():
+		}
+		addr, err := sp.sntl.MasterAddr()
+		if err != nil {
+			logFields(sp.logger(), LogError, "poll master address failed",
+				"master", sp.sntl.masterName(), "err", err)
+			sp.setState(StateDegraded, "poll failed")
+			continue
+		}
+		if sp.opts.pollHybrid {
+			sp.mu.RLock()
+			curAddr, paused := sp.curAddr, sp.paused
+			sp.mu.RUnlock()
+			if paused || curAddr == "" || addr == curAddr {
+				disagreements = 0
+			} else {
+				disagreements++
+				if !sp.confirmPoll(addr, disagreements) {
+					continue
+				}
+				disagreements = 0
+				logFields(sp.logger(), LogWarn, "master switch missed by subscription",
+					"master", sp.sntl.masterName(), "old", curAddr, "new", addr)
+			}
+		}
+		received := sp.clock().Now()
+		verifying, verified := sp.verifyMaster(addr)
+		sp.mu.Lock()
+		sp._switchMaster(addr, "poll", received, verifying, verified)
+		sp.mu.Unlock()
+	}
+}
+
+// confirmPoll reports whether master on addr polled in hybrid mode, which
+// disagrees with subscription on consecutive polls, should be switched to.
+// It is once enough polls disagreed or sentinels report configuration
+// epoch newer than known, as failover bumps it.
+func (sp *SentinelPool) confirmPoll(addr string, disagreements int) bool {
+	if disagreements >= hybridPollConfirmations {
+		return true
+	}
+	known := sp.sntl.configEpoch()
+	state, err := sp.sntl.MasterState()
+	if err == nil && state.Addr == addr && state.ConfigEpoch > known {
+		return true
+	}
+	logFields(sp.logger(), LogDebug, "poll disagrees with subscription, waiting for confirmation",
+		"master", sp.sntl.masterName(), "addr", addr, "polls", disagreements)
+	return false
+}
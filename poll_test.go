@@ -0,0 +1,112 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelPoolPolling(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithPolling(20*time.Millisecond))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case change := <-changes:
+		if change.New != cluster.Master.Addr() || change.Reason != "poll" {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not polled")
+	}
+	sp.mu.RLock()
+	watching := sp.masterWatcher != nil
+	sp.mu.RUnlock()
+	if watching {
+		t.Fatal("polling pool must not subscribe")
+	}
+}
+
+func TestSentinelPoolHybridPolling(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	// subscription is made to sentinel which never sees failover, so only
+	// polling notices it
+	silent, err := sentineltest.NewSentinel("mymaster", cluster.Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithHybridPolling(20*time.Millisecond), WithLazyInit())
+	defer sp.Close()
+	sp.sntl.SubscribeDial = func(string) (redis.Conn, error) {
+		return redis.Dial("tcp", silent.Addr())
+	}
+	changes := sp.MasterChanges()
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	waitWatching(t, sp)
+
+	newMaster := cluster.Replicas[0]
+	cluster.Failover(newMaster)
+	select {
+	case change := <-changes:
+		if change.New != newMaster.Addr() || change.Reason != "poll" {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+}
+
+func TestSentinelPoolConfirmPoll(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithHybridPolling(time.Hour))
+	defer sp.Close()
+	if _, err := sp.sntl.MasterState(); err != nil {
+		t.Fatal(err)
+	}
+
+	// poll reporting another master is not trusted on its own
+	stale := cluster.Replicas[0].Addr()
+	if sp.confirmPoll(stale, 1) {
+		t.Fatal("single poll without newer epoch must not be confirmed")
+	}
+	if !sp.confirmPoll(stale, hybridPollConfirmations) {
+		t.Fatal("consecutive polls must be confirmed")
+	}
+	cluster.Failover(cluster.Replicas[0])
+	if !sp.confirmPoll(cluster.Master.Addr(), 1) {
+		t.Fatal("poll with newer epoch must be confirmed")
+	}
+}
+
+func TestWithPollingClearsHybrid(t *testing.T) {
+	var o poolOptions
+	WithHybridPolling(time.Second)(&o)
+	WithPolling(time.Second)(&o)
+	if o.pollHybrid {
+		t.Fatal("WithPolling must disable hybrid polling")
+	}
+}
@@ -0,0 +1,25 @@
+package sentinel
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Pooler is implemented by SentinelPool. Applications can depend on it
+// instead of SentinelPool to substitute a fake in tests.
+type Pooler interface {
+	// Get returns connection to current master. It must be closed after use.
+	Get() redis.Conn
+	// GetContext is like Get, but waits for exhausted pool no longer than
+	// ctx allows.
+	GetContext(ctx context.Context) (redis.Conn, error)
+	// MasterAddr returns address of current master.
+	MasterAddr() string
+	// Stats returns pool counters.
+	Stats() PoolStats
+	// Close releases all resources.
+	Close()
+}
+
+var _ Pooler = (*SentinelPool)(nil)
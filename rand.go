@@ -0,0 +1,42 @@
+package sentinel
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedSource makes rand.Source safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// float64 returns pseudo-random number in [0.0,1.0) from source pool was
+// configured with.
+func (sp *SentinelPool) float64() float64 {
+	if sp.opts.rand != nil {
+		return sp.opts.rand.Float64()
+	}
+	return rand.Float64()
+}
+
+// jitter returns d randomly lengthened or shortened by up to fraction of it.
+func (sp *SentinelPool) jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	return d + time.Duration((2*sp.float64()-1)*fraction*float64(d))
+}
@@ -0,0 +1,56 @@
+package sentinel
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitterSeeded(t *testing.T) {
+	newPool := func(seed int64) *SentinelPool {
+		sp := &SentinelPool{}
+		WithRandSource(rand.NewSource(seed))(&sp.opts)
+		return sp
+	}
+	a, b, c := newPool(1), newPool(1), newPool(2)
+	same := true
+	for i := 0; i < 10; i++ {
+		da := a.jitter(time.Second, 0.2)
+		if da < 800*time.Millisecond || da > 1200*time.Millisecond {
+			t.Fatalf("jitter %v out of range", da)
+		}
+		if db := b.jitter(time.Second, 0.2); db != da {
+			t.Fatalf("same seed produced %v and %v", da, db)
+		}
+		if c.jitter(time.Second, 0.2) != da {
+			same = false
+		}
+	}
+	if same {
+		t.Fatal("distinct seeds produced same jitter")
+	}
+	if d := a.jitter(time.Second, 0); d != time.Second {
+		t.Fatalf("unexpected jitter %v without fraction", d)
+	}
+}
+
+func TestRandomSelectorSeeded(t *testing.T) {
+	newPool := func(seed int64) *SentinelPool {
+		sp, err := NewSentinelPoolE([]string{"127.0.0.1:0"}, "mymaster", 0, "",
+			WithLazyInit(), WithReplicaSelector(RandomSelector()),
+			WithRandSource(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sp
+	}
+	a, b := newPool(1), newPool(1)
+	defer a.Close()
+	defer b.Close()
+	replicas := make([]Replica, 10)
+	for i := 0; i < 10; i++ {
+		if ia, ib := a.replicas.selector.Select(replicas), b.replicas.selector.Select(replicas); ia != ib {
+			t.Fatalf("same seed picked replicas %d and %d", ia, ib)
+		}
+	}
+}
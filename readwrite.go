@@ -0,0 +1,313 @@
+package sentinel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ReadPreference selects which instances ReadWritePool sends read commands
+// to. Writes always go to master.
+type ReadPreference int
+
+const (
+	// ReadMaster sends reads to master.
+	ReadMaster ReadPreference = iota
+	// ReadPreferReplica sends reads to replicas, or to master when no
+	// replica can be used.
+	ReadPreferReplica
+	// ReadReplica sends reads to replicas only and fails when none can be
+	// used.
+	ReadReplica
+	// ReadNearest sends reads to master or replica which answered fastest
+	// recently.
+	ReadNearest
+)
+
+func (rp ReadPreference) String() string {
+	switch rp {
+	case ReadMaster:
+		return "master"
+	case ReadPreferReplica:
+		return "prefer-replica"
+	case ReadReplica:
+		return "replica"
+	case ReadNearest:
+		return "nearest"
+	}
+	return "unknown"
+}
+
+// readCommands are commands which only read data and can be served by
+// replicas.
+var readCommands = map[string]bool{
+	"BITCOUNT": true, "BITPOS": true, "DBSIZE": true, "DUMP": true,
+	"EXISTS": true, "GEODIST": true, "GEOHASH": true, "GEOPOS": true,
+	"GEORADIUSBYMEMBER_RO": true, "GEORADIUS_RO": true, "GEOSEARCH": true,
+	"GET": true, "GETBIT": true, "GETRANGE": true, "HEXISTS": true,
+	"HGET": true, "HGETALL": true, "HKEYS": true, "HLEN": true,
+	"HMGET": true, "HRANDFIELD": true, "HSCAN": true, "HSTRLEN": true,
+	"HVALS": true, "KEYS": true, "LINDEX": true, "LLEN": true,
+	"LPOS": true, "LRANGE": true, "MGET": true, "PFCOUNT": true,
+	"PTTL": true, "RANDOMKEY": true, "SCAN": true, "SCARD": true,
+	"SDIFF": true, "SINTER": true, "SISMEMBER": true, "SMEMBERS": true,
+	"SMISMEMBER": true, "SRANDMEMBER": true, "SSCAN": true, "STRLEN": true,
+	"SUNION": true, "TTL": true, "TYPE": true, "XLEN": true,
+	"XRANGE": true, "XREVRANGE": true, "ZCARD": true, "ZCOUNT": true,
+	"ZLEXCOUNT": true, "ZMSCORE": true, "ZRANDMEMBER": true, "ZRANGE": true,
+	"ZRANGEBYLEX": true, "ZRANGEBYSCORE": true, "ZRANK": true,
+	"ZREVRANGE": true, "ZREVRANGEBYLEX": true, "ZREVRANGEBYSCORE": true,
+	"ZREVRANK": true, "ZSCAN": true, "ZSCORE": true,
+}
+
+// ReadWritePool hands out connections sending read commands to instances
+// chosen by ReadPreference and everything else to master of SentinelPool,
+// so applications need not manage separate master and replica pools.
+type ReadWritePool struct {
+	sp      *SentinelPool
+	pref    ReadPreference
+	latency latencies
+}
+
+var _ Pooler = (*ReadWritePool)(nil)
+
+// NewReadWritePool creates pool routing commands over sp according to pref.
+// Closing it closes sp.
+func NewReadWritePool(sp *SentinelPool, pref ReadPreference) *ReadWritePool {
+	return &ReadWritePool{sp: sp, pref: pref}
+}
+
+// Get returns connection routing every command sent with Do by its name:
+// reads go to instance chosen by read preference and other commands to
+// master. Commands sent with Send are pipelined to master, and so is every
+// command after MULTI or WATCH, so transactions see consistent data.
+// Instances are connected to on first command sent to them.
+// redis.Conn must Close after use
+func (rw *ReadWritePool) Get() redis.Conn {
+	return &routingConn{rw: rw, ctx: context.Background()}
+}
+
+// GetContext is like Get, but waiting for exhausted master pool is bounded
+// by ctx.
+func (rw *ReadWritePool) GetContext(ctx context.Context) (redis.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return errorConn{err}, err
+	}
+	return &routingConn{rw: rw, ctx: ctx}, nil
+}
+
+// MasterAddr returns address of current master.
+func (rw *ReadWritePool) MasterAddr() string {
+	return rw.sp.MasterAddr()
+}
+
+// Stats returns counters of underlying SentinelPool.
+func (rw *ReadWritePool) Stats() PoolStats {
+	return rw.sp.Stats()
+}
+
+// Close closes underlying SentinelPool.
+func (rw *ReadWritePool) Close() {
+	rw.sp.Close()
+}
+
+const (
+	// latencyWeight is a weight of new sample in moving average of latency.
+	latencyWeight = 4
+	// failedLatency is recorded for instance command failed to reach, so
+	// that reads move elsewhere.
+	failedLatency = time.Second
+)
+
+// latencies keeps moving average of command latency per instance address.
+type latencies struct {
+	mu   sync.Mutex
+	avgs map[string]time.Duration
+}
+
+func (l *latencies) record(addr string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.avgs == nil {
+		l.avgs = make(map[string]time.Duration)
+	}
+	if avg, ok := l.avgs[addr]; ok {
+		d = avg + (d-avg)/latencyWeight
+	}
+	l.avgs[addr] = d
+}
+
+// nearest returns address among addrs with the lowest latency. Addresses
+// without any sample yet are preferred, so that every one is measured.
+func (l *latencies) nearest(addrs []string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	best, bestAvg := "", time.Duration(0)
+	for _, addr := range addrs {
+		avg, ok := l.avgs[addr]
+		if !ok {
+			return addr
+		}
+		if best == "" || avg < bestAvg {
+			best, bestAvg = addr, avg
+		}
+	}
+	return best
+}
+
+// measure records latency of command sent to addr at start, unless addr
+// is empty.
+func (rw *ReadWritePool) measure(addr string, start time.Time, err error) {
+	if addr == "" {
+		return
+	}
+	d := time.Since(start)
+	if _, ok := err.(redis.Error); err != nil && !ok {
+		d = failedLatency
+	}
+	rw.latency.record(addr, d)
+}
+
+// routingConn sends commands to master or read connection, both taken
+// from pools when first needed.
+type routingConn struct {
+	rw       *ReadWritePool
+	ctx      context.Context
+	master   redis.Conn
+	read     redis.Conn
+	readAddr string
+	// readMaster is set once master was chosen for reads.
+	readMaster bool
+	// pinned is set once transaction is started, sending every following
+	// command to master.
+	pinned bool
+}
+
+func (c *routingConn) masterConn() redis.Conn {
+	if c.master == nil {
+		conn, err := c.rw.sp.GetContext(c.ctx)
+		if err != nil {
+			conn = errorConn{err}
+		}
+		c.master = conn
+	}
+	return c.master
+}
+
+// readConn returns connection for read commands and address of instance it
+// is connected to, if latency is measured for it. Instance is chosen once
+// per connection.
+func (c *routingConn) readConn() (redis.Conn, string) {
+	if c.readMaster {
+		return c.masterConn(), c.readAddr
+	}
+	if c.read != nil {
+		return c.read, c.readAddr
+	}
+	sp := c.rw.sp
+	switch c.rw.pref {
+	case ReadReplica:
+		c.read, _ = sp.GetReplicaContext(c.ctx)
+		return c.read, ""
+	case ReadPreferReplica:
+		read, err := sp.GetReplicaContext(c.ctx)
+		if err == nil {
+			c.read = read
+			return c.read, ""
+		}
+		read.Close()
+	case ReadNearest:
+		masterAddr := sp.MasterAddr()
+		addrs, pools, _ := sp.replicaPools()
+		addr := c.rw.latency.nearest(append([]string{masterAddr}, addrs...))
+		if pool, ok := pools[addr]; ok && addr != masterAddr {
+			read, err := getPooled(c.ctx, pool)
+			if err == nil {
+				c.read, c.readAddr = read, addr
+				return c.read, addr
+			}
+			if c.ctx.Err() == nil {
+				c.rw.latency.record(addr, failedLatency)
+			}
+			read.Close()
+		}
+		c.readAddr = masterAddr
+	}
+	c.readMaster = true
+	return c.masterConn(), c.readAddr
+}
+
+// route returns connection cmd should be sent over and address of
+// instance latency of cmd is measured for, if any.
+func (c *routingConn) route(cmd string) (redis.Conn, string) {
+	cmd = strings.ToUpper(cmd)
+	if cmd == "MULTI" || cmd == "WATCH" {
+		c.pinned = true
+	}
+	if c.pinned || !readCommands[cmd] {
+		return c.masterConn(), ""
+	}
+	return c.readConn()
+}
+
+func (c *routingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	conn, addr := c.route(cmd)
+	start := time.Now()
+	reply, err := conn.Do(cmd, args...)
+	c.rw.measure(addr, start, err)
+	return reply, err
+}
+
+func (c *routingConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	conn, addr := c.route(cmd)
+	start := time.Now()
+	reply, err := redis.DoWithTimeout(conn, timeout, cmd, args...)
+	c.rw.measure(addr, start, err)
+	return reply, err
+}
+
+func (c *routingConn) Send(cmd string, args ...interface{}) error {
+	return c.masterConn().Send(cmd, args...)
+}
+
+func (c *routingConn) Flush() error {
+	return c.masterConn().Flush()
+}
+
+func (c *routingConn) Receive() (interface{}, error) {
+	return c.masterConn().Receive()
+}
+
+func (c *routingConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.masterConn(), timeout)
+}
+
+// Err returns error of master connection, or of read one if master has not
+// been used.
+func (c *routingConn) Err() error {
+	if c.master != nil {
+		return c.master.Err()
+	}
+	if c.read != nil {
+		return c.read.Err()
+	}
+	return nil
+}
+
+func (c *routingConn) Close() error {
+	var err error
+	if c.read != nil {
+		err = c.read.Close()
+	}
+	if c.master != nil {
+		if merr := c.master.Close(); err == nil {
+			err = merr
+		}
+	}
+	return err
+}
+
+var _ redis.ConnWithTimeout = (*routingConn)(nil)
@@ -0,0 +1,132 @@
+package sentinel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestLatenciesNearest(t *testing.T) {
+	var l latencies
+	addrs := []string{"10.0.0.1:6379", "10.0.0.2:6379"}
+	if addr := l.nearest(addrs); addr != addrs[0] {
+		t.Fatalf("unexpected nearest %s", addr)
+	}
+	l.record(addrs[0], 10*time.Millisecond)
+	if addr := l.nearest(addrs); addr != addrs[1] {
+		t.Fatalf("unmeasured address must be preferred, got %s", addr)
+	}
+	l.record(addrs[1], time.Millisecond)
+	if addr := l.nearest(addrs); addr != addrs[1] {
+		t.Fatalf("unexpected nearest %s", addr)
+	}
+	for i := 0; i < 10; i++ {
+		l.record(addrs[1], failedLatency)
+	}
+	if addr := l.nearest(addrs); addr != addrs[0] {
+		t.Fatalf("failing address must be avoided, got %s", addr)
+	}
+}
+
+func TestReadWritePool(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	master, replica := cluster.Master.Addr(), cluster.Replicas[0].Addr()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	rw := NewReadWritePool(sp, ReadReplica)
+	defer rw.Close()
+
+	if _, err := Do(rw, redis.String, "SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Do(rw, redis.String, "get", "key"); err != redis.ErrNil {
+		t.Fatalf("read was not sent to replica: %v", err)
+	}
+	c := rw.Get()
+	c.Do("MULTI")
+	c.Do("GET", "key")
+	c.Close()
+
+	// closing connection in transaction sends DISCARD to master
+	st := rw.Stats()
+	if st.AddrCommands[master] != 4 || st.AddrCommands[replica] != 1 {
+		t.Fatalf("unexpected routing %+v", st.AddrCommands)
+	}
+}
+
+func TestReadWritePoolPreferReplica(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+
+	if _, err := Do(NewReadWritePool(sp, ReadReplica), redis.String, "GET", "key"); err != ErrNoReplicas {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rw := NewReadWritePool(sp, ReadPreferReplica)
+	if _, err := Do(rw, redis.String, "SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := Do(rw, redis.String, "GET", "key"); err != nil || v != "value" {
+		t.Fatalf("read did not fall back to master: %q, %v", v, err)
+	}
+}
+
+func TestReadWritePoolReadContext(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithMaxActive(1), WithWait(true))
+	defer sp.Close()
+	// exhausted replica pool makes read wait for a connection
+	held := sp.GetReplica()
+	defer held.Close()
+	if _, err := held.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pref := range []ReadPreference{ReadReplica, ReadPreferReplica, ReadNearest} {
+		rw := NewReadWritePool(sp, pref)
+		if pref == ReadNearest {
+			// master is measured first, replica has to be measured too
+			rw.latency.record(sp.MasterAddr(), time.Second)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		c, err := rw.GetContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			_, err := c.Do("GET", "key")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != context.DeadlineExceeded {
+				t.Fatalf("read preference %v: expected deadline exceeded, got %v", pref, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("read preference %v: read ignored context", pref)
+		}
+		c.Close()
+		cancel()
+	}
+}
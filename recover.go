@@ -0,0 +1,66 @@
+package sentinel
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// RestartPolicy controls restarting of pool background goroutines after
+// they panicked.
+type RestartPolicy struct {
+	// MaxRestarts is a number of restarts after which goroutine is left
+	// stopped. Negative value means no limit.
+	MaxRestarts int
+	// Delay is a pause before every restart.
+	Delay time.Duration
+	// Jitter is a fraction of Delay by which every pause is randomly
+	// lengthened or shortened, e.g. 0.1 for +/-10%.
+	Jitter float64
+}
+
+// defaultRestartPolicy restarts background goroutines forever.
+var defaultRestartPolicy = RestartPolicy{MaxRestarts: -1, Delay: time.Second}
+
+// reportPanic logs recovered panic value v of named goroutine with logger
+// and passes it to handler if set.
+func reportPanic(logger Logger, handler func(goroutine string, v interface{}), goroutine string, v interface{}) {
+	logFields(logger, LogError, "background goroutine panicked",
+		"goroutine", goroutine, "panic", v, "stack", string(debug.Stack()))
+	if handler != nil {
+		handler(goroutine, v)
+	}
+}
+
+// _runGuarded runs f, restarting it according to restart policy if it
+// panics, until it returns normally or pool is closed.
+func (sp *SentinelPool) _runGuarded(name string, f func()) {
+	policy := defaultRestartPolicy
+	if sp.opts.restartPolicy != nil {
+		policy = *sp.opts.restartPolicy
+	}
+	for restarts := 0; ; restarts++ {
+		if !sp._runRecovered(name, f) {
+			return
+		}
+		if policy.MaxRestarts >= 0 && restarts >= policy.MaxRestarts {
+			logFields(sp.logger(), LogError, "background goroutine stopped",
+				"goroutine", name, "restarts", restarts)
+			return
+		}
+		if !sp.sleep(sp.jitter(policy.Delay, policy.Jitter)) {
+			return
+		}
+	}
+}
+
+// _runRecovered runs f and reports whether it panicked.
+func (sp *SentinelPool) _runRecovered(name string, f func()) (panicked bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			panicked = true
+			reportPanic(sp.logger(), sp.opts.onPanic, name, v)
+		}
+	}()
+	f()
+	return false
+}
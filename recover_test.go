@@ -0,0 +1,32 @@
+package sentinel
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRunGuarded(t *testing.T) {
+	var panics []interface{}
+	sp := &SentinelPool{mu: &sync.RWMutex{}}
+	WithRestartPolicy(RestartPolicy{MaxRestarts: 2})(&sp.opts)
+	WithPanicHandler(func(goroutine string, v interface{}) {
+		panics = append(panics, v)
+	})(&sp.opts)
+
+	runs := 0
+	sp._runGuarded("test", func() {
+		runs++
+		panic("boom")
+	})
+	if runs != 3 || len(panics) != 3 {
+		t.Fatalf("expected 3 runs and panics, got %d runs and %d panics", runs, len(panics))
+	}
+
+	runs = 0
+	sp._runGuarded("test", func() {
+		runs++
+	})
+	if runs != 1 {
+		t.Fatalf("expected goroutine returning normally to run once, got %d runs", runs)
+	}
+}
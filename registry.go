@@ -0,0 +1,104 @@
+package sentinel
+
+import (
+	"time"
+)
+
+// SentinelNode holds what client knows about a single Sentinel.
+type SentinelNode struct {
+	// Addr is an address client uses to connect to Sentinel.
+	Addr string `json:"addr"`
+	// RunID is Sentinel run ID, empty if not learned yet.
+	RunID string `json:"run_id,omitempty"`
+	// Flags are Sentinel flags as last reported by other sentinels.
+	Flags string `json:"flags,omitempty"`
+	// LastSeen is time of last successful reply from Sentinel.
+	LastSeen time.Time `json:"last_seen"`
+	// Latency is a duration of last successful request to Sentinel.
+	Latency time.Duration `json:"latency"`
+	// Up reports whether last request or ping to Sentinel succeeded.
+	Up bool `json:"up"`
+	// ConsecutiveFailures is a number of requests and pings failed since
+	// Sentinel last replied.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+}
+
+// Nodes returns known information about every Sentinel in Addrs, in the
+// order they are tried.
+func (s *Sentinel) Nodes() []SentinelNode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	nodes := make([]SentinelNode, 0, len(s.Addrs))
+	for _, addr := range s.Addrs {
+		node, ok := s.nodes[addr]
+		if !ok {
+			nodes = append(nodes, SentinelNode{Addr: addr})
+			continue
+		}
+		nodes = append(nodes, *node)
+	}
+	return nodes
+}
+
+// node returns registry entry for addr, creating it if needed.
+//
+// Lock must be held by caller.
+func (s *Sentinel) node(addr string) *SentinelNode {
+	if s.nodes == nil {
+		s.nodes = make(map[string]*SentinelNode)
+	}
+	node, ok := s.nodes[addr]
+	if !ok {
+		node = &SentinelNode{Addr: addr}
+		s.nodes[addr] = node
+	}
+	return node
+}
+
+// runID returns run ID of Sentinel on addr or empty string if unknown.
+//
+// Lock must be held by caller.
+func (s *Sentinel) runID(addr string) string {
+	if node, ok := s.nodes[addr]; ok {
+		return node.RunID
+	}
+	return ""
+}
+
+// setRunID remembers run ID of Sentinel on addr.
+//
+// Lock must be held by caller.
+func (s *Sentinel) setRunID(addr, runID string) {
+	s.node(addr).RunID = runID
+}
+
+// seen records successful request to Sentinel on addr.
+//
+// Lock must be held by caller.
+func (s *Sentinel) seen(addr string, latency time.Duration) {
+	node := s.node(addr)
+	node.LastSeen = time.Now()
+	node.Latency = latency
+	node.Up = true
+	node.ConsecutiveFailures = 0
+}
+
+// failed records failed request or ping to Sentinel on addr.
+//
+// Lock must be held by caller.
+func (s *Sentinel) failed(addr string) {
+	node := s.node(addr)
+	node.Up = false
+	node.ConsecutiveFailures++
+}
+
+// discovered records Sentinel reported by SENTINEL sentinels.
+//
+// Lock must be held by caller.
+func (s *Sentinel) discovered(info sentinelInfo) {
+	node := s.node(info.addr)
+	if info.runID != "" {
+		node.RunID = info.runID
+	}
+	node.Flags = info.flags
+}
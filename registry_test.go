@@ -0,0 +1,60 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelNodes(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	first, second := cluster.Sentinels[0], cluster.Sentinels[1]
+
+	sntl := NewSentinel([]string{first.Addr()}, "mymaster")
+	defer sntl.Close()
+	if nodes := sntl.Nodes(); len(nodes) != 1 || nodes[0].Addr != first.Addr() || nodes[0].Up {
+		t.Fatalf("unexpected nodes before any request %+v", nodes)
+	}
+	start := time.Now()
+	if err := sntl.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	nodes := sntl.Nodes()
+	if len(nodes) != 2 {
+		t.Fatalf("peer was not discovered %+v", nodes)
+	}
+	byAddr := make(map[string]SentinelNode)
+	for _, node := range nodes {
+		byAddr[node.Addr] = node
+	}
+	asked := byAddr[first.Addr()]
+	if !asked.Up || asked.LastSeen.Before(start) || asked.Latency <= 0 || asked.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected node of asked sentinel %+v", asked)
+	}
+	peer := byAddr[second.Addr()]
+	if peer.RunID == "" || peer.Flags != "sentinel" || !peer.LastSeen.IsZero() {
+		t.Fatalf("unexpected node of discovered sentinel %+v", peer)
+	}
+
+	first.Close()
+	if _, err := sntl.MasterAddr(); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range sntl.Nodes() {
+		switch node.Addr {
+		case first.Addr():
+			if node.Up || node.ConsecutiveFailures != 1 || node.LastSeen != asked.LastSeen {
+				t.Fatalf("unexpected node of closed sentinel %+v", node)
+			}
+		case second.Addr():
+			if !node.Up || node.RunID != peer.RunID || node.LastSeen.IsZero() {
+				t.Fatalf("unexpected node of peer %+v", node)
+			}
+		}
+	}
+}
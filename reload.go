@@ -0,0 +1,116 @@
+package sentinel
+
+import (
+	"errors"
+	"time"
+)
+
+// PoolConfig is a part of SentinelPool configuration which can be changed
+// while pool is running, see Reload.
+type PoolConfig struct {
+	// SentinelAddrs is a list of Sentinel addresses.
+	SentinelAddrs []string
+	// Username is used with Password to AUTH new master and replica
+	// connections. It requires Redis 6 ACL and can be empty.
+	Username string
+	// Password is used to AUTH new master and replica connections.
+	Password string
+	// MaxIdle is a maximum number of idle connections in pool.
+	MaxIdle int
+	// MaxActive is a maximum number of connections allocated by pool at a
+	// given time. When zero, there is no limit.
+	MaxActive int
+	// IdleTimeout closes connections after remaining idle for this duration.
+	IdleTimeout time.Duration
+	// MaxConnLifetime closes connections older than this duration when
+	// they are returned to or taken from pool. When zero, connections are
+	// kept regardless of age.
+	MaxConnLifetime time.Duration
+}
+
+// IdleReaper controls how many idle connections a pool keeps and for how
+// long. Connections which expired are closed when pool is next used, as
+// redigo pools have no background reaper; keep MaxIdleAge below server or
+// proxy idle timeouts accordingly.
+type IdleReaper struct {
+	// MaxIdle is a maximum number of idle connections kept. Zero means
+	// pool default.
+	MaxIdle int
+	// MaxIdleAge closes connections which remained idle longer. Zero means
+	// default of 240 seconds and negative value keeps them forever.
+	MaxIdleAge time.Duration
+}
+
+// defaultIdleAge is how long idle connections are kept by default.
+const defaultIdleAge = 240 * time.Second
+
+// apply returns maxIdle and idleTimeout of redis.Pool according to reaper,
+// using defaultMaxIdle unless MaxIdle is set.
+func (r IdleReaper) apply(defaultMaxIdle int) (int, time.Duration) {
+	maxIdle, age := r.MaxIdle, r.MaxIdleAge
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdle
+	}
+	switch {
+	case age == 0:
+		age = defaultIdleAge
+	case age < 0:
+		age = 0
+	}
+	return maxIdle, age
+}
+
+// Config returns currently applied runtime configuration of pool.
+func (p *SentinelPool) Config() PoolConfig {
+	p.mu.RLock()
+	cfg := p.cfg
+	p.mu.RUnlock()
+	p.sntl.mu.RLock()
+	cfg.SentinelAddrs = append([]string(nil), p.sntl.Addrs...)
+	p.sntl.mu.RUnlock()
+	return cfg
+}
+
+// AddSentinel adds Sentinel address pool queries for master.
+func (p *SentinelPool) AddSentinel(addr string) error {
+	return p.sntl.AddSentinel(addr)
+}
+
+// RemoveSentinel removes Sentinel address pool queries for master.
+func (p *SentinelPool) RemoveSentinel(addr string) {
+	p.sntl.RemoveSentinel(addr)
+}
+
+// Reload applies cfg to running pool, e.g. after configuration file changed.
+// Established connections are kept where possible: new password is only used
+// for new connections, and connection pool is only replaced when its sizing
+// changed, in which case idle connections are closed and borrowed ones are
+// closed on return. Pools of replicas are resized along with master one.
+func (p *SentinelPool) Reload(cfg PoolConfig) error {
+	if len(cfg.SentinelAddrs) == 0 {
+		return errors.New("redigo: no sentinel addresses configured")
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	old := p.pool
+	resize := cfg.MaxIdle != p.cfg.MaxIdle ||
+		cfg.MaxActive != p.cfg.MaxActive ||
+		cfg.IdleTimeout != p.cfg.IdleTimeout ||
+		cfg.MaxConnLifetime != p.cfg.MaxConnLifetime
+	p.cfg = cfg
+	p.cfg.SentinelAddrs = nil
+	if resize {
+		p.pool = p._newPool()
+	}
+	p.mu.Unlock()
+
+	p.sntl.setAddrs(cfg.SentinelAddrs)
+	if resize {
+		old.Close()
+		p.resizeReplicas()
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package sentinel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestIdleReaper(t *testing.T) {
+	tests := []struct {
+		reaper  IdleReaper
+		maxIdle int
+		timeout time.Duration
+	}{
+		{IdleReaper{}, 3, defaultIdleAge},
+		{IdleReaper{MaxIdle: 1, MaxIdleAge: time.Second}, 1, time.Second},
+		{IdleReaper{MaxIdleAge: -1}, 3, 0},
+	}
+	for _, tt := range tests {
+		s := &Sentinel{IdleReaper: tt.reaper}
+		pool := s.defaultPool("127.0.0.1:26379")
+		if pool.MaxIdle != tt.maxIdle || pool.IdleTimeout != tt.timeout {
+			t.Errorf("%+v: got max idle %d, timeout %v", tt.reaper, pool.MaxIdle, pool.IdleTimeout)
+		}
+	}
+}
+
+func TestPoolSizingOptions(t *testing.T) {
+	errBroken := errors.New("broken")
+	sp := NewSentinelPool([]string{"127.0.0.1:26379"}, "mymaster", 0, "",
+		WithLazyInit(), WithMaxIdle(2), WithIdleTimeout(0), WithMaxActive(8),
+		WithWait(true), WithDialTimeout(time.Second), WithMaxConnLifetime(time.Hour),
+		WithTestOnBorrow(func(c redis.Conn, _ time.Time) error { return errBroken }))
+	defer sp.Close()
+
+	pool := sp.Pool()
+	if pool.MaxIdle != 2 || pool.IdleTimeout != 0 || pool.MaxActive != 8 || !pool.Wait ||
+		pool.MaxConnLifetime != time.Hour {
+		t.Fatalf("options not applied: max idle %d, timeout %v, max active %d, wait %v, lifetime %v",
+			pool.MaxIdle, pool.IdleTimeout, pool.MaxActive, pool.Wait, pool.MaxConnLifetime)
+	}
+	if err := pool.TestOnBorrow(nopConn{}, time.Now()); err != errBroken {
+		t.Fatalf("unexpected test on borrow result %v", err)
+	}
+	if cfg := sp.Config(); cfg.MaxActive != 8 || cfg.MaxIdle != 2 || cfg.MaxConnLifetime != time.Hour {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if d := sp.connectTimeout(); d != time.Second {
+		t.Fatalf("unexpected connect timeout %v", d)
+	}
+}
+
+func TestSentinelPoolReload(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.RequirePass("old")
+	first, second := cluster.Sentinels[0], cluster.Sentinels[1]
+
+	sp := NewSentinelPool([]string{first.Addr()}, "mymaster", 0, "old",
+		WithTopologyLogLevel(LogOff), WithMaxIdle(2))
+	defer sp.Close()
+	ping := func(c redis.Conn) {
+		t.Helper()
+		if _, err := c.Do("PING"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := sp.Get()
+	ping(c)
+	c.Close()
+
+	if err := sp.Reload(PoolConfig{}); err == nil {
+		t.Fatal("expected error without sentinel addresses")
+	}
+	cluster.Master.RequirePass("new")
+	cfg := sp.Config()
+	cfg.SentinelAddrs = []string{second.Addr()}
+	cfg.Password = "new"
+	pool := sp.Pool()
+	if err := sp.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := sp.Config(); len(got.SentinelAddrs) != 1 || got.SentinelAddrs[0] != second.Addr() ||
+		got.Password != "new" {
+		t.Fatalf("config not applied %+v", got)
+	}
+	// idle connection authenticated with old password is kept, new one
+	// uses new password
+	if sp.Pool() != pool || pool.IdleCount() != 1 {
+		t.Fatalf("pool was replaced without sizing change, %d idle", pool.IdleCount())
+	}
+	idle, fresh := sp.Get(), sp.Get()
+	ping(idle)
+	ping(fresh)
+	idle.Close()
+	fresh.Close()
+	first.Close()
+	if addr, err := sp.sntl.MasterAddr(); err != nil || addr != cluster.Master.Addr() {
+		t.Fatalf("reloaded sentinel not used: %s, %v", addr, err)
+	}
+
+	cfg.MaxIdle = 4
+	if err := sp.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if sp.Pool() == pool || sp.Pool().MaxIdle != 4 || pool.ActiveCount() != 0 {
+		t.Fatalf("pool was not replaced on resize, %d old connections", pool.ActiveCount())
+	}
+	c = sp.Get()
+	ping(c)
+	c.Close()
+
+	sp.Close()
+	if err := sp.Reload(cfg); err != ErrPoolClosed {
+		t.Fatalf("expected closed pool error, got %v", err)
+	}
+}
+
+func TestSentinelPoolReloadReplicas(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithMaxActive(1))
+	defer sp.Close()
+	c := sp.GetReplica()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.GetReplica().Err(); err != redis.ErrPoolExhausted {
+		t.Fatalf("expected exhausted replica pool, got %v", err)
+	}
+
+	cfg := sp.Config()
+	cfg.MaxActive = 2
+	if err := sp.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// connection borrowed from replaced pool is closed on return
+	c.Close()
+	_, pools, _ := sp.lookupReplicas()
+	pool := pools[cluster.Replicas[0].Addr()]
+	if pool == nil || pool.MaxActive != 2 {
+		t.Fatalf("replica pool was not resized: %+v", pool)
+	}
+	for _, c := range []redis.Conn{sp.GetReplica(), sp.GetReplica()} {
+		if _, err := c.Do("PING"); err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+}
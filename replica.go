@@ -0,0 +1,309 @@
+package sentinel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// replicaEventChannels are Sentinel event channels after which replicas of
+// master may have changed, besides switch master one.
+var replicaEventChannels = []string{
+	ChannelSlave,
+	ChannelSDown,
+	ChannelSDownCleared,
+	ChannelConvertToSlave,
+	ChannelSlaveReconfDone,
+}
+
+// replicaSet keeps connection pool to every known replica of current master.
+type replicaSet struct {
+	mu     sync.Mutex
+	addrs  []string
+	pools  map[string]*redis.Pool
+	loaded bool
+	closed bool
+	// looking is closed once lookup in progress is done, nil if there is
+	// none.
+	looking chan struct{}
+	// err is error of the last lookup, nil if it succeeded.
+	err error
+	// stale is set to 1 once replicas have to be looked up again.
+	stale    int32
+	selector Selector
+	// lagging are replicas found too far behind master, see
+	// WithReplicaLagGuard.
+	lagging map[string]bool
+}
+
+// anyLagging reports whether some replica was found lagging behind master.
+func (rs *replicaSet) anyLagging() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return len(rs.lagging) > 0
+}
+
+// invalidate makes replicas to be looked up on next use. It does not block,
+// so it can be called with pool lock held.
+func (rs *replicaSet) invalidate() {
+	atomic.StoreInt32(&rs.stale, 1)
+}
+
+// close closes pools of all replicas.
+func (rs *replicaSet) close() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.closed = true
+	for _, pool := range rs.pools {
+		pool.Close()
+	}
+	rs.pools = nil
+	rs.addrs = nil
+}
+
+// GetReplica gets connection to one of replicas of current master, for
+// reads which tolerate replication lag. Replicas are picked round-robin
+// unless another Selector is set with WithReplicaSelector, and another one
+// is picked if dialing fails. They are looked up on first
+// use and again after sentinels report changes of replicas or master. If no
+// replica can be used, returned connection fails with ErrNoReplicas, error
+// of looking replicas up or error of the last attempt.
+// redis.Conn must Close after use
+func (p *SentinelPool) GetReplica() redis.Conn {
+	c, _ := p.GetReplicaContext(context.Background())
+	return c
+}
+
+// GetReplicaContext is like GetReplica, but gives up once ctx is done,
+// which also aborts dialing replica. Returned connection must be closed
+// after use if error is nil.
+func (p *SentinelPool) GetReplicaContext(ctx context.Context) (redis.Conn, error) {
+	p.mu.RLock()
+	closed := p.closed
+	p.mu.RUnlock()
+	if closed {
+		return errorConn{ErrPoolClosed}, ErrPoolClosed
+	}
+	p.startLagCheck()
+	addrs, pools, err := p.replicaPools()
+	if len(addrs) == 0 {
+		if err != nil {
+			return errorConn{err}, err
+		}
+		if p.replicas.anyLagging() {
+			return errorConn{ErrReplicasLagging}, ErrReplicasLagging
+		}
+		return errorConn{ErrNoReplicas}, ErrNoReplicas
+	}
+	candidates := make([]Replica, 0, len(addrs))
+	for _, addr := range addrs {
+		pool := pools[addr]
+		candidates = append(candidates, Replica{Addr: addr, InUse: pool.ActiveCount() - pool.IdleCount()})
+	}
+	var c redis.Conn
+	for len(candidates) > 0 {
+		i := p.replicas.selector.Select(candidates)
+		if i < 0 || i >= len(candidates) {
+			i = 0
+		}
+		addr := candidates[i].Addr
+		if c, err = getPooled(ctx, pools[addr]); err == nil {
+			return c, nil
+		}
+		if ctx.Err() != nil {
+			return c, ctx.Err()
+		}
+		logFields(p.logger(), LogWarn, "replica connection failed",
+			"master", p.sntl.masterName(), "addr", addr, "err", err)
+		c.Close()
+		// copy, so Selector may keep slices it was given
+		candidates = append(candidates[:i:i], candidates[i+1:]...)
+	}
+	return c, err
+}
+
+// GetSlave is an alias of GetReplica.
+func (p *SentinelPool) GetSlave() redis.Conn {
+	return p.GetReplica()
+}
+
+// replicaPools returns addresses of known replicas which do not lag behind
+// master and their pools, see lookupReplicas.
+func (p *SentinelPool) replicaPools() ([]string, map[string]*redis.Pool, error) {
+	addrs, pools, err := p.lookupReplicas()
+	rs := &p.replicas
+	rs.mu.Lock()
+	lagging := rs.lagging
+	rs.mu.Unlock()
+	if len(lagging) == 0 {
+		return addrs, pools, err
+	}
+	usable := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if !lagging[addr] {
+			usable = append(usable, addr)
+		}
+	}
+	return usable, pools, err
+}
+
+// lookupReplicas returns addresses of known replicas and their pools,
+// looking replicas up if they are not known or stale. Concurrent callers
+// wait for a single lookup, which is made without replica set lock held.
+// If lookup fails, previously known replicas are returned along with its
+// error.
+func (p *SentinelPool) lookupReplicas() ([]string, map[string]*redis.Pool, error) {
+	rs := &p.replicas
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.closed {
+		return nil, nil, ErrPoolClosed
+	}
+	if atomic.LoadInt32(&rs.stale) == 0 && rs.loaded {
+		return rs.addrs, rs.pools, nil
+	}
+	if looking := rs.looking; looking != nil {
+		rs.mu.Unlock()
+		<-looking
+		rs.mu.Lock()
+		return rs.addrs, rs.pools, rs.err
+	}
+	looking := make(chan struct{})
+	rs.looking = looking
+	// changes reported from now on need another lookup
+	atomic.StoreInt32(&rs.stale, 0)
+	rs.mu.Unlock()
+	addrs, err := p.sntl.SlaveAddrs()
+	rs.mu.Lock()
+	rs.looking = nil
+	rs.err = err
+	close(looking)
+	switch {
+	case err != nil:
+		// retry on next use
+		rs.invalidate()
+		logFields(p.logger(), LogWarn, "replicas lookup failed",
+			"master", p.sntl.masterName(), "err", err)
+	case !rs.closed:
+		p._updateReplicas(addrs)
+	}
+	return rs.addrs, rs.pools, err
+}
+
+// _updateReplicas makes pools for new replicas and closes ones of replicas
+// which are gone.
+// Replica set lock must be held by caller.
+func (p *SentinelPool) _updateReplicas(addrs []string) {
+	rs := &p.replicas
+	pools := make(map[string]*redis.Pool, len(addrs))
+	for _, addr := range addrs {
+		if pool, ok := rs.pools[addr]; ok {
+			pools[addr] = pool
+		} else {
+			pools[addr] = p.newReplicaPool(addr)
+		}
+	}
+	for addr, pool := range rs.pools {
+		if _, ok := pools[addr]; !ok {
+			pool.Close()
+		}
+	}
+	rs.addrs = addrs
+	rs.pools = pools
+	rs.loaded = true
+}
+
+// resizeReplicas replaces pools of known replicas with ones sized like
+// master pool, after its sizing was changed with Reload. Idle connections
+// of replaced pools are closed and borrowed ones are closed on return.
+func (p *SentinelPool) resizeReplicas() {
+	rs := &p.replicas
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.closed {
+		return
+	}
+	pools := make(map[string]*redis.Pool, len(rs.pools))
+	for addr, pool := range rs.pools {
+		pools[addr] = p.newReplicaPool(addr)
+		pool.Close()
+	}
+	rs.pools = pools
+}
+
+// newReplicaPool creates connection pool to replica on addr sized like
+// master one.
+func (p *SentinelPool) newReplicaPool(addr string) *redis.Pool {
+	p.mu.RLock()
+	cfg := p.cfg
+	p.mu.RUnlock()
+	return &redis.Pool{
+		MaxIdle:         cfg.MaxIdle,
+		MaxActive:       cfg.MaxActive,
+		IdleTimeout:     cfg.IdleTimeout,
+		MaxConnLifetime: cfg.MaxConnLifetime,
+		Wait:            p.opts.wait,
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			return p.dialReplica(ctx, addr)
+		},
+		TestOnBorrow: p.opts.testOnBorrow,
+	}
+}
+
+// dialReplica dials replica on addr, authenticating and selecting database
+// the same way master connections do. It gives up once ctx is done.
+func (p *SentinelPool) dialReplica(ctx context.Context, addr string) (redis.Conn, error) {
+	creds := p.credentials(addr, roleReplica)
+	timeout := defaultTimeout * time.Second
+	c, err := dialContext(ctx, addr, p.sntl.Family, p.sntl.TLSConfig,
+		p.connectTimeout(), timeout, timeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.sntl.dialFailed(addr, err)
+		}
+		return nil, err
+	}
+	hc := withContext(ctx, c)
+	if err := creds.auth(hc); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if _, err := hc.Do("SELECT", p.db); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := p.opts.replicaClientFlags.apply(hc); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &countingConn{
+		Conn:    c,
+		counter: &p.commands,
+		role:    roleReplica,
+		addr:    addr,
+	}, nil
+}
+
+// watchEvents invalidates known replicas whenever event received on events
+// reports change of a replica of master, and follows failover progress.
+// Master changes invalidate replicas when adopted.
+func (sp *SentinelPool) watchEvents(events <-chan Event) {
+	for ev := range events {
+		if ev.Channel != ChannelSwitchMaster {
+			sp.instanceEvent(ev)
+		}
+	}
+}
+
+// instanceEvent handles event other than master switch, which may concern
+// another master.
+func (sp *SentinelPool) instanceEvent(ev Event) {
+	sp.failoverEvent(ev)
+	e, err := ParseInstanceEvent(ev.Payload)
+	if err == nil && e.Type == "slave" && e.MasterName == sp.sntl.masterName() {
+		sp.replicas.invalidate()
+	}
+}
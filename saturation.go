@@ -0,0 +1,44 @@
+package sentinel
+
+import "sync"
+
+// dialWindowSize is how many recent master dial attempts are considered
+// when reporting dial failure rate.
+const dialWindowSize = 32
+
+// outcomeWindow keeps outcomes of recent dial attempts.
+type outcomeWindow struct {
+	mu     sync.Mutex
+	failed [dialWindowSize]bool
+	next   int
+	n      int
+}
+
+// record adds outcome of attempt, replacing the oldest one when window is
+// full.
+func (w *outcomeWindow) record(failed bool) {
+	w.mu.Lock()
+	w.failed[w.next] = failed
+	w.next = (w.next + 1) % dialWindowSize
+	if w.n < dialWindowSize {
+		w.n++
+	}
+	w.mu.Unlock()
+}
+
+// failureRate returns fraction of failed attempts in window, zero when
+// nothing was recorded yet.
+func (w *outcomeWindow) failureRate() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.n == 0 {
+		return 0
+	}
+	failures := 0
+	for i := 0; i < w.n; i++ {
+		if w.failed[i] {
+			failures++
+		}
+	}
+	return float64(failures) / float64(w.n)
+}
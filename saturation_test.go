@@ -0,0 +1,60 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestOutcomeWindow(t *testing.T) {
+	var w outcomeWindow
+	if r := w.failureRate(); r != 0 {
+		t.Fatalf("unexpected rate %v of empty window", r)
+	}
+	w.record(true)
+	w.record(false)
+	if r := w.failureRate(); r != 0.5 {
+		t.Fatalf("unexpected rate %v", r)
+	}
+	for i := 0; i < dialWindowSize; i++ {
+		w.record(false)
+	}
+	if r := w.failureRate(); r != 0 {
+		t.Fatalf("old failures must leave window, rate %v", r)
+	}
+}
+
+func TestPoolExhaustedSaturation(t *testing.T) {
+	sp := &SentinelPool{
+		mu: &sync.RWMutex{},
+		pool: &redis.Pool{
+			MaxActive: 1,
+			Wait:      true,
+			Dial:      func() (redis.Conn, error) { return nopConn{}, nil },
+		},
+		opts: poolOptions{waitTimeout: 20 * time.Millisecond},
+	}
+	sp.dialOutcomes.record(true)
+	sp.dialOutcomes.record(false)
+
+	held := sp.Get()
+	defer held.Close()
+	_, err := sp.GetContext(context.Background())
+	var pe PoolExhausted
+	if !errors.As(err, &pe) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if pe.ActiveCount != 1 || pe.WaitQueue != 0 || pe.DialFailureRate != 0.5 ||
+		!errors.Is(err, redis.ErrPoolExhausted) {
+		t.Fatalf("unexpected saturation %+v", pe)
+	}
+
+	sp.pool.Wait = false
+	if _, err := sp.GetContext(context.Background()); !errors.As(err, &pe) || pe.Waited != 0 {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
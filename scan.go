@@ -0,0 +1,186 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ScanPolicy tells Scan how to go on after master changed mid-iteration.
+type ScanPolicy int
+
+const (
+	// ScanRestart starts iteration over from cursor zero on new master, so
+	// every key is seen, some of them more than once.
+	ScanRestart ScanPolicy = iota
+	// ScanContinue keeps cursor returned by former master. Cursors are not
+	// portable between instances, so keys may be missed or repeated.
+	ScanContinue
+)
+
+const (
+	// defaultScanFailovers is how many master changes Scan tolerates when
+	// ScanOptions.MaxFailovers is not set.
+	defaultScanFailovers = 3
+	// scanRetries is how many times in a row SCAN failing with transient
+	// error is retried.
+	scanRetries = 3
+)
+
+// ErrScanInterrupted is returned by Scan when master changed more times
+// than ScanOptions.MaxFailovers allows.
+var ErrScanInterrupted = errors.New("redigo: scan interrupted by too many master changes")
+
+// ScanOptions are arguments of SCAN and failover handling of Scan.
+type ScanOptions struct {
+	// Match, Count and Type are SCAN options, omitted when empty.
+	Match string
+	Count int
+	Type  string
+	// Policy tells what to do after master changed.
+	Policy ScanPolicy
+	// MaxFailovers is a number of master changes after which Scan gives
+	// up, 3 when zero.
+	MaxFailovers int
+}
+
+// ScanReport describes how Scan iteration went.
+type ScanReport struct {
+	// Masters are addresses of masters iteration ran against, in order.
+	Masters []string
+	// Failovers is a number of master changes during iteration.
+	Failovers int
+	// Restarts is a number of times iteration started over from zero.
+	Restarts int
+	// Retries is a number of SCAN calls retried after transient errors.
+	Retries int
+}
+
+// Scan iterates keys of master with SCAN, calling f with every non-empty
+// batch. When master changes during iteration it goes on against new master
+// as opts.Policy says; SCAN failing with transient error, e.g. while
+// failover is in progress, is retried after a pause. Iteration stops at the
+// first error f returns. Report tells what happened even on error.
+func (sp *SentinelPool) Scan(ctx context.Context, opts ScanOptions, f func(keys []string) error) (ScanReport, error) {
+	var report ScanReport
+	maxFailovers := opts.MaxFailovers
+	if maxFailovers <= 0 {
+		maxFailovers = defaultScanFailovers
+	}
+	if _, err := sp._resolveMaster(ctx); err != nil {
+		return report, err
+	}
+	info := sp.MasterAddrInfo()
+	report.Masters = append(report.Masters, info.Addr)
+
+	cursor, retries := "0", 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		keys, next, err := sp.scanStep(ctx, cursor, opts)
+		if cur := sp.MasterAddrInfo(); cur.Generation != info.Generation {
+			report.Failovers++
+			report.Masters = append(report.Masters, cur.Addr)
+			logFields(sp.logger(), LogWarn, "scan interrupted by master change",
+				"master", sp.sntl.masterName(), "old", info.Addr, "new", cur.Addr,
+				"cursor", cursor)
+			info = cur
+			if report.Failovers > maxFailovers {
+				return report, ErrScanInterrupted
+			}
+			if opts.Policy == ScanRestart {
+				// batch may come from either master, it is seen again anyway
+				report.Restarts++
+				cursor, retries = "0", 0
+				continue
+			}
+		}
+		if err != nil {
+			if !IsTransient(err) || retries >= scanRetries {
+				return report, err
+			}
+			retries++
+			report.Retries++
+			if err := sp.pause(ctx, sp.jitter(monitorRetryDelay, monitorRetryJitter)); err != nil {
+				return report, err
+			}
+			continue
+		}
+		retries = 0
+		if len(keys) > 0 {
+			if err := f(keys); err != nil {
+				return report, err
+			}
+		}
+		if next == "0" {
+			return report, nil
+		}
+		cursor = next
+	}
+}
+
+// ScanKeys is like Scan, but collects keys, each of them once.
+func (sp *SentinelPool) ScanKeys(ctx context.Context, opts ScanOptions) ([]string, ScanReport, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	report, err := sp.Scan(ctx, opts, func(batch []string) error {
+		for _, key := range batch {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		return nil
+	})
+	return keys, report, err
+}
+
+// scanStep runs single SCAN from cursor and returns keys and next cursor.
+func (sp *SentinelPool) scanStep(ctx context.Context, cursor string, opts ScanOptions) ([]string, string, error) {
+	c, err := sp.GetContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer c.Close()
+	args := redis.Args{cursor}
+	if opts.Match != "" {
+		args = args.Add("MATCH", opts.Match)
+	}
+	if opts.Count > 0 {
+		args = args.Add("COUNT", opts.Count)
+	}
+	if opts.Type != "" {
+		args = args.Add("TYPE", opts.Type)
+	}
+	values, err := redis.Values(c.Do("SCAN", args...))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(values) != 2 {
+		return nil, "", fmt.Errorf("redigo: unexpected SCAN reply of %d elements", len(values))
+	}
+	next, err := redis.String(values[0], nil)
+	if err != nil {
+		return nil, "", err
+	}
+	keys, err := redis.Strings(values[1], nil)
+	return keys, next, err
+}
+
+// pause waits for d unless ctx is done or pool is closed meanwhile.
+func (sp *SentinelPool) pause(ctx context.Context, d time.Duration) error {
+	t := sp.clock().NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sp.done:
+		return ErrPoolClosed
+	}
+}
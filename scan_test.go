@@ -0,0 +1,62 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestScanFailover(t *testing.T) {
+	for _, policy := range []ScanPolicy{ScanRestart, ScanContinue} {
+		cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+			WithTopologyLogLevel(LogOff), WithStrictConsistency(time.Second))
+		for i := 0; i < 25; i++ {
+			if _, err := Do(sp, redis.String, "SET", fmt.Sprintf("old%02d", i), "v"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		oldMaster, newMaster := cluster.Master.Addr(), cluster.Replicas[0].Addr()
+
+		var keys []string
+		report, err := sp.Scan(context.Background(), ScanOptions{Count: 10, Policy: policy}, func(batch []string) error {
+			if len(keys) == 0 {
+				changes := sp.MasterChanges()
+				cluster.Failover(cluster.Replicas[0])
+				<-changes
+				for i := 0; i < 5; i++ {
+					if _, err := Do(sp, redis.String, "SET", fmt.Sprintf("new%02d", i), "v"); err != nil {
+						return err
+					}
+				}
+			}
+			keys = append(keys, batch...)
+			return nil
+		})
+		sp.Close()
+		cluster.Close()
+		if err != nil {
+			t.Fatalf("policy %d: %v", policy, err)
+		}
+		if report.Failovers != 1 || fmt.Sprint(report.Masters) != fmt.Sprint([]string{oldMaster, newMaster}) {
+			t.Fatalf("policy %d: unexpected report %+v", policy, report)
+		}
+		switch policy {
+		case ScanRestart:
+			if report.Restarts != 1 || len(keys) != 15 || keys[10] != "new00" {
+				t.Fatalf("restart: unexpected report %+v, keys %v", report, keys)
+			}
+		case ScanContinue:
+			if report.Restarts != 0 || len(keys) != 10 {
+				t.Fatalf("continue: unexpected report %+v, keys %v", report, keys)
+			}
+		}
+	}
+}
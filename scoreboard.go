@@ -0,0 +1,56 @@
+package sentinel
+
+import "time"
+
+// PingSentinels sends PING to every known Sentinel at once and records the
+// outcome in registry, see Nodes. Sentinels which did not reply are moved to
+// the end of Addrs, so queries try them last.
+func (s *Sentinel) PingSentinels() {
+	s.mu.RLock()
+	addrs := s.Addrs
+	s.mu.RUnlock()
+	forEachAddr(addrs, len(addrs), func(addr string) error {
+		conn := s.get(addr)
+		start := time.Now()
+		_, err := conn.Do("PING")
+		latency := time.Since(start)
+		conn.Close()
+		s.metricsFor(addr).countRequest(latency, err)
+
+		s.mu.Lock()
+		if err == nil {
+			s.seen(addr, latency)
+			s.mu.Unlock()
+			return nil
+		}
+		s.failed(addr)
+		if pool, ok := s.pools[addr]; ok {
+			pool.Close()
+			delete(s.pools, addr)
+		}
+		// Sentinel may have been removed meanwhile
+		known := stringInSlice(addr, s.Addrs)
+		if known {
+			s.putToBottom(addr)
+		}
+		s.mu.Unlock()
+		if known {
+			s.sentinelDown(addr, err)
+		}
+		return err
+	})
+}
+
+// _pingSentinels pings sentinels at interval until pool is closed.
+func (sp *SentinelPool) _pingSentinels() {
+	ticker := sp.clock().NewTicker(sp.opts.sentinelPing)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sp.done:
+			return
+		case <-ticker.C():
+		}
+		sp.sntl.PingSentinels()
+	}
+}
@@ -0,0 +1,34 @@
+package sentinel
+
+import (
+	"testing"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestPingSentinels(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	up, down := cluster.Sentinels[1].Addr(), cluster.Sentinels[0].Addr()
+	cluster.Sentinels[0].Close()
+
+	s := NewSentinel([]string{down, up}, "mymaster")
+	s.TopologyLogLevel = LogOff
+	defer s.Close()
+	s.PingSentinels()
+	s.PingSentinels()
+
+	nodes := s.Nodes()
+	if len(nodes) != 2 || nodes[0].Addr != up || nodes[1].Addr != down {
+		t.Fatalf("down sentinel must be tried last, got %+v", nodes)
+	}
+	if !nodes[0].Up || nodes[0].ConsecutiveFailures != 0 || nodes[0].LastSeen.IsZero() {
+		t.Fatalf("unexpected scoreboard of live sentinel %+v", nodes[0])
+	}
+	if nodes[1].Up || nodes[1].ConsecutiveFailures != 2 {
+		t.Fatalf("unexpected scoreboard of down sentinel %+v", nodes[1])
+	}
+}
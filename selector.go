@@ -0,0 +1,77 @@
+package sentinel
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// Replica is a replica Selector picks from.
+type Replica struct {
+	Addr string
+	// InUse is a number of connections to replica currently borrowed from
+	// pool.
+	InUse int
+}
+
+// Selector picks replica GetReplica connects to, so reads are spread
+// across replicas by chosen policy. Select is called with at least one
+// replica and returns index of the picked one. If connection to it fails,
+// Select is called again with remaining replicas. It is called
+// concurrently.
+type Selector interface {
+	Select(replicas []Replica) int
+}
+
+// SelectorFunc is an adapter to use ordinary function as Selector.
+type SelectorFunc func(replicas []Replica) int
+
+// Select calls f(replicas).
+func (f SelectorFunc) Select(replicas []Replica) int {
+	return f(replicas)
+}
+
+// RoundRobinSelector returns Selector picking replicas in turn. It is used
+// by default.
+func RoundRobinSelector() Selector {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next uint32
+}
+
+func (rr *roundRobin) Select(replicas []Replica) int {
+	return int(atomic.AddUint32(&rr.next, 1) % uint32(len(replicas)))
+}
+
+// RandomSelector returns Selector picking random replica. Pool configured
+// with WithRandSource picks from that source instead of global math/rand.
+func RandomSelector() Selector {
+	return randomSelector{}
+}
+
+type randomSelector struct {
+	rand *rand.Rand
+}
+
+func (rs randomSelector) Select(replicas []Replica) int {
+	if rs.rand != nil {
+		return rs.rand.Intn(len(replicas))
+	}
+	return rand.Intn(len(replicas))
+}
+
+// LeastConnectionsSelector returns Selector picking replica with fewest
+// connections in use, the first one of them if several have the same
+// number.
+func LeastConnectionsSelector() Selector {
+	return SelectorFunc(func(replicas []Replica) int {
+		least := 0
+		for i, r := range replicas {
+			if r.InUse < replicas[least].InUse {
+				least = i
+			}
+		}
+		return least
+	})
+}
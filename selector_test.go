@@ -0,0 +1,91 @@
+package sentinel
+
+import (
+	"testing"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSelectors(t *testing.T) {
+	replicas := []Replica{{Addr: "a", InUse: 3}, {Addr: "b", InUse: 1}, {Addr: "c", InUse: 1}}
+
+	rr := RoundRobinSelector()
+	seen := make(map[int]int)
+	for i := 0; i < 6; i++ {
+		seen[rr.Select(replicas)]++
+	}
+	if seen[0] != 2 || seen[1] != 2 || seen[2] != 2 {
+		t.Fatalf("replicas are not picked in turn: %v", seen)
+	}
+	if i := LeastConnectionsSelector().Select(replicas); i != 1 {
+		t.Fatalf("expected replica with fewest connections, got %d", i)
+	}
+	random := RandomSelector()
+	for i := 0; i < 10; i++ {
+		if i := random.Select(replicas); i < 0 || i >= len(replicas) {
+			t.Fatalf("index %d out of range", i)
+		}
+	}
+}
+
+func TestSentinelPoolReplicaSelector(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithReplicaSelector(LeastConnectionsSelector()))
+	defer sp.Close()
+
+	busy := sp.GetReplica()
+	defer busy.Close()
+	if _, err := busy.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		c := sp.GetReplica()
+		if _, err := c.Do("PING"); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	st := sp.Stats()
+	first, second := st.AddrCommands[cluster.Replicas[0].Addr()], st.AddrCommands[cluster.Replicas[1].Addr()]
+	if first+second != 4 || (first != 1 && second != 1) {
+		t.Fatalf("replica with borrowed connection must be avoided: %+v", st.AddrCommands)
+	}
+}
+
+func TestSentinelPoolReplicaSelectorFallback(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	dead := cluster.Replicas[0].Addr()
+	cluster.Replicas[0].Close()
+
+	var picked [][]Replica
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithReplicaSelector(SelectorFunc(func(replicas []Replica) int {
+			picked = append(picked, replicas)
+			for i, r := range replicas {
+				if r.Addr == dead {
+					return i
+				}
+			}
+			return 0
+		})))
+	defer sp.Close()
+
+	c := sp.GetReplica()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	if len(picked) != 2 || len(picked[0]) != 2 || len(picked[1]) != 1 || picked[1][0].Addr == dead {
+		t.Fatalf("failed replica must be excluded from next pick: %v", picked)
+	}
+}
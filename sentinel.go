@@ -1,16 +1,16 @@
 package sentinel
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"bytes"
-
-	log "github.com/cihub/seelog"
-	"github.com/garyburd/redigo/redis"
+	"github.com/gomodule/redigo/redis"
 )
 
 // Sentinel provides a way to add high availability (HA) to Redis Pool using
@@ -61,10 +61,18 @@ import (
 //  }
 
 const (
-	switchMasterChannel = "+switch-master"
-	defaultTimeout      = 10 // seconds
+	defaultTimeout     = 10 // seconds
+	monitorRetryDelay  = time.Second
+	monitorRetryJitter = 0.2
+	// stableSubscription is how long subscription has to last before
+	// failures to keep it are no longer considered consecutive.
+	stableSubscription = 10 * time.Second
 )
 
+// errSubscriptionLost is reported to monitor backoff when subscription to
+// master switch events ended shortly after it was made.
+var errSubscriptionLost = errors.New("redigo: subscription to master switch events lost")
+
 type Sentinel struct {
 	// Addrs is a slice with known Sentinel addresses.
 	Addrs []string
@@ -78,32 +86,178 @@ type Sentinel struct {
 	// while connecting to Sentinels, and should not be set to 0.
 	Dial func(addr string) (redis.Conn, error)
 
+	// DialContext is like Dial, but connection attempt should be aborted once
+	// ctx is done. It takes precedence over Dial if both are set.
+	DialContext func(ctx context.Context, addr string) (redis.Conn, error)
+
+	// Dialer connects to Sentinel for discovery queries when set, taking
+	// precedence over DialContext and Dial, so they can be made with a
+	// Redis client other than redigo. Connections it makes do not support
+	// pub/sub, so events still need redigo connections made by
+	// SubscribeDial or SubscribeDialContext, or master switches can be
+	// detected by polling, see WithPolling. If neither them nor Dial is set,
+	// events are subscribed to with default redigo connections.
+	Dialer Dialer
+
+	// SubscribeDial is an optional function to connect to Sentinel for listening
+	// to events. Unlike connections made by Dial it should not have read timeout
+	// set as no data is sent over it until an event occurs. If nil,
+	// SubscribeDialContext, DialContext or Dial is used.
+	SubscribeDial func(addr string) (redis.Conn, error)
+
+	// SubscribeDialContext is like SubscribeDial, but connection attempt
+	// should be aborted once ctx is done, which happens when Sentinel is
+	// closed. SubscribeDial takes precedence if both are set, so it can
+	// still replace the one NewSentinel sets.
+	SubscribeDialContext func(ctx context.Context, addr string) (redis.Conn, error)
+
+	// Tiers optionally groups Addrs by priority, e.g. sentinels in local data
+	// center first and remote ones second. Sentinels of a tier are all tried
+	// before any Sentinel of the next one, both for queries and subscription.
+	// Addresses not listed in any tier are tried last.
+	Tiers [][]string
+
 	// Pool is a user supplied function returning custom connection pool to Sentinel.
 	// This can be useful to tune options if you are not satisfied with what default
 	// Sentinel pool offers. See defaultPool() method for default pool implementation.
 	// In most cases you only need to provide Dial function and let this be nil.
 	Pool func(addr string) *redis.Pool
 
-	mu    sync.RWMutex
-	pools map[string]*redis.Pool
-	addr  string
+	// Bootstrap is an optional master Sentinels are asked to monitor when they
+	// do not know MasterName, see Monitor. Leave nil unless client is
+	// responsible for provisioning Sentinels.
+	Bootstrap *MonitorSeed
+
+	// TopologyLogLevel is a level changes of Sentinel order and of replica
+	// membership are logged at.
+	TopologyLogLevel LogLevel
+
+	// EventChannels is a set of Sentinel event channels, like +sdown or
+	// +odown, subscriptions made by MasterSwitch listen on. Only switch
+	// master channel is subscribed to by default, see InstanceEventChannels
+	// for events preceding it. Note that MasterSentinel Watch relies on
+	// switch master channel being in the set.
+	EventChannels []string
+
+	// Firehose makes subscriptions made by MasterSwitch listen to every
+	// Sentinel event channel using PSUBSCRIBE, ignoring EventChannels.
+	Firehose bool
+
+	// ClientFlags are CLIENT options issued on every connection to Sentinel
+	// made by default pool or for subscription, e.g. NoEvict for monitoring
+	// connections.
+	ClientFlags ClientFlags
+
+	// NodeClientFlags are CLIENT options issued on every connection to
+	// masters and replicas Sentinel dials itself, like ones of MasterConn
+	// or of role and replication offset probes.
+	NodeClientFlags ClientFlags
+
+	// IdleReaper controls idle connections kept by default pools to
+	// sentinels.
+	IdleReaper IdleReaper
+
+	// SubscriptionKeepalive is an interval subscriptions made by
+	// MasterSwitch are PINGed at, so that half-open connection to Sentinel
+	// is detected within twice the interval and subscription fails instead
+	// of waiting for events forever. Zero disables keepalive.
+	SubscriptionKeepalive time.Duration
+
+	// AllSlaves makes SlaveAddrs return replicas flagged s_down, o_down or
+	// disconnected too. By default they are skipped, so reads are not
+	// routed to dead replicas.
+	AllSlaves bool
+
+	// Family restricts or orders address families used by default dialers
+	// for sentinels, masters and replicas.
+	Family AddressFamily
+
+	// Logger receives messages about topology changes and failures.
+	// Nothing is logged if it is nil.
+	Logger Logger
+
+	// Tracer starts spans around queries to sentinels, see Tracer. Nothing
+	// is traced if it is nil.
+	Tracer Tracer
+
+	// Hooks are callbacks notified when sentinels fail and dials to them
+	// fail. OnMasterSwitch is only called by SentinelPool.
+	Hooks Hooks
+
+	// TLSConfig makes default dialers for sentinels, masters and replicas
+	// connect over TLS. Unless it sets ServerName, host of dialed address
+	// is used for SNI and verification.
+	TLSConfig *tls.Config
+
+	// Credentials returns credentials to AUTH connections to sentinels,
+	// masters and replicas with. Nil means no AUTH except for passwords
+	// given explicitly.
+	Credentials CredentialsFunc
+
+	// SentinelUsername and SentinelPassword AUTH connections to sentinels,
+	// including subscriptions, when Credentials returns none for them.
+	// Username requires Redis 6 ACL and can be empty.
+	SentinelUsername string
+	SentinelPassword string
+
+	mu       sync.RWMutex
+	pools    map[string]*redis.Pool
+	addr     string
+	nodes    map[string]*SentinelNode
+	metrics  map[string]*sentinelPoolMetrics
+	slaves   []string
+	onPanic  func(goroutine string, v interface{})
+	watchers map[*MasterSentinel]struct{}
+	master   string
+	// masterSource is an address of Sentinel master was learned from.
+	masterSource string
+	epoch        int64
+	// generation is incremented every time master address changes.
+	generation uint64
+	// done is closed by Close.
+	done chan struct{}
+}
+
+// NewSentinelContext is like NewSentinel, but once ctx is done all
+// subscriptions made with MasterSwitch are closed and Sentinel is closed.
+func NewSentinelContext(ctx context.Context, addrs []string, masterName string) *Sentinel {
+	s := NewSentinel(addrs, masterName)
+	done := s.closedChan()
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.closeWatchers()
+			s.Close()
+		case <-done:
+		}
+	}()
+	return s
 }
 
 func NewSentinel(addrs []string, masterName string) *Sentinel {
-	return &Sentinel{
-		Addrs:      addrs,
+	s := &Sentinel{
+		Addrs:      normalizeAddrs(addrs),
 		MasterName: masterName,
-		Dial: func(addr string) (redis.Conn, error) {
-			timeout := defaultTimeout * time.Second
-			// read timeout set to 0 to wait sentinel notify
-			c, err := redis.DialTimeout("tcp", addr,
-				timeout, 0, timeout)
-			if err != nil {
-				return nil, err
-			}
-			return c, nil
-		},
 	}
+	s.DialContext = func(ctx context.Context, addr string) (redis.Conn, error) {
+		timeout := defaultTimeout * time.Second
+		c, err := dialContext(ctx, addr, s.Family, s.TLSConfig,
+			timeout, timeout, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	s.SubscribeDialContext = s.dialSubscribe
+	return s
+}
+
+// dialSubscribe dials Sentinel on addr with redigo for pub/sub, the default
+// SubscribeDialContext.
+func (s *Sentinel) dialSubscribe(ctx context.Context, addr string) (redis.Conn, error) {
+	timeout := defaultTimeout * time.Second
+	// read timeout set to 0 to wait sentinel notify
+	return dialContext(ctx, addr, s.Family, s.TLSConfig, timeout, 0, timeout)
 }
 
 type SentinelPool struct {
@@ -113,91 +267,558 @@ type SentinelPool struct {
 	mu            *sync.RWMutex
 	curAddr       string
 	closed        bool
+	opts          poolOptions
+	commands      commandCounter
+	db            int
+	cfg           PoolConfig
+	paused        bool
+	pendingAddr   string
+	dialBudget    *tokenBucket
+	tracer        *failoverTracer
+	failover      failoverSpan
+	done          chan struct{}
+	changeSubs    []chan MasterChange
+	roles         roleCache
+	resolvedAt    time.Time
+	masterSource  string
+	generation    uint64
+	state         PoolState
+	stateHandlers []func(StateTransition)
+	stateQueue    []StateTransition
+	notifying     bool
+	droppedEvents uint64
+	dialOutcomes  outcomeWindow
+	waiting       int64
+	replicas      replicaSet
+	switches      uint64
+	lastSwitch    time.Time
+	resubscribes  uint64
+	subscribed    bool
+	switchQueue   []MasterChange
+	switchRunning bool
+	gate          failoverGate
+
+	resolveMu   sync.Mutex
+	resolving   *resolveCall
+	monitorOnce sync.Once
+	lagOnce     sync.Once
 }
 
+// NewSentinelPool creates pool of connections to current master of masterName.
+// Unless WithLazyInit option is given, master address is resolved before
+// return and it panics if no sentinel could answer. See NewSentinelPoolE.
 func NewSentinelPool(addrs []string, masterName string,
-	defaultDb int, password string) *SentinelPool {
+	defaultDb int, password string, options ...PoolOption) *SentinelPool {
+	sp, err := NewSentinelPoolE(addrs, masterName, defaultDb, password, options...)
+	if err != nil {
+		panic(err)
+	}
+	return sp
+}
+
+// NewSentinelPoolE is like NewSentinelPool, but returns error instead of
+// panicking when master address could not be resolved, so caller can retry.
+// With WithLazyInit it never fails and resolving is deferred to first Get.
+func NewSentinelPoolE(addrs []string, masterName string,
+	defaultDb int, password string, options ...PoolOption) (*SentinelPool, error) {
 	sp := &SentinelPool{
 		sntl: NewSentinel(addrs, masterName),
 		mu:   &sync.RWMutex{},
+		done: make(chan struct{}),
 	}
-	var err error
-	sp.curAddr, err = sp.sntl.MasterAddr()
-	if err != nil {
-		panic(err)
+	for _, opt := range options {
+		opt(&sp.opts)
+	}
+	sp.state.Since = sp.clock().Now()
+	sp._configureSentinel()
+	sp.replicas.selector = sp.opts.replicaSelector
+	if sp.replicas.selector == nil {
+		sp.replicas.selector = RoundRobinSelector()
+	}
+	if _, ok := sp.replicas.selector.(randomSelector); ok && sp.opts.rand != nil {
+		sp.replicas.selector = randomSelector{rand: sp.opts.rand}
+	}
+	if sp.opts.failoverTrace != nil {
+		sp.tracer = &failoverTracer{report: sp.opts.failoverTrace}
+	}
+	if sp.opts.dialBudget > 0 {
+		sp.dialBudget = newTokenBucket(sp.opts.dialBudget, sp.opts.dialBurst)
+	}
+	if t := sp.opts.topology; t != nil {
+		sp.sntl.Import(*t)
+		if t.MasterName == masterName && t.Master != "" {
+			sp._adoptMaster(t.Master, "import")
+			sp._startMonitor()
+		}
+	}
+	if !sp.opts.lazyInit && sp.curAddr == "" {
+		start := time.Now()
+		addr, err := sp.sntl.MasterAddr()
+		if err != nil {
+			sp.sntl.Close()
+			return nil, err
+		}
+		sp._adoptMaster(addr, "initial", "latency", time.Since(start))
+		sp._startMonitor()
 	}
-	go sp._monitorMaster()
 
 	sp._initPool(defaultDb, password)
-	return sp
+	if sp.opts.healthInterval > 0 {
+		go sp._runGuarded("health", sp._checkHealth)
+	}
+	if sp.opts.sentinelPing > 0 {
+		go sp._runGuarded("sentinel health", sp._pingSentinels)
+	}
+	if ctx := sp.opts.ctx; ctx != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				sp.Close()
+			case <-sp.done:
+			}
+		}()
+	}
+	return sp, nil
+}
+
+// _configureSentinel applies pool options to Sentinel pool works with.
+func (sp *SentinelPool) _configureSentinel() {
+	sp.sntl.ClientFlags = sp.opts.sentinelClientFlags
+	sp.sntl.NodeClientFlags = sp.opts.probeClientFlags
+	sp.sntl.SubscriptionKeepalive = sp.opts.keepalive
+	sp.sntl.Credentials = sp.credentials
+	sp.sntl.SentinelUsername = sp.opts.sentinelAuth.Username
+	sp.sntl.SentinelPassword = sp.opts.sentinelAuth.Password
+	sp.sntl.Family = sp.opts.family
+	sp.sntl.TLSConfig = sp.opts.tlsConfig
+	if sp.opts.clientCert != nil {
+		sp.sntl.TLSConfig = clientCertConfig(sp.opts.tlsConfig, sp.opts.clientCert)
+	}
+	sp.sntl.IdleReaper = sp.opts.sentinelIdleReaper
+	sp.sntl.Bootstrap = sp.opts.bootstrap
+	sp.sntl.EventChannels = append([]string{ChannelSwitchMaster}, replicaEventChannels...)
+	if sp.opts.failoverWait > 0 {
+		sp.sntl.EventChannels = append(sp.sntl.EventChannels, failoverEventChannels...)
+	}
+	for _, tier := range sp.opts.sentinelTiers {
+		sp.sntl.Tiers = append(sp.sntl.Tiers, normalizeAddrs(tier))
+	}
+	sp.sntl.TopologyLogLevel = sp.opts.topologyLogLevel
+	sp.sntl.Logger = sp.opts.logger
+	sp.sntl.Tracer = sp.opts.tracer
+	sp.sntl.Hooks = sp.opts.hooks
+	sp.sntl.onPanic = sp.opts.onPanic
+	for _, tier := range sp.sntl.Tiers {
+		for _, addr := range tier {
+			if !stringInSlice(addr, sp.sntl.Addrs) {
+				sp.sntl.Addrs = append(sp.sntl.Addrs, addr)
+			}
+		}
+	}
+}
+
+// resolveCall is a master address lookup shared by concurrent callers.
+type resolveCall struct {
+	done chan struct{}
+	addr string
+	err  error
+}
+
+// _resolveMaster returns current master address, asking sentinels if it has
+// not been resolved yet. Concurrent callers wait for a single lookup, each
+// until its ctx is done; lookup itself goes on for callers still waiting.
+func (sp *SentinelPool) _resolveMaster(ctx context.Context) (string, error) {
+	sp.mu.RLock()
+	addr := sp.curAddr
+	sp.mu.RUnlock()
+	if addr != "" {
+		return addr, nil
+	}
+
+	sp.resolveMu.Lock()
+	call := sp.resolving
+	if call == nil {
+		call = &resolveCall{done: make(chan struct{})}
+		sp.resolving = call
+		go sp.resolveMaster(call)
+	}
+	sp.resolveMu.Unlock()
+	select {
+	case <-call.done:
+		return call.addr, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// resolveMaster looks master address up for call and adopts it.
+func (sp *SentinelPool) resolveMaster(call *resolveCall) {
+	start := time.Now()
+	call.addr, call.err = sp.sntl.MasterAddr()
+	if call.err == nil {
+		sp.mu.Lock()
+		// monitor may have already seen a switch
+		if sp.curAddr == "" {
+			sp._adoptMaster(call.addr, "resolve", "latency", time.Since(start))
+		} else {
+			call.addr = sp.curAddr
+		}
+		sp.mu.Unlock()
+		sp._startMonitor()
+	}
+
+	sp.resolveMu.Lock()
+	sp.resolving = nil
+	sp.resolveMu.Unlock()
+	close(call.done)
+}
+
+func (sp *SentinelPool) _startMonitor() {
+	sp.monitorOnce.Do(func() {
+		if sp.opts.pollInterval > 0 {
+			go sp._runGuarded("poll", sp._pollMaster)
+			if !sp.opts.pollHybrid {
+				return
+			}
+		}
+		if sp.opts.sharedMonitor {
+			// events are delivered by MultiSentinelPool
+			return
+		}
+		go sp._runGuarded("monitor", sp._monitorMaster)
+	})
 }
 
 func (sp *SentinelPool) _monitorMaster() {
+	failures := 0
 	for {
 		sp.mu.RLock()
-		if sp.closed {
-			log.Debug("sentinel pool closed")
-			break
-		}
+		closed := sp.closed
 		sp.mu.RUnlock()
+		if closed {
+			logFields(sp.logger(), LogDebug, "sentinel pool closed",
+				"master", sp.sntl.masterName())
+			return
+		}
 		ms, err := sp.sntl.MasterSwitch()
 		if err != nil {
-			log.Errorf("subscript master switch error:%v",
-				err)
+			logFields(sp.logger(), LogError, "subscribe to master switch failed",
+				"master", sp.sntl.masterName(), "err", err)
+			sp.setState(StateDegraded, "subscribe failed")
+			failures++
+			sp.monitorFailed(err, failures)
+			continue
 		}
 		w, err := ms.Watch()
 		if err != nil {
-			log.Errorf("watch channel error:%v",
-				err)
+			logFields(sp.logger(), LogError, "watch master switch failed",
+				"master", sp.sntl.masterName(), "err", err)
+			ms.Close()
+			failures++
+			sp.monitorFailed(err, failures)
+			continue
+		}
+		subscribed := sp.clock().Now()
+		if events, err := ms.Events(); err == nil {
+			go sp.watchEvents(events)
 		}
 		sp.mu.Lock()
 		sp.masterWatcher = ms
+		if sp.subscribed {
+			sp.resubscribes++
+		}
+		sp.subscribed = true
 		sp.mu.Unlock()
 		for addr := range w {
-			sp.mu.Lock()
-			sp.curAddr = addr
-			sp.mu.Unlock()
+			sp.masterReported(addr, "switch-master", sp.clock().Now())
 		}
+		err = ms.Err()
 		// close in case error occured
 		ms.Close()
+		// failover can not be followed without subscription
+		sp.gate.open()
+		sp.mu.Lock()
+		sp.droppedEvents += ms.Dropped()
+		sp.masterWatcher = nil
+		closed = sp.closed
+		sp.mu.Unlock()
+		if closed {
+			continue
+		}
+		// subscription dropped right away, e.g. by proxy, counts as failure
+		if sp.clock().Now().Sub(subscribed) >= stableSubscription {
+			failures = 0
+		} else {
+			if err == nil {
+				err = errSubscriptionLost
+			}
+			failures++
+			sp.monitorFailed(err, failures)
+		}
+	}
+}
+
+// masterReported switches pool to master on addr reported at received,
+// verifying its role first if WithMasterVerification is used.
+func (sp *SentinelPool) masterReported(addr, reason string, received time.Time) {
+	verifying, verified := sp.verifyMaster(addr)
+	sp.mu.Lock()
+	sp._switchMaster(addr, reason, received, verifying, verified)
+	sp.mu.Unlock()
+}
+
+// _switchMaster switches pool to master on addr learned at received,
+// unless it is already used or switching is paused. Verifying is when
+// verification of its role started, zero if it was not verified.
+// Lock must be held by caller.
+func (sp *SentinelPool) _switchMaster(addr, reason string, received, verifying time.Time, verified bool) {
+	switch {
+	case sp.paused:
+		sp.pendingAddr = addr
+		sp.gate.open()
+	case addr == sp.curAddr:
+		// replay of master already in use, or failover kept it, so
+		// callers waiting for failover have nothing to wait for
+		sp.gate.open()
+	default:
+		sp.tracer.begin(sp.sntl.masterName(), sp.curAddr, addr, received)
+		sp.failover.begin(sp.sntl, sp.curAddr, addr)
+		if verifying.IsZero() {
+			sp.tracer.span(SpanEvent, received)
+			sp._adoptMaster(addr, reason)
+			return
+		}
+		sp.tracer.spanUntil(SpanEvent, received, verifying)
+		sp.tracer.span(SpanVerification, verifying)
+		sp._adoptMaster(addr, reason, "verified", verified)
 	}
 }
 
 func (sp *SentinelPool) _initPool(defaultDb int, password string) {
-	sp.pool = &redis.Pool{
-		MaxIdle:     16,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			sp.mu.RLock()
-			addr := sp.curAddr
-			sp.mu.RUnlock()
-			timeout := defaultTimeout * time.Second
-			c, err := redis.DialTimeout("tcp", addr,
-				timeout, timeout, timeout)
-			if err != nil {
-				return nil, err
-			}
-			if password != "" {
-				if _, err := c.Do("AUTH", password); err != nil {
-					c.Close()
-					return nil, err
-				}
-			}
-			_, selectErr := c.Do("SELECT", defaultDb)
-			if selectErr != nil {
-				c.Close()
-				return nil, selectErr
+	sp.db = defaultDb
+	sp.cfg.Username = sp.opts.username
+	sp.cfg.Password = password
+	sp.cfg.MaxIdle, sp.cfg.IdleTimeout = sp.opts.idleReaper.apply(16)
+	sp.cfg.MaxActive = sp.opts.maxActive
+	sp.cfg.MaxConnLifetime = sp.opts.maxConnLifetime
+	sp.pool = sp._newPool()
+}
+
+// _newPool creates connection pool sized according to current config, or
+// one made by pool factory, see WithPool.
+// Lock must be held by caller.
+func (sp *SentinelPool) _newPool() *redis.Pool {
+	var pool *redis.Pool
+	if sp.opts.poolFactory != nil {
+		pool = sp.opts.poolFactory(func() (redis.Conn, error) {
+			return sp.dialPooled(context.Background())
+		})
+	} else {
+		pool = &redis.Pool{
+			MaxIdle:         sp.cfg.MaxIdle,
+			MaxActive:       sp.cfg.MaxActive,
+			IdleTimeout:     sp.cfg.IdleTimeout,
+			MaxConnLifetime: sp.cfg.MaxConnLifetime,
+			Wait:            sp.opts.wait || sp.opts.waitTimeout > 0,
+			DialContext:     sp.dialPooled,
+		}
+	}
+	tests := []func(redis.Conn, time.Time) error{pool.TestOnBorrow, sp.opts.testOnBorrow}
+	if sp.opts.roleOnBorrow {
+		tests = append(tests, testRoleOnBorrow)
+	}
+	if sp.opts.strict {
+		tests = append(tests, sp.testOnBorrow)
+	}
+	pool.TestOnBorrow = chainTestOnBorrow(tests...)
+	return pool
+}
+
+// chainTestOnBorrow returns TestOnBorrow function running non-nil tests in
+// order until one fails, or nil if there are none.
+func chainTestOnBorrow(tests ...func(redis.Conn, time.Time) error) func(redis.Conn, time.Time) error {
+	var chain []func(redis.Conn, time.Time) error
+	for _, test := range tests {
+		if test != nil {
+			chain = append(chain, test)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return func(c redis.Conn, t time.Time) error {
+		for _, test := range chain {
+			if err := test(c, t); err != nil {
+				return err
 			}
-			return c, nil
-		},
+		}
+		return nil
 	}
 }
 
+// connectTimeout returns timeout of connecting to master or replica.
+func (sp *SentinelPool) connectTimeout() time.Duration {
+	if sp.opts.dialTimeout > 0 {
+		return sp.opts.dialTimeout
+	}
+	return defaultTimeout * time.Second
+}
+
+// dialPooled dials current master for connection pool, giving up once ctx
+// is done.
+func (sp *SentinelPool) dialPooled(ctx context.Context) (_ redis.Conn, err error) {
+	defer func() {
+		if err != ErrDialBudgetExhausted && (err == nil || ctx.Err() == nil) {
+			sp.dialOutcomes.record(err != nil)
+		}
+	}()
+	addr, err := sp._resolveMaster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds := sp.credentials(addr, roleMaster)
+	if sp.dialBudget != nil && !sp.dialBudget.allow(sp.clock().Now()) {
+		return nil, ErrDialBudgetExhausted
+	}
+	timeout := defaultTimeout * time.Second
+	c, err := dialContext(ctx, addr, sp.sntl.Family, sp.sntl.TLSConfig,
+		sp.connectTimeout(), timeout, timeout, sp.opts.dialOptions...)
+	if err != nil {
+		if ctx.Err() != nil {
+			// master is not blamed for dial abandoned by caller
+			return nil, err
+		}
+		if sp.dialBudget != nil {
+			sp.dialBudget.take(sp.clock().Now())
+		}
+		logFields(sp.logger(), LogWarn, "master dial failed",
+			"master", sp.sntl.masterName(), "addr", addr, "err", err)
+		sp.failover.dialed(addr, err)
+		sp.sntl.dialFailed(addr, err)
+		sp.setState(StateDegraded, "dial failed")
+		return nil, err
+	}
+	hc := withContext(ctx, c)
+	if err := creds.auth(hc); err != nil {
+		c.Close()
+		return nil, err
+	}
+	_, selectErr := hc.Do("SELECT", sp.db)
+	if selectErr != nil {
+		c.Close()
+		return nil, selectErr
+	}
+	if err := sp.opts.clientFlags.apply(hc); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if sp.opts.strict {
+		if err := sp.verifyRole(hc, addr); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	sp.tracer.connected(addr)
+	sp.failover.dialed(addr, nil)
+	sp.setState(StateHealthy, "connected")
+	return &countingConn{
+		Conn:    c,
+		counter: &sp.commands,
+		role:    roleMaster,
+		addr:    addr,
+	}, nil
+}
+
+// Pool returns connection pool SentinelPool currently hands out connections
+// from, e.g. to instrument it. It is replaced when master name changes,
+// Reload changes pool sizing or master switches with WithDrainOnSwitch, and
+// must not be closed by caller.
+func (p *SentinelPool) Pool() *redis.Pool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pool
+}
+
+// Get gets connection to master. It implements redis.ConnWithContext and
+// redis.ConnWithTimeout, so redis.DoContext and redis.DoWithTimeout can be
+// used with it.
 // redis.Conn must Close after use
 func (p *SentinelPool) Get() redis.Conn {
-	return p.pool.Get()
+	if err := p.waitFailover(context.Background()); err != nil {
+		return errorConn{err}
+	}
+	p.mu.RLock()
+	pool := p.pool
+	p.mu.RUnlock()
+	c, _ := p.getContext(context.Background(), pool)
+	return c
+}
+
+// GetContext gets connection to master. If pool is exhausted, it waits for
+// a connection until ctx is done, which also aborts resolving and dialing
+// master. Returned connection must be closed after use if error is nil.
+func (p *SentinelPool) GetContext(ctx context.Context) (redis.Conn, error) {
+	if err := p.waitFailover(ctx); err != nil {
+		return errorConn{err}, err
+	}
+	p.mu.RLock()
+	pool := p.pool
+	p.mu.RUnlock()
+	return p.getContext(ctx, pool)
+}
+
+// GetTimeout gets connection to master, spending no longer than d on
+// waiting for exhausted pool and dialing master altogether. It is meant for
+// callers without context at hand, see GetContext. Returned connection must
+// be closed after use if error is nil.
+func (p *SentinelPool) GetTimeout(d time.Duration) (redis.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return p.GetContext(ctx)
 }
 
+func (p *SentinelPool) getContext(ctx context.Context, pool *redis.Pool) (redis.Conn, error) {
+	waitCtx := ctx
+	if p.opts.waitTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, p.opts.waitTimeout)
+		defer cancel()
+	}
+	atomic.AddInt64(&p.waiting, 1)
+	c, err := getPooled(waitCtx, pool)
+	waiting := atomic.AddInt64(&p.waiting, -1)
+	if err != nil {
+		p.mu.RLock()
+		cur, closed := p.pool, p.closed
+		p.mu.RUnlock()
+		if cur != pool && !closed {
+			// pool was replaced, e.g. drained on master switch, meanwhile
+			return p.getContext(ctx, cur)
+		}
+	}
+	waited := p.opts.waitTimeout
+	switch {
+	case err == context.DeadlineExceeded && ctx.Err() == nil:
+	case err == redis.ErrPoolExhausted:
+		waited = 0
+	default:
+		return c, err
+	}
+	err = PoolExhausted{
+		Waited:          waited,
+		ActiveCount:     pool.ActiveCount(),
+		IdleCount:       pool.IdleCount(),
+		WaitQueue:       int(waiting),
+		DialFailureRate: p.dialOutcomes.failureRate(),
+	}
+	return errorConn{err}, err
+}
+
+// MasterAddr returns address pool currently dials. It is empty until first
+// Get when pool was created with WithLazyInit.
 func (p *SentinelPool) MasterAddr() string {
 	p.mu.RLock()
 	addr := p.curAddr
@@ -207,13 +828,59 @@ func (p *SentinelPool) MasterAddr() string {
 
 func (p *SentinelPool) Close() {
 	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
 	p.closed = true
+	close(p.done)
 	p.pool.Close()
-	p.masterWatcher.Close()
+	if p.masterWatcher != nil {
+		p.masterWatcher.Close()
+	}
 	p.sntl.Close()
+	p._closeChangeSubs()
+	p.mu.Unlock()
+	p.replicas.close()
+}
+
+// SetMasterName switches pool to master with another name, e.g. to migrate
+// to a new master group. New master address is resolved first and pool keeps
+// its current target if that fails. On success connections to previous
+// master are closed and switch events are watched for the new name.
+func (p *SentinelPool) SetMasterName(name string) error {
+	addr, err := p.sntl.masterAddrOf(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.sntl.SetMasterName(name)
+	p._adoptMaster(addr, "master-name-changed")
+	old := p.pool
+	p.pool = p._newPool()
+	watcher := p.masterWatcher
 	p.mu.Unlock()
+
+	old.Close()
+	// monitor resubscribes using new name once watcher is closed
+	if watcher != nil {
+		watcher.Close()
+	}
+	p._startMonitor()
+	return nil
 }
 
+// ErrPoolClosed is returned when using SentinelPool after Close.
+var ErrPoolClosed = errors.New("redigo: sentinel pool closed")
+
+// ErrUnknownMaster is returned when Sentinel does not monitor master with
+// requested name.
+var ErrUnknownMaster = errors.New("redigo: sentinel does not monitor master")
+
 // NoSentinelsAvailable is returned when all sentinels in the list are exhausted
 // (or none configured), and contains the last error returned by Dial (which
 // may be nil)
@@ -246,6 +913,8 @@ func (s *Sentinel) putToTop(addr string) {
 		// Already on top.
 		return
 	}
+	logFields(s.Logger, s.TopologyLogLevel, "sentinel reordered",
+		"sentinel", addr, "position", "top", "reason", "replied")
 	newAddrs := []string{addr}
 	for _, a := range addrs {
 		if a == addr {
@@ -267,6 +936,9 @@ func (s *Sentinel) putToBottom(addr string) {
 		// Already on bottom.
 		return
 	}
+	logFields(s.Logger, s.TopologyLogLevel, "sentinel reordered",
+		"sentinel", addr, "position", "bottom", "reason", "failed")
+	atomic.AddUint64(&s.addrMetrics(addr).demotions, 1)
 	newAddrs := []string{}
 	for _, a := range addrs {
 		if a == addr {
@@ -281,13 +953,22 @@ func (s *Sentinel) putToBottom(addr string) {
 // defaultPool returns a connection pool to one Sentinel. This allows
 // us to call concurrent requests to Sentinel using connection Do method.
 func (s *Sentinel) defaultPool(addr string) *redis.Pool {
+	maxIdle, idleTimeout := s.IdleReaper.apply(3)
 	return &redis.Pool{
-		MaxIdle:     3,
+		MaxIdle:     maxIdle,
 		MaxActive:   10,
 		Wait:        true,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			return s.Dial(addr)
+		IdleTimeout: idleTimeout,
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			c, err := s.dial(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.ClientFlags.apply(withContext(ctx, c)); err != nil {
+				c.Close()
+				return nil, err
+			}
+			return c, nil
 		},
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
 			_, err := c.Do("PING")
@@ -298,6 +979,7 @@ func (s *Sentinel) defaultPool(addr string) *redis.Pool {
 
 func (s *Sentinel) get(addr string) redis.Conn {
 	pool := s.poolForAddr(addr)
+	s.metricsFor(addr).countGet()
 	return pool.Get()
 }
 
@@ -325,10 +1007,31 @@ func (s *Sentinel) poolForAddr(addr string) *redis.Pool {
 }
 
 func (s *Sentinel) newPool(addr string) *redis.Pool {
+	var pool *redis.Pool
 	if s.Pool != nil {
-		return s.Pool(addr)
+		pool = s.Pool(addr)
+	} else {
+		pool = s.defaultPool(addr)
 	}
-	return s.defaultPool(addr)
+	metrics := s.metricsFor(addr)
+	dial, dialContext := pool.Dial, pool.DialContext
+	pool.Dial = nil
+	pool.DialContext = func(ctx context.Context) (redis.Conn, error) {
+		var c redis.Conn
+		var err error
+		if dialContext != nil {
+			c, err = dialContext(ctx)
+		} else {
+			c, err = dial()
+		}
+		metrics.countDial(err)
+		// sentinel is not blamed for dial abandoned by caller
+		if err != nil && ctx.Err() == nil {
+			s.dialFailed(addr, err)
+		}
+		return c, err
+	}
+	return pool
 }
 
 // close connection pool to Sentinel.
@@ -342,48 +1045,91 @@ func (s *Sentinel) close() {
 	s.pools = nil
 }
 
-func (s *Sentinel) doUntilSuccess(f func(redis.Conn) (interface{}, error)) (interface{}, error) {
+func (s *Sentinel) doUntilSuccess(ctx context.Context, f func(redis.Conn) (interface{}, error)) (interface{}, error) {
+	reply, _, err := s.doUntilSuccessFrom(ctx, f)
+	return reply, err
+}
+
+// doUntilSuccessFrom is like doUntilSuccess, but also returns address of
+// Sentinel that replied. It gives up with ctx error once ctx is done.
+func (s *Sentinel) doUntilSuccessFrom(ctx context.Context, f func(redis.Conn) (interface{}, error)) (interface{}, string, error) {
 	s.mu.RLock()
-	addrs := s.Addrs
+	addrs := s.tieredAddrs()
 	s.mu.RUnlock()
 
 	var lastErr error
 
-	for _, addr := range addrs {
-		conn := s.get(addr)
+	parent := spanFromContext(ctx)
+	for i, addr := range addrs {
+		parent.SetAttributes("sentinel", addr, "retries", i)
+		qctx, span := s.startSpan(ctx, TraceQuery)
+		span.SetAttributes("sentinel", addr, "attempt", i+1)
+		conn, err := s.getContext(qctx, addr)
+		if err != nil {
+			span.End(err)
+			return nil, "", err
+		}
+		start := time.Now()
 		reply, err := f(conn)
+		latency := time.Since(start)
 		conn.Close()
+		span.End(err)
+		s.metricsFor(addr).countRequest(latency, err)
 		if err != nil {
+			if err == context.DeadlineExceeded || ctx.Err() != nil {
+				// Sentinel is not to blame for caller giving up
+				return nil, "", err
+			}
 			lastErr = err
+			logFields(s.Logger, LogWarn, "sentinel query failed",
+				"master", s.masterName(), "sentinel", addr, "err", err)
 			s.mu.Lock()
 			pool, ok := s.pools[addr]
 			if ok {
 				pool.Close()
 				delete(s.pools, addr)
 			}
+			s.failed(addr)
 			s.putToBottom(addr)
 			s.mu.Unlock()
+			s.sentinelDown(addr, err)
 			continue
 		}
+		s.mu.Lock()
 		s.putToTop(addr)
-		return reply, nil
+		s.seen(addr, latency)
+		s.mu.Unlock()
+		return reply, addr, nil
 	}
 
-	return nil, NoSentinelsAvailable{lastError: lastErr}
+	return nil, "", NoSentinelsAvailable{lastError: lastErr}
 }
 
-func (s *Sentinel) subscriptMasterSwitch() (redis.PubSubConn, error) {
+// subscribeChannels subscribes to event channels, or to every channel if
+// pattern is set, on the first Sentinel which accepts subscription.
+func (s *Sentinel) subscribeChannels(ctx context.Context, channels []string, pattern bool) (redis.PubSubConn, string, error) {
 	s.mu.RLock()
-	addrs := s.Addrs
+	addrs := s.tieredAddrs()
 	s.mu.RUnlock()
 	var lastErr error
 
 	for _, addr := range addrs {
-		conn := s.get(addr)
+		conn, err := s.subscribeConn(ctx, addr)
 		sub := redis.PubSubConn{Conn: conn}
-		err := sub.Subscribe(switchMasterChannel)
+		if err == nil {
+			if pattern {
+				err = sub.PSubscribe("*")
+			} else {
+				err = sub.Subscribe(redis.Args{}.AddFlat(channels)...)
+			}
+			if err != nil {
+				conn.Close()
+			}
+		}
 		if err != nil {
 			lastErr = err
+			logFields(s.Logger, LogWarn, "sentinel subscribe failed",
+				"master", s.masterName(), "sentinel", addr, "err", err)
 			s.mu.Lock()
 			pool, ok := s.pools[addr]
 			if ok {
@@ -392,21 +1138,81 @@ func (s *Sentinel) subscriptMasterSwitch() (redis.PubSubConn, error) {
 			}
 			s.putToBottom(addr)
 			s.mu.Unlock()
+			s.sentinelDown(addr, err)
 			continue
 		}
+		s.mu.Lock()
 		s.putToTop(addr)
-		return sub, nil
+		s.mu.Unlock()
+		return sub, addr, nil
 	}
 
-	return redis.PubSubConn{nil}, NoSentinelsAvailable{lastError: lastErr}
+	return redis.PubSubConn{}, "", NoSentinelsAvailable{lastError: lastErr}
+}
+
+// subscribeConn returns a dedicated connection to Sentinel on addr used for
+// pub/sub. It is dialed with SubscribeDial or SubscribeDialContext, falling
+// back to DialContext or Dial, and only uses pooled connection if none is set.
+// Connections pooled for Dialer do not support pub/sub, so redigo is used
+// with it instead.
+func (s *Sentinel) subscribeConn(ctx context.Context, addr string) (redis.Conn, error) {
+	var c redis.Conn
+	var err error
+	switch {
+	case s.SubscribeDial != nil || s.SubscribeDialContext != nil ||
+		s.Dialer != nil && s.DialContext == nil && s.Dial == nil:
+		switch {
+		case s.SubscribeDial != nil:
+			c, err = s.SubscribeDial(addr)
+		case s.SubscribeDialContext != nil:
+			c, err = s.SubscribeDialContext(ctx, addr)
+		default:
+			c, err = s.dialSubscribe(ctx, addr)
+		}
+		if err == nil {
+			if err = s.credentials(addr, roleSentinel).auth(withContext(ctx, c)); err != nil {
+				c.Close()
+			}
+		}
+	case s.DialContext != nil || s.Dial != nil:
+		c, err = s.dial(ctx, addr)
+	default:
+		return s.get(addr), nil
+	}
+	if err != nil {
+		s.dialFailed(addr, err)
+		return nil, err
+	}
+	if err := s.ClientFlags.apply(withContext(ctx, c)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
 }
 
 type MasterSentinel struct {
+	sntl       *Sentinel
 	masterName string
+	source     string
+	pattern    bool
 	pubsub     redis.PubSubConn
 	mu         *sync.Mutex
 	closed     bool
 	watchExit  chan struct{}
+	keepalive  time.Duration
+	onPanic    func(goroutine string, v interface{})
+	release    func()
+
+	// subMu protects subscribers and receive loop state
+	subMu     sync.Mutex
+	started   bool
+	finished  bool
+	watchers  []*watchSub
+	eventSubs []*eventSub
+	errSubs   []chan error
+	err       error
+	// dropped is a total number of items discarded by all subscribers
+	dropped uint64
 }
 
 func (ms *MasterSentinel) Close() error {
@@ -414,88 +1220,269 @@ func (ms *MasterSentinel) Close() error {
 	ms.mu.Lock()
 	// prevent repeatedly call
 	if ms.closed {
+		ms.mu.Unlock()
 		return nil
 	}
-	ms.pubsub.Unsubscribe(switchMasterChannel)
+	if ms.pattern {
+		ms.pubsub.PUnsubscribe()
+	} else {
+		ms.pubsub.Unsubscribe()
+	}
 	ms.closed = true
-	// wait watch rontine exit
-	<-ms.watchExit
+	if ms.release != nil {
+		ms.release()
+	}
+	ms.subMu.Lock()
+	started := ms.started
+	ms.subMu.Unlock()
+	if started {
+		// wait watch rontine exit
+		<-ms.watchExit
+	} else {
+		ms.finish(nil)
+	}
 	ms.mu.Unlock()
 	return ms.pubsub.Close()
 }
 
+// Watch returns channel receiving address of new master every time it is
+// switched. If master address is already known, it is delivered first.
+// Only the latest address is kept for slow receiver, older undelivered one
+// is dropped. Channel is closed when subscription is closed or fails.
 func (ms *MasterSentinel) Watch() (<-chan string, error) {
-	ch := make(chan string)
-	go func() {
-		defer func() {
-			close(ms.watchExit)
-		}()
-		for {
-			switch reply := ms.pubsub.Receive().(type) {
-			case redis.Message:
-				p := bytes.Split(reply.Data, []byte(" "))
-				if len(p) != 5 || string(p[0]) != ms.masterName {
-					continue
-				}
-				addr := fmt.Sprintf("%s:%s", string(p[3]), string(p[4]))
-				ch <- addr
-			case error:
-				log.Errorf("channel receive error:%v", reply)
-				close(ch)
-				return
-			case redis.Subscription:
-				if reply.Channel == switchMasterChannel &&
-					reply.Kind == "unsubscribe" && reply.Count == 0 {
-					log.Debugf("unsubscribe switch-master")
-					close(ch)
-					return
-				}
-			}
+	ch := make(chan string, 1)
+	if addr, _ := ms.currentMaster(); addr != "" {
+		ch <- addr
+	}
+	ms.subMu.Lock()
+	if ms.finished {
+		close(ch)
+	} else {
+		ms.watchers = append(ms.watchers, &watchSub{masterName: ms.masterName, ch: ch})
+	}
+	ms.subMu.Unlock()
+	ms.start()
+	return ch, nil
+}
+
+// WatchSwitches is like Watch, but delivers MasterSwitchEvent carrying
+// both former and new master address parsed from +switch-master payload.
+// If master address is already known, replay event with it is delivered
+// first.
+func (ms *MasterSentinel) WatchSwitches() (<-chan MasterSwitchEvent, error) {
+	ch := make(chan MasterSwitchEvent, 1)
+	if addr, generation := ms.currentMaster(); addr != "" {
+		ch <- MasterSwitchEvent{
+			MasterName: ms.masterName,
+			OldAddr:    addr,
+			NewAddr:    addr,
+			Source:     ms.source,
+			Time:       time.Now(),
+			Generation: generation,
+			Replay:     true,
 		}
-	}()
+	}
+	ms.subMu.Lock()
+	if ms.finished {
+		close(ch)
+	} else {
+		ms.watchers = append(ms.watchers, &watchSub{masterName: ms.masterName, switches: ch})
+	}
+	ms.subMu.Unlock()
+	ms.start()
 	return ch, nil
 }
 
+// WatchMaster is like WatchSwitches, but delivers switches of master with
+// masterName, so a single subscription can be shared by watchers of every
+// master sentinels monitor instead of subscribing once per master. Replay
+// event is delivered first only for master subscription was made for, as
+// address of other masters is not tracked.
+func (ms *MasterSentinel) WatchMaster(masterName string) (<-chan MasterSwitchEvent, error) {
+	if masterName == ms.masterName {
+		return ms.WatchSwitches()
+	}
+	ch := make(chan MasterSwitchEvent, 1)
+	ms.subMu.Lock()
+	if ms.finished {
+		close(ch)
+	} else {
+		ms.watchers = append(ms.watchers, &watchSub{masterName: masterName, switches: ch})
+	}
+	ms.subMu.Unlock()
+	ms.start()
+	return ch, nil
+}
+
+// WatchWithErrors is like Watch, but also returns channel receiving an error
+// subscription failed with. When subscription is closed with Close, error
+// channel is closed without receiving a value.
+func (ms *MasterSentinel) WatchWithErrors() (<-chan string, <-chan error, error) {
+	errc := make(chan error, 1)
+	ms.subMu.Lock()
+	if ms.finished {
+		if ms.err != nil {
+			errc <- ms.err
+		}
+		close(errc)
+	} else {
+		ms.errSubs = append(ms.errSubs, errc)
+	}
+	ms.subMu.Unlock()
+	w, err := ms.Watch()
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, errc, nil
+}
+
 func (s *Sentinel) MasterSwitch() (*MasterSentinel, error) {
-	sub, err := s.subscriptMasterSwitch()
+	return s.subscribe(s.eventChannels(), s.Firehose)
+}
+
+// subscribe makes subscription to event channels, or to every channel if
+// pattern is set, which is closed together with Sentinel context.
+func (s *Sentinel) subscribe(channels []string, pattern bool) (*MasterSentinel, error) {
+	ctx, cancel := s.closeContext()
+	sub, source, err := s.subscribeChannels(ctx, channels, pattern)
+	cancel()
 	if err != nil {
 		return nil, err
 	}
-	return &MasterSentinel{
+	ms := &MasterSentinel{
+		sntl:       s,
+		pattern:    pattern,
+		source:     source,
 		pubsub:     sub,
-		masterName: s.MasterName,
+		masterName: s.masterName(),
+		keepalive:  s.SubscriptionKeepalive,
+		onPanic:    s.onPanic,
 		closed:     false,
 		mu:         &sync.Mutex{},
 		watchExit:  make(chan struct{}),
-	}, nil
+	}
+	s.mu.Lock()
+	if s.watchers == nil {
+		s.watchers = make(map[*MasterSentinel]struct{})
+	}
+	s.watchers[ms] = struct{}{}
+	s.mu.Unlock()
+	ms.release = func() {
+		s.mu.Lock()
+		delete(s.watchers, ms)
+		s.mu.Unlock()
+	}
+	return ms, nil
+}
+
+// closeWatchers closes all subscriptions made with MasterSwitch.
+func (s *Sentinel) closeWatchers() {
+	s.mu.RLock()
+	watchers := make([]*MasterSentinel, 0, len(s.watchers))
+	for ms := range s.watchers {
+		watchers = append(watchers, ms)
+	}
+	s.mu.RUnlock()
+	for _, ms := range watchers {
+		ms.Close()
+	}
 }
 
 // MasterAddr returns an address of current Redis master instance.
+// If sentinels do not monitor MasterName and Bootstrap is set, they are
+// asked to start monitoring it first.
 func (s *Sentinel) MasterAddr() (string, error) {
-	res, err := s.doUntilSuccess(func(c redis.Conn) (interface{}, error) {
-		return queryForMaster(c, s.MasterName)
+	return s.MasterAddrContext(context.Background())
+}
+
+// MasterAddrContext is like MasterAddr, but gives up once ctx is done.
+func (s *Sentinel) MasterAddrContext(ctx context.Context) (addr string, err error) {
+	ctx, span := s.startSpan(ctx, TraceMasterAddr)
+	span.SetAttributes("master", s.masterName())
+	defer func() {
+		span.SetAttributes("addr", addr)
+		span.End(err)
+	}()
+	addr, err = s.masterAddr(ctx)
+	if err != nil && s.Bootstrap != nil && isUnknownMaster(err) {
+		if err := s.Monitor(ctx, *s.Bootstrap); err != nil {
+			return "", err
+		}
+		return s.masterAddr(ctx)
+	}
+	return addr, err
+}
+
+func (s *Sentinel) masterAddr(ctx context.Context) (string, error) {
+	return s.masterAddrOf(ctx, s.masterName())
+}
+
+func (s *Sentinel) masterAddrOf(ctx context.Context, masterName string) (string, error) {
+	res, source, err := s.doUntilSuccessFrom(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForMaster(c, masterName)
 	})
 	if err != nil {
 		return "", err
 	}
+	s.mu.Lock()
+	if masterName == s.MasterName {
+		s._setMaster(res.(string), source)
+	}
+	s.mu.Unlock()
 	return res.(string), nil
 }
 
+// MasterAddrs returns addresses of all masters Sentinels monitor keyed by
+// master name.
+func (s *Sentinel) MasterAddrs() (map[string]string, error) {
+	return s.MasterAddrsContext(context.Background())
+}
+
+// MasterAddrsContext is like MasterAddrs, but gives up once ctx is done.
+func (s *Sentinel) MasterAddrsContext(ctx context.Context) (map[string]string, error) {
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForMasters(c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(map[string]string), nil
+}
+
 // SlaveAddrs returns a slice with known slaves of current master instance.
+// Replicas Sentinel considers down are skipped unless AllSlaves is set.
 func (s *Sentinel) SlaveAddrs() ([]string, error) {
-	res, err := s.doUntilSuccess(func(c redis.Conn) (interface{}, error) {
-		return queryForSlaves(c, s.MasterName)
+	return s.SlaveAddrsContext(context.Background())
+}
+
+// SlaveAddrsContext is like SlaveAddrs, but gives up once ctx is done.
+func (s *Sentinel) SlaveAddrsContext(ctx context.Context) ([]string, error) {
+	masterName := s.masterName()
+	ctx, span := s.startSpan(ctx, TraceSlaveAddrs)
+	span.SetAttributes("master", masterName)
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForSlaves(c, masterName, s.AllSlaves, s.Logger)
 	})
+	span.End(err)
 	if err != nil {
 		return nil, err
 	}
-	return res.([]string), nil
+	slaves := res.([]string)
+	s.mu.Lock()
+	s.updateSlaves(masterName, slaves)
+	s.mu.Unlock()
+	return slaves, nil
 }
 
 // SentinelAddrs returns a slice of known Sentinel addresses Sentinel server aware of.
 func (s *Sentinel) SentinelAddrs() ([]string, error) {
-	res, err := s.doUntilSuccess(func(c redis.Conn) (interface{}, error) {
-		return queryForSentinels(c, s.MasterName)
+	return s.SentinelAddrsContext(context.Background())
+}
+
+// SentinelAddrsContext is like SentinelAddrs, but gives up once ctx is done.
+func (s *Sentinel) SentinelAddrsContext(ctx context.Context) ([]string, error) {
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForSentinels(c, s.masterName(), s.Logger)
 	})
 	if err != nil {
 		return nil, err
@@ -508,29 +1495,197 @@ func (s *Sentinel) SentinelAddrs() ([]string, error) {
 // A client may update its internal list of Sentinel nodes following this procedure:
 // 1) Obtain a list of other Sentinels for this master using the command SENTINEL sentinels <master-name>.
 // 2) Add every ip:port pair not already existing in our list at the end of the list.
+//
+// Sentinels are also deduplicated by run ID, so the same Sentinel known under
+// several addresses (hostname and IP, NAT) is kept only once.
 func (s *Sentinel) Discover() error {
-	addrs, err := s.SentinelAddrs()
+	return s.DiscoverContext(context.Background())
+}
+
+// DiscoverContext is like Discover, but gives up once ctx is done.
+func (s *Sentinel) DiscoverContext(ctx context.Context) (err error) {
+	ctx, span := s.startSpan(ctx, TraceDiscover)
+	span.SetAttributes("master", s.masterName())
+	defer func() { span.End(err) }()
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForSentinelInfos(c, s.masterName(), s.Logger)
+	})
 	if err != nil {
 		return err
 	}
+	s.learnRunIDs(ctx)
 	s.mu.Lock()
-	for _, addr := range addrs {
-		if !stringInSlice(addr, s.Addrs) {
-			s.Addrs = append(s.Addrs, addr)
+	for _, info := range res.([]sentinelInfo) {
+		s.addDiscovered(info)
+	}
+	s.dedupeByRunID()
+	s.mu.Unlock()
+	return nil
+}
+
+// learnRunIDs asks every known Sentinel with unknown run ID for it. Sentinels
+// which fail to answer are skipped.
+func (s *Sentinel) learnRunIDs(ctx context.Context) {
+	s.mu.RLock()
+	var unknown []string
+	for _, addr := range s.Addrs {
+		if node, ok := s.nodes[addr]; !ok || node.RunID == "" {
+			unknown = append(unknown, addr)
 		}
 	}
+	s.mu.RUnlock()
+
+	for _, addr := range unknown {
+		conn, err := s.getContext(ctx, addr)
+		if err != nil {
+			return
+		}
+		runID, err := queryForRunID(conn)
+		conn.Close()
+		if err != nil || runID == "" {
+			continue
+		}
+		s.mu.Lock()
+		s.setRunID(addr, runID)
+		s.mu.Unlock()
+	}
+}
+
+// addDiscovered adds Sentinel to the end of address list unless it is already
+// known by address or by run ID.
+//
+// Lock must be held by caller.
+func (s *Sentinel) addDiscovered(info sentinelInfo) {
+	s.discovered(info)
+	if stringInSlice(info.addr, s.Addrs) {
+		return
+	}
+	if info.runID != "" {
+		for _, addr := range s.Addrs {
+			if s.runID(addr) == info.runID {
+				return
+			}
+		}
+	}
+	s.Addrs = append(s.Addrs, info.addr)
+}
+
+// dedupeByRunID removes addresses pointing to the same Sentinel as one of
+// the addresses before them.
+//
+// Lock must be held by caller.
+func (s *Sentinel) dedupeByRunID() {
+	seen := make(map[string]bool)
+	newAddrs := make([]string, 0, len(s.Addrs))
+	for _, addr := range s.Addrs {
+		runID := s.runID(addr)
+		if runID != "" && seen[runID] {
+			continue
+		}
+		if runID != "" {
+			seen[runID] = true
+		}
+		newAddrs = append(newAddrs, addr)
+	}
+	s.Addrs = newAddrs
+}
+
+// SetMasterName switches Sentinel to another master name. Subscriptions
+// created before keep watching previous master.
+func (s *Sentinel) SetMasterName(name string) {
+	s.mu.Lock()
+	s.MasterName = name
+	s.mu.Unlock()
+}
+
+func (s *Sentinel) masterName() string {
+	s.mu.RLock()
+	name := s.MasterName
+	s.mu.RUnlock()
+	return name
+}
+
+// AddSentinel adds Sentinel address to the end of address list unless it is
+// already there. It is safe to call while Sentinel is in use. InvalidAddr
+// error is returned if addr can not be parsed.
+func (s *Sentinel) AddSentinel(addr string) error {
+	addr, err := NormalizeAddr(addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if !stringInSlice(addr, s.Addrs) {
+		newAddrs := make([]string, 0, len(s.Addrs)+1)
+		newAddrs = append(newAddrs, s.Addrs...)
+		s.Addrs = append(newAddrs, addr)
+	}
 	s.mu.Unlock()
 	return nil
 }
 
+// RemoveSentinel removes Sentinel address from address list and closes
+// connection pool to it. It is safe to call while Sentinel is in use.
+func (s *Sentinel) RemoveSentinel(addr string) {
+	if n, err := NormalizeAddr(addr); err == nil {
+		addr = n
+	}
+	s.mu.Lock()
+	newAddrs := make([]string, 0, len(s.Addrs))
+	for _, a := range s.Addrs {
+		if a != addr {
+			newAddrs = append(newAddrs, a)
+		}
+	}
+	s.Addrs = newAddrs
+	if pool, ok := s.pools[addr]; ok {
+		pool.Close()
+		delete(s.pools, addr)
+	}
+	delete(s.nodes, addr)
+	s.mu.Unlock()
+}
+
+// setAddrs replaces list of Sentinel addresses, closing pools to Sentinels
+// which are no longer listed.
+func (s *Sentinel) setAddrs(addrs []string) {
+	addrs = normalizeAddrs(addrs)
+	s.mu.Lock()
+	for addr, pool := range s.pools {
+		if !stringInSlice(addr, addrs) {
+			pool.Close()
+			delete(s.pools, addr)
+		}
+	}
+	s.Addrs = append([]string(nil), addrs...)
+	s.mu.Unlock()
+}
+
 // Close closes current connection to Sentinel.
 func (s *Sentinel) Close() error {
 	s.mu.Lock()
 	s.close()
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
 	s.mu.Unlock()
 	return nil
 }
 
+// closedChan returns channel which is closed once Sentinel is closed.
+func (s *Sentinel) closedChan() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+	return s.done
+}
+
 // TestRole wraps GetRole in a test to verify if the role matches an expected
 // role string. If there was any error in querying the supplied connection,
 // the function returns false. Works with Redis >= 2.8.12.
@@ -561,45 +1716,128 @@ func getRole(c redis.Conn) (string, error) {
 
 func queryForMaster(conn redis.Conn, masterName string) (string, error) {
 	res, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", masterName))
+	if err == redis.ErrNil {
+		return "", ErrUnknownMaster
+	}
 	if err != nil {
 		return "", err
 	}
-	masterAddr := strings.Join(res, ":")
-	return masterAddr, nil
+	if len(res) != 2 {
+		return "", fmt.Errorf("redigo: unexpected master address reply %q", res)
+	}
+	return joinAddr(res[0], res[1])
 }
 
-func queryForSlaves(conn redis.Conn, masterName string) ([]string, error) {
-	res, err := redis.Values(conn.Do("SENTINEL", "slaves", masterName))
+func queryForMasters(conn redis.Conn) (map[string]string, error) {
+	res, err := redis.Values(conn.Do("SENTINEL", "masters"))
 	if err != nil {
 		return nil, err
 	}
-	slaves := make([]string, 0)
+	masters := make(map[string]string)
 	for _, a := range res {
 		sm, err := redis.StringMap(a, err)
 		if err != nil {
-			return slaves, err
+			return masters, err
 		}
-		slaves = append(slaves, fmt.Sprintf("%s:%s", sm["ip"], sm["port"]))
+		addr, err := joinAddr(sm["ip"], sm["port"])
+		if err != nil {
+			return masters, err
+		}
+		masters[sm["name"]] = addr
 	}
-	return slaves, nil
+	return masters, nil
+}
+
+// queryForSlaves returns addresses of replicas, only of healthy ones unless
+// all is set.
+func queryForSlaves(conn redis.Conn, masterName string, all bool, logger Logger) ([]string, error) {
+	infos, err := queryForSlaveInfos(conn, masterName, logger)
+	return slaveAddrs(infos, all), err
+}
+
+func queryForSentinels(conn redis.Conn, masterName string, logger Logger) ([]string, error) {
+	infos, err := queryForSentinelInfos(conn, masterName, logger)
+	sentinels := make([]string, 0, len(infos))
+	for _, info := range infos {
+		sentinels = append(sentinels, info.addr)
+	}
+	return sentinels, err
 }
 
-func queryForSentinels(conn redis.Conn, masterName string) ([]string, error) {
+// sentinelInfo is an entry of SENTINEL sentinels reply.
+type sentinelInfo struct {
+	addr  string
+	runID string
+	flags string
+}
+
+// queryForSentinelInfos returns peers of sentinel conn is connected to.
+// Malformed entries are logged and skipped, so one of them does not hide
+// the others.
+func queryForSentinelInfos(conn redis.Conn, masterName string, logger Logger) ([]sentinelInfo, error) {
 	res, err := redis.Values(conn.Do("SENTINEL", "sentinels", masterName))
 	if err != nil {
 		return nil, err
 	}
-	sentinels := make([]string, 0)
+	sentinels := make([]sentinelInfo, 0)
 	for _, a := range res {
-		sm, err := redis.StringMap(a, err)
+		sm, err := redis.StringMap(a, nil)
 		if err != nil {
-			return sentinels, err
+			logFields(logger, LogWarn, "malformed sentinel entry skipped",
+				"master", masterName, "err", err)
+			continue
 		}
-		sentinels = append(sentinels, fmt.Sprintf("%s:%s", sm["ip"], sm["port"]))
+		addr, err := joinAddr(sm["ip"], sm["port"])
+		if err != nil {
+			logFields(logger, LogWarn, "malformed sentinel entry skipped",
+				"master", masterName, "err", err)
+			continue
+		}
+		sentinels = append(sentinels, sentinelInfo{
+			addr:  addr,
+			runID: sm["runid"],
+			flags: sm["flags"],
+		})
 	}
 	return sentinels, nil
 }
 
+// queryForRunID returns run ID of instance from INFO server section.
+func queryForRunID(conn redis.Conn) (string, error) {
+	info, err := redis.String(conn.Do("INFO", "server"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info, "\r\n") {
+		if strings.HasPrefix(line, "run_id:") {
+			return strings.TrimPrefix(line, "run_id:"), nil
+		}
+	}
+	return "", nil
+}
+
+// updateSlaves remembers known slaves, logging which were added or removed.
+//
+// Lock must be held by caller.
+func (s *Sentinel) updateSlaves(masterName string, slaves []string) {
+	var added, removed []string
+	for _, addr := range slaves {
+		if !stringInSlice(addr, s.slaves) {
+			added = append(added, addr)
+		}
+	}
+	for _, addr := range s.slaves {
+		if !stringInSlice(addr, slaves) {
+			removed = append(removed, addr)
+		}
+	}
+	s.slaves = slaves
+	if len(added) > 0 || len(removed) > 0 {
+		logFields(s.Logger, s.TopologyLogLevel, "replicas changed",
+			"master", masterName, "added", added, "removed", removed)
+	}
+}
+
 func stringInSlice(str string, slice []string) bool {
 	for _, s := range slice {
 		if s == str {
@@ -1,12 +1,13 @@
 package sentinel
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
-	"github.com/garyburd/redigo/redis"
+	"github.com/gomodule/redigo/redis"
 )
 
 func TestSentinel(t *testing.T) {
@@ -66,3 +67,75 @@ func TestSentinelPool(t *testing.T) {
 	}
 	sp.Close()
 }
+
+func TestDedupeByRunID(t *testing.T) {
+	st := &Sentinel{Addrs: []string{"sentinel-a:26379", "10.0.0.2:26379"}}
+	st.setRunID("sentinel-a:26379", "aaa")
+
+	st.addDiscovered(sentinelInfo{addr: "10.0.0.1:26379", runID: "aaa"})
+	st.addDiscovered(sentinelInfo{addr: "10.0.0.3:26379", runID: "ccc"})
+	st.setRunID("10.0.0.2:26379", "ccc")
+	st.dedupeByRunID()
+
+	expected := []string{"sentinel-a:26379", "10.0.0.2:26379"}
+	if fmt.Sprint(st.Addrs) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, st.Addrs)
+	}
+}
+
+func TestTieredAddrs(t *testing.T) {
+	st := &Sentinel{
+		Addrs: []string{"remote1:26379", "local1:26379", "other:26379", "local2:26379"},
+		Tiers: [][]string{
+			{"local1:26379", "local2:26379"},
+			{"remote1:26379"},
+		},
+	}
+	expected := []string{"local1:26379", "local2:26379", "remote1:26379", "other:26379"}
+	if addrs := st.tieredAddrs(); fmt.Sprint(addrs) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, addrs)
+	}
+}
+
+func TestAddRemoveSentinel(t *testing.T) {
+	st := &Sentinel{Addrs: []string{"s1:26379", "s2:26379"}}
+	st.AddSentinel("s3:26379")
+	st.AddSentinel("s1:26379")
+	st.RemoveSentinel("s2:26379")
+
+	expected := []string{"s1:26379", "s3:26379"}
+	if fmt.Sprint(st.Addrs) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, st.Addrs)
+	}
+}
+
+func TestGetTimeout(t *testing.T) {
+	release := make(chan struct{})
+	sp := &SentinelPool{
+		mu: &sync.RWMutex{},
+		pool: &redis.Pool{
+			DialContext: func(ctx context.Context) (redis.Conn, error) {
+				select {
+				case <-release:
+					return nopConn{}, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			},
+		},
+	}
+	start := time.Now()
+	c, err := sp.GetTimeout(50 * time.Millisecond)
+	if err != context.DeadlineExceeded || c.Err() != err {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("GetTimeout took %v", elapsed)
+	}
+	close(release)
+	c, err = sp.GetTimeout(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
@@ -0,0 +1,255 @@
+// Package sentinelgoredis plugs master and replica discovery and
+// switch-master watching of Sentinel into go-redis clients, for
+// applications migrating to go-redis which keep failover handling of this
+// package.
+//
+//	sntl := sentinel.NewSentinel(addrs, "mymaster")
+//	d := sentinelgoredis.NewMasterDialer(sntl)
+//	defer d.Close()
+//	client := redis.NewClient(&redis.Options{Addr: "mymaster", Dialer: d.Dial})
+package sentinelgoredis
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/redis/go-redis/v9"
+)
+
+// resubscribeDelay is how long Dialer waits before subscribing to master
+// switch events again after subscription failed.
+const resubscribeDelay = time.Second
+
+// maxDialAttempts is how many times Dial connects to master when master
+// switches while connecting.
+const maxDialAttempts = 3
+
+// errMasterChanged is returned by Dial when master kept switching while
+// connecting to it.
+var errMasterChanged = errors.New("sentinelgoredis: master changed while dialing")
+
+// Dialer dials current master or one of replicas of master Sentinel
+// monitors, whatever address go-redis asks for. Its Dial is meant to be
+// used as Dialer of redis.Options. Connections to master are closed once
+// Sentinel reports master switch, so go-redis discards them and dials new
+// master.
+//
+// go-redis does not apply TLSConfig of redis.Options to connections made by
+// Dialer, so they use TLSConfig of Dialer, or of Sentinel if it is nil.
+type Dialer struct {
+	// TLSConfig enables TLS for connections to master and replicas. It
+	// must be set before Dial is used.
+	TLSConfig *tls.Config
+
+	sntl    *sentinel.Sentinel
+	replica bool
+	net     net.Dialer
+	next    uint32
+	done    chan struct{}
+	once    sync.Once
+
+	mu     sync.Mutex
+	master string
+	conns  map[*trackedConn]struct{}
+	closed bool
+	ms     *sentinel.MasterSentinel
+}
+
+// NewMasterDialer returns Dialer connecting to current master. It watches
+// master switches in background until Close is called.
+func NewMasterDialer(sntl *sentinel.Sentinel) *Dialer {
+	d := &Dialer{
+		sntl:  sntl,
+		done:  make(chan struct{}),
+		conns: make(map[*trackedConn]struct{}),
+	}
+	go d.watch()
+	return d
+}
+
+// NewReplicaDialer returns Dialer connecting to replicas of current master,
+// picked round-robin. Replicas are looked up on every dial.
+func NewReplicaDialer(sntl *sentinel.Sentinel) *Dialer {
+	return &Dialer{
+		sntl:    sntl,
+		replica: true,
+		done:    make(chan struct{}),
+		conns:   make(map[*trackedConn]struct{}),
+	}
+}
+
+// Dial connects to master or replica, ignoring addr go-redis asks for.
+// Connection to master which switched while connecting is closed and new
+// master is dialed instead.
+func (d *Dialer) Dial(ctx context.Context, network, _ string) (net.Conn, error) {
+	for attempt := 0; attempt < maxDialAttempts; attempt++ {
+		addr, err := d.addr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c, err := d.dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if d.replica {
+			return c, nil
+		}
+		tc := &trackedConn{Conn: c, d: d, addr: addr}
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			c.Close()
+			return nil, redis.ErrClosed
+		}
+		// switch seen while connecting did not close this connection
+		if d.master != "" && d.master != addr {
+			d.mu.Unlock()
+			c.Close()
+			continue
+		}
+		d.conns[tc] = struct{}{}
+		d.mu.Unlock()
+		return tc, nil
+	}
+	return nil, errMasterChanged
+}
+
+// dial connects to addr, with TLS if it is configured.
+func (d *Dialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, err := d.net.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	config := d.TLSConfig
+	if config == nil {
+		config = d.sntl.TLSConfig
+	}
+	if config == nil {
+		return c, nil
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tc := tls.Client(c, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// addr returns address to dial.
+func (d *Dialer) addr(ctx context.Context) (string, error) {
+	if d.replica {
+		addrs, err := d.sntl.SlaveAddrsContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(addrs) == 0 {
+			return "", sentinel.ErrNoReplicas
+		}
+		return addrs[atomic.AddUint32(&d.next, 1)%uint32(len(addrs))], nil
+	}
+	d.mu.Lock()
+	addr := d.master
+	d.mu.Unlock()
+	if addr != "" {
+		return addr, nil
+	}
+	addr, err := d.sntl.MasterAddrContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	if d.master == "" {
+		d.master = addr
+	}
+	d.mu.Unlock()
+	return addr, nil
+}
+
+// watch follows master switches until Dialer is closed.
+func (d *Dialer) watch() {
+	for {
+		ms, err := d.sntl.MasterSwitch()
+		if err == nil {
+			d.mu.Lock()
+			if d.closed {
+				d.mu.Unlock()
+				ms.Close()
+				return
+			}
+			d.ms = ms
+			d.mu.Unlock()
+			w, _ := ms.Watch()
+			for addr := range w {
+				d.switchMaster(addr)
+			}
+			ms.Close()
+			// switches may be missed until subscribed again
+			d.mu.Lock()
+			d.ms = nil
+			d.master = ""
+			d.mu.Unlock()
+		}
+		select {
+		case <-d.done:
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+// switchMaster makes Dialer dial addr and closes connections to previous
+// master.
+func (d *Dialer) switchMaster(addr string) {
+	d.mu.Lock()
+	d.master = addr
+	var stale []*trackedConn
+	for c := range d.conns {
+		if c.addr != addr {
+			stale = append(stale, c)
+		}
+	}
+	d.mu.Unlock()
+	for _, c := range stale {
+		c.Close()
+	}
+}
+
+// Close stops watching master switches. Established connections are left
+// to go-redis client.
+func (d *Dialer) Close() error {
+	d.once.Do(func() {
+		close(d.done)
+	})
+	d.mu.Lock()
+	d.closed = true
+	ms := d.ms
+	d.mu.Unlock()
+	if ms != nil {
+		return ms.Close()
+	}
+	return nil
+}
+
+// trackedConn is connection to master which is closed on master switch.
+type trackedConn struct {
+	net.Conn
+	d    *Dialer
+	addr string
+}
+
+func (c *trackedConn) Close() error {
+	c.d.mu.Lock()
+	delete(c.d.conns, c)
+	c.d.mu.Unlock()
+	return c.Conn.Close()
+}
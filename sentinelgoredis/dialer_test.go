@@ -0,0 +1,106 @@
+package sentinelgoredis
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMasterDialer(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sntl := sentinel.NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer sntl.Close()
+	d := NewMasterDialer(sntl)
+	defer d.Close()
+	client := redis.NewClient(&redis.Options{Addr: "mymaster", Dialer: d.Dial, Protocol: 2})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Set(ctx, "key", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	cluster.Failover(cluster.Replicas[0])
+	for {
+		err := client.Set(ctx, "key", "value", 0).Err()
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("client did not follow master switch: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if role, err := client.Do(ctx, "ROLE").Slice(); err != nil || role[0] != "master" {
+		t.Fatalf("unexpected role %v, %v", role, err)
+	}
+}
+
+func TestMasterDialerSwitchWhileDialing(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sntl := sentinel.NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer sntl.Close()
+	oldAddr, newAddr := cluster.Master.Addr(), cluster.Replicas[0].Addr()
+	// not watching switches, so only the one below is seen
+	d := &Dialer{
+		sntl:   sntl,
+		done:   make(chan struct{}),
+		conns:  make(map[*trackedConn]struct{}),
+		master: oldAddr,
+	}
+	d.net.Control = func(_, address string, _ syscall.RawConn) error {
+		if address == oldAddr {
+			d.switchMaster(newAddr)
+		}
+		return nil
+	}
+	c, err := d.Dial(context.Background(), "tcp", "mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if addr := c.(*trackedConn).addr; addr != newAddr {
+		t.Fatalf("connected to %s instead of master %s", addr, newAddr)
+	}
+	if len(d.conns) != 1 {
+		t.Fatalf("connection to former master was kept, got %d connections", len(d.conns))
+	}
+}
+
+func TestDialerTLS(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	addr := server.Listener.Addr().String()
+	sntl := sentinel.NewSentinel([]string{addr}, "mymaster")
+	defer sntl.Close()
+	sntl.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	d := &Dialer{
+		sntl:   sntl,
+		done:   make(chan struct{}),
+		conns:  make(map[*trackedConn]struct{}),
+		master: addr,
+	}
+	c, err := d.Dial(context.Background(), "tcp", "mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, ok := c.(*trackedConn).Conn.(*tls.Conn); !ok {
+		t.Fatalf("connection %T does not use TLS", c.(*trackedConn).Conn)
+	}
+}
@@ -0,0 +1,77 @@
+// Package sentinelotel traces Sentinel queries and failover handling with
+// OpenTelemetry.
+package sentinelotel
+
+import (
+	"context"
+	"fmt"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// attributeKeys renames attribute keys Sentinel sets to ones which do not
+// clash with attributes of other instrumentations in the same trace.
+var attributeKeys = map[string]string{
+	"master":   "sentinel.master_name",
+	"addr":     "sentinel.master_addr",
+	"old":      "sentinel.old_addr",
+	"new":      "sentinel.new_addr",
+	"sentinel": "sentinel.addr",
+}
+
+// NewTracer returns Tracer starting spans with tracer, e.g. one returned by
+// otel.Tracer. Use it with WithTracer or as Sentinel Tracer.
+func NewTracer(tracer trace.Tracer) sentinel.Tracer {
+	return otelTracer{tracer}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+func (t otelTracer) Start(ctx context.Context, name string) (context.Context, sentinel.Span) {
+	kind := trace.SpanKindClient
+	if name == sentinel.TraceFailover {
+		kind = trace.SpanKindInternal
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(kv ...interface{}) {
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		if k, ok := attributeKeys[key]; ok {
+			key = k
+		} else {
+			key = "sentinel." + key
+		}
+		switch v := kv[i+1].(type) {
+		case string:
+			attrs = append(attrs, attribute.String(key, v))
+		case int:
+			attrs = append(attrs, attribute.Int(key, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(key, v))
+		default:
+			attrs = append(attrs, attribute.String(key, fmt.Sprint(v)))
+		}
+	}
+	s.span.SetAttributes(attrs...)
+}
+
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,73 @@
+package sentinelotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := NewTracer(tp.Tracer("sentinelotel"))
+
+	_, span := tracer.Start(context.Background(), sentinel.TraceQuery)
+	span.SetAttributes("sentinel", "127.0.0.1:26379", "master", "mymaster")
+	span.End(nil)
+	_, span = tracer.Start(context.Background(), sentinel.TraceFailover)
+	span.SetAttributes("old", "127.0.0.1:6379", "new", "127.0.0.1:6380",
+		"attempts", 2, "confirmed", true, "epoch", int64(3))
+	span.End(errors.New("redigo: failover failed"))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("unexpected %d spans", len(spans))
+	}
+	query, failover := spans[0], spans[1]
+	if query.Name() != sentinel.TraceQuery || query.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("unexpected query span %s of kind %v", query.Name(), query.SpanKind())
+	}
+	if query.Status().Code != codes.Unset {
+		t.Fatalf("unexpected query span status %v", query.Status())
+	}
+	expectAttributes(t, query.Attributes(),
+		attribute.String("sentinel.addr", "127.0.0.1:26379"),
+		attribute.String("sentinel.master_name", "mymaster"))
+
+	if failover.Name() != sentinel.TraceFailover || failover.SpanKind() != trace.SpanKindInternal {
+		t.Fatalf("unexpected failover span %s of kind %v", failover.Name(), failover.SpanKind())
+	}
+	if st := failover.Status(); st.Code != codes.Error || st.Description != "redigo: failover failed" {
+		t.Fatalf("unexpected failover span status %v", st)
+	}
+	if len(failover.Events()) != 1 || failover.Events()[0].Name != "exception" {
+		t.Fatalf("error was not recorded, events %v", failover.Events())
+	}
+	// keys without a mapping get sentinel. prefix, unknown types are
+	// formatted as strings
+	expectAttributes(t, failover.Attributes(),
+		attribute.String("sentinel.old_addr", "127.0.0.1:6379"),
+		attribute.String("sentinel.new_addr", "127.0.0.1:6380"),
+		attribute.Int("sentinel.attempts", 2),
+		attribute.Bool("sentinel.confirmed", true),
+		attribute.String("sentinel.epoch", "3"))
+}
+
+func expectAttributes(t *testing.T, got []attribute.KeyValue, expected ...attribute.KeyValue) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("unexpected attributes %v", got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("unexpected attribute %v, expected %v", got[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package sentinelprom exports SentinelPool metrics to Prometheus.
+package sentinelprom
+
+import (
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "redis_sentinel"
+
+// Collector collects metrics of SentinelPool on every scrape. Register it
+// with prometheus.MustRegister.
+type Collector struct {
+	pool *sentinel.SentinelPool
+
+	failovers     *prometheus.Desc
+	sinceSwitch   *prometheus.Desc
+	dialErrors    *prometheus.Desc
+	active        *prometheus.Desc
+	idle          *prometheus.Desc
+	resubscribes  *prometheus.Desc
+	masterCmds    *prometheus.Desc
+	droppedEvents *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector creates collector of pool metrics labeled with masterName,
+// so pools of several masters can be registered together.
+func NewCollector(pool *sentinel.SentinelPool, masterName string) *Collector {
+	labels := prometheus.Labels{"master_name": masterName}
+	desc := func(name, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name),
+			help, variableLabels, labels)
+	}
+	return &Collector{
+		pool: pool,
+		failovers: desc("master_switches_total",
+			"Number of times pool switched to another master."),
+		sinceSwitch: desc("seconds_since_master_switch",
+			"Seconds since pool last switched master, absent if it never did."),
+		dialErrors: desc("sentinel_dial_errors_total",
+			"Number of failed dials to sentinel.", "sentinel"),
+		active: desc("pool_active_connections",
+			"Number of connections to master, idle or in use."),
+		idle: desc("pool_idle_connections",
+			"Number of idle connections to master."),
+		resubscribes: desc("subscription_reconnects_total",
+			"Number of times subscription to master switch events was established again."),
+		masterCmds: desc("master_commands_total",
+			"Number of commands sent over master connections."),
+		droppedEvents: desc("dropped_events_total",
+			"Number of Sentinel notifications discarded before pool processed them."),
+	}
+}
+
+// Describe sends descriptors of all metrics collector exports.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.failovers
+	ch <- c.sinceSwitch
+	ch <- c.dialErrors
+	ch <- c.active
+	ch <- c.idle
+	ch <- c.resubscribes
+	ch <- c.masterCmds
+	ch <- c.droppedEvents
+}
+
+// Collect sends current values of pool metrics.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.pool.Stats()
+	ch <- prometheus.MustNewConstMetric(c.failovers, prometheus.CounterValue,
+		float64(st.MasterSwitches))
+	if !st.LastMasterSwitch.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.sinceSwitch, prometheus.GaugeValue,
+			time.Since(st.LastMasterSwitch).Seconds())
+	}
+	for addr, sst := range st.SentinelPools {
+		ch <- prometheus.MustNewConstMetric(c.dialErrors, prometheus.CounterValue,
+			float64(sst.DialErrors), addr)
+	}
+	ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue,
+		float64(st.ActiveCount))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue,
+		float64(st.IdleCount))
+	ch <- prometheus.MustNewConstMetric(c.resubscribes, prometheus.CounterValue,
+		float64(st.SubscriptionReconnects))
+	ch <- prometheus.MustNewConstMetric(c.masterCmds, prometheus.CounterValue,
+		float64(st.MasterCommands))
+	ch <- prometheus.MustNewConstMetric(c.droppedEvents, prometheus.CounterValue,
+		float64(st.DroppedEvents))
+}
@@ -0,0 +1,63 @@
+package sentinelprom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sp := sentinel.NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		sentinel.WithTopologyLogLevel(sentinel.LogOff))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+
+	c := NewCollector(sp, "mymaster")
+	// pedantic registry checks collected metrics against descriptors
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(c, "redis_sentinel_seconds_since_master_switch"); n != 0 {
+		t.Fatalf("unexpected time since switch before any, %d metrics", n)
+	}
+
+	conn := sp.Get()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+
+	expected := `
+# HELP redis_sentinel_master_switches_total Number of times pool switched to another master.
+# TYPE redis_sentinel_master_switches_total counter
+redis_sentinel_master_switches_total{master_name="mymaster"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"redis_sentinel_master_switches_total"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(c, "redis_sentinel_seconds_since_master_switch"); n != 1 {
+		t.Fatalf("unexpected time since switch, %d metrics", n)
+	}
+	if n := testutil.CollectAndCount(c, "redis_sentinel_sentinel_dial_errors_total"); n != 1 {
+		t.Fatalf("expected dial errors of one sentinel, got %d metrics", n)
+	}
+}
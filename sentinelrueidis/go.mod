@@ -0,0 +1,15 @@
+module github.com/RivenZoo/go-sentinel/sentinelrueidis
+
+go 1.25.0
+
+require (
+	github.com/RivenZoo/go-sentinel v0.0.0
+	github.com/redis/rueidis v1.0.78
+)
+
+require (
+	github.com/gomodule/redigo v1.9.3 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+)
+
+replace github.com/RivenZoo/go-sentinel => ../
@@ -0,0 +1,295 @@
+// Package sentinelrueidis keeps rueidis clients to master and replicas
+// discovered through Sentinel. Each node is served by a single client
+// multiplexing concurrent commands over one pipelined connection, which
+// scales better under high concurrency than a pool of redigo connections.
+//
+//	sntl := sentinel.NewSentinel(addrs, "mymaster")
+//	pool, err := sentinelrueidis.NewPool(sntl, rueidis.ClientOption{})
+//	if err != nil {
+//		return err
+//	}
+//	defer pool.Close()
+//	client := pool.Master()
+//	err = client.Do(ctx, client.B().Set().Key("k").Value("v").Build()).Error()
+package sentinelrueidis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/redis/rueidis"
+)
+
+// resubscribeDelay is how long Pool waits before subscribing to master
+// switch events again after subscription failed.
+const resubscribeDelay = time.Second
+
+// ErrPoolClosed is returned when using Pool after Close.
+var ErrPoolClosed = errors.New("sentinelrueidis: pool closed")
+
+// Pool keeps rueidis client to current master of master Sentinel monitors
+// and clients to its replicas. When Sentinel reports master switch, client
+// to new master replaces the old one, which is closed once commands in
+// flight are finished.
+type Pool struct {
+	sntl *sentinel.Sentinel
+	opt  rueidis.ClientOption
+	next uint32
+	done chan struct{}
+	// loadMu serializes replica lookups, so p.mu is not held while
+	// Sentinel is asked and replicas are connected to.
+	loadMu sync.Mutex
+
+	mu       sync.RWMutex
+	addr     string
+	master   rueidis.Client
+	replicas map[string]rueidis.Client
+	addrs    []string
+	stale    bool
+	// switches counts master switches, so lookup started before one
+	// does not mark replicas fresh.
+	switches uint64
+	closed   bool
+	ms       *sentinel.MasterSentinel
+}
+
+// NewPool resolves master through sntl and connects to it with client
+// configured by opt. InitAddress and ForceSingleClient of opt are set by
+// Pool, and unless PipelineMultiplex is set every node is served by a
+// single connection. Master switches are watched until Close is called.
+func NewPool(sntl *sentinel.Sentinel, opt rueidis.ClientOption) (*Pool, error) {
+	if opt.PipelineMultiplex == 0 {
+		opt.PipelineMultiplex = -1
+	}
+	opt.ForceSingleClient = true
+	p := &Pool{
+		sntl:  sntl,
+		opt:   opt,
+		done:  make(chan struct{}),
+		stale: true,
+	}
+	addr, err := sntl.MasterAddr()
+	if err != nil {
+		return nil, err
+	}
+	if p.master, err = p.newClient(addr); err != nil {
+		return nil, err
+	}
+	p.addr = addr
+	go p.watch()
+	return p, nil
+}
+
+// newClient connects to node on addr.
+func (p *Pool) newClient(addr string) (rueidis.Client, error) {
+	opt := p.opt
+	opt.InitAddress = []string{addr}
+	return rueidis.NewClient(opt)
+}
+
+// Master returns client to current master. It must not be closed by
+// caller and should not be kept for long, as it is closed after master
+// switch.
+func (p *Pool) Master() rueidis.Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.master
+}
+
+// MasterAddr returns address of current master.
+func (p *Pool) MasterAddr() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.addr
+}
+
+// Do sends cmd to current master.
+func (p *Pool) Do(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResult {
+	return p.Master().Do(ctx, cmd)
+}
+
+// Replica returns client to one of replicas of current master, picked
+// round-robin. Replicas are looked up on first use and again after master
+// switch. Returned client must not be closed by caller.
+func (p *Pool) Replica() (rueidis.Client, error) {
+	p.mu.RLock()
+	closed, stale := p.closed, p.stale
+	p.mu.RUnlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+	if stale {
+		if err := p.loadReplicas(); err != nil {
+			return nil, err
+		}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	if len(p.addrs) == 0 {
+		return nil, sentinel.ErrNoReplicas
+	}
+	addr := p.addrs[atomic.AddUint32(&p.next, 1)%uint32(len(p.addrs))]
+	return p.replicas[addr], nil
+}
+
+// loadReplicas looks replicas up, connecting to new ones and closing
+// clients to ones which are gone. Concurrent callers wait for lookup in
+// progress instead of making their own.
+func (p *Pool) loadReplicas() error {
+	p.loadMu.Lock()
+	defer p.loadMu.Unlock()
+	p.mu.RLock()
+	stale, switches, known := p.stale, p.switches, p.replicas
+	p.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+
+	addrs, err := p.sntl.SlaveAddrs()
+	if err != nil {
+		return err
+	}
+	replicas := make(map[string]rueidis.Client, len(addrs))
+	var reachable []string
+	for _, addr := range addrs {
+		c, ok := known[addr]
+		if !ok {
+			if c, err = p.newClient(addr); err != nil {
+				continue
+			}
+		}
+		replicas[addr] = c
+		reachable = append(reachable, addr)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		// clients known before were closed by Close
+		for addr, c := range replicas {
+			if _, ok := known[addr]; !ok {
+				c.Close()
+			}
+		}
+		return ErrPoolClosed
+	}
+	p.replicas, p.addrs = replicas, reachable
+	// master switched meanwhile, replicas have to be looked up again
+	p.stale = p.switches != switches
+	p.mu.Unlock()
+	for addr, c := range known {
+		if _, ok := replicas[addr]; !ok {
+			go c.Close()
+		}
+	}
+	return nil
+}
+
+// watch follows master switches until pool is closed.
+func (p *Pool) watch() {
+	for {
+		ms, err := p.sntl.MasterSwitch()
+		if err == nil {
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				ms.Close()
+				return
+			}
+			p.ms = ms
+			p.mu.Unlock()
+			// switches may be missed while not subscribed, so master is
+			// resolved again and Watch replays its fresh address
+			p.sntl.MasterAddr()
+			w, _ := ms.Watch()
+			p.follow(w)
+			ms.Close()
+			p.mu.Lock()
+			p.ms = nil
+			p.mu.Unlock()
+		}
+		select {
+		case <-p.done:
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+// follow switches pool to master addresses received on w until it is
+// closed. Switch which failed is retried until it succeeds or another
+// master is reported.
+func (p *Pool) follow(w <-chan string) {
+	var pending string
+	var retry <-chan time.Time
+	for {
+		select {
+		case addr, ok := <-w:
+			if !ok {
+				return
+			}
+			pending = addr
+		case <-retry:
+		}
+		if p.switchMaster(pending) {
+			pending, retry = "", nil
+		} else {
+			retry = time.After(resubscribeDelay)
+		}
+	}
+}
+
+// switchMaster connects to master on addr unless it is already used, and
+// reports whether pool uses it now. If connecting fails, pool keeps
+// previous master.
+func (p *Pool) switchMaster(addr string) bool {
+	if p.MasterAddr() == addr {
+		return true
+	}
+	c, err := p.newClient(addr)
+	if err != nil {
+		return false
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		c.Close()
+		return true
+	}
+	old := p.master
+	p.master, p.addr, p.stale = c, addr, true
+	p.switches++
+	p.mu.Unlock()
+	// Close waits for commands in flight
+	go old.Close()
+	return true
+}
+
+// Close stops watching master switches and closes all clients.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	ms := p.ms
+	master, replicas := p.master, p.replicas
+	p.replicas = nil
+	p.mu.Unlock()
+	if ms != nil {
+		ms.Close()
+	}
+	master.Close()
+	for _, c := range replicas {
+		c.Close()
+	}
+}
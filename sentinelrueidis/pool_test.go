@@ -0,0 +1,134 @@
+package sentinelrueidis
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sentinel "github.com/RivenZoo/go-sentinel"
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/redis/rueidis"
+)
+
+// slowConn holds SENTINEL SLAVES queries while slow is set, signalling
+// entered first.
+type slowConn struct {
+	sentinel.Conn
+	slow    *atomic.Bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (c slowConn) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if c.slow.Load() && cmd == "SENTINEL" && strings.EqualFold(args[0].(string), "slaves") {
+		c.entered <- struct{}{}
+		<-c.release
+	}
+	return c.Conn.Do(ctx, cmd, args...)
+}
+
+func TestPoolReplica(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	var slow atomic.Bool
+	entered, release := make(chan struct{}), make(chan struct{})
+	sntl := sentinel.NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	sntl.Dialer = sentinel.DialerFunc(func(ctx context.Context, addr string) (sentinel.Conn, error) {
+		c, err := sentinel.RedigoDialer{}.Dial(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return slowConn{Conn: c, slow: &slow, entered: entered, release: release}, nil
+	})
+	defer sntl.Close()
+	p, err := NewPool(sntl, rueidis.ClientOption{DisableCache: true, AlwaysRESP2: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	slow.Store(true)
+	type result struct {
+		c   rueidis.Client
+		err error
+	}
+	got := make(chan result, 1)
+	go func() {
+		c, err := p.Replica()
+		got <- result{c, err}
+	}()
+	<-entered
+
+	// master stays usable while replicas are looked up
+	addr := make(chan string, 1)
+	go func() {
+		addr <- p.MasterAddr()
+	}()
+	select {
+	case a := <-addr:
+		if a != cluster.Master.Addr() {
+			t.Fatalf("unexpected master %s", a)
+		}
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("MasterAddr blocked by replica lookup")
+	}
+
+	close(release)
+	r := <-got
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	role, err := r.c.Do(ctx, r.c.B().Role().Build()).ToArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := role[0].ToString(); s != "slave" {
+		t.Fatalf("replica client connected to %s", s)
+	}
+}
+
+func TestPoolFailoverWhileUnsubscribed(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	sntl := sentinel.NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer sntl.Close()
+	p, err := NewPool(sntl, rueidis.ClientOption{DisableCache: true, AlwaysRESP2: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	subscribed := func() bool {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return p.ms != nil
+	}
+	waitFor := func(cond func() bool, what string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !cond(); {
+			if time.Now().After(deadline) {
+				t.Fatal(what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(subscribed, "pool did not subscribe")
+	cluster.Sentinels[0].DropConns()
+	waitFor(func() bool { return !subscribed() }, "subscription loss was not noticed")
+	// switch-master event is published while nobody listens
+	cluster.Failover(cluster.Replicas[0])
+	waitFor(func() bool { return p.MasterAddr() == cluster.Master.Addr() },
+		"failover missed while unsubscribed was not followed")
+}
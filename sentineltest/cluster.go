@@ -0,0 +1,83 @@
+package sentineltest
+
+// Cluster is a master group of fake Redis instances monitored by fake
+// Sentinels aware of each other.
+type Cluster struct {
+	MasterName string
+	Master     *Redis
+	Replicas   []*Redis
+	Sentinels  []*Sentinel
+}
+
+// NewCluster starts master with given number of replicas and sentinels.
+func NewCluster(masterName string, replicas, sentinels int) (*Cluster, error) {
+	c := &Cluster{MasterName: masterName}
+	var err error
+	if c.Master, err = NewRedis(); err != nil {
+		return nil, err
+	}
+	for i := 0; i < replicas; i++ {
+		r, err := NewRedis()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		r.ReplicaOf(c.Master)
+		c.Replicas = append(c.Replicas, r)
+	}
+	for i := 0; i < sentinels; i++ {
+		s, err := NewSentinel(masterName, c.Master, c.Replicas...)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.Sentinels = append(c.Sentinels, s)
+	}
+	for _, s := range c.Sentinels {
+		s.AddPeers(c.Sentinels...)
+	}
+	return c, nil
+}
+
+// SentinelAddrs returns addresses of all sentinels.
+func (c *Cluster) SentinelAddrs() []string {
+	addrs := make([]string, 0, len(c.Sentinels))
+	for _, s := range c.Sentinels {
+		addrs = append(addrs, s.Addr())
+	}
+	return addrs
+}
+
+// Failover promotes replica to master, turns other instances into its
+// replicas and makes every sentinel publish +switch-master.
+func (c *Cluster) Failover(promote *Redis) {
+	old := c.Master
+	replicas := []*Redis{old}
+	for _, r := range c.Replicas {
+		if r != promote {
+			replicas = append(replicas, r)
+		}
+	}
+	promote.Promote()
+	for _, r := range replicas {
+		r.ReplicaOf(promote)
+	}
+	c.Master = promote
+	c.Replicas = replicas
+	for _, s := range c.Sentinels {
+		s.SwitchMaster(promote)
+	}
+}
+
+// Close stops all instances and sentinels.
+func (c *Cluster) Close() {
+	for _, s := range c.Sentinels {
+		s.Close()
+	}
+	for _, r := range c.Replicas {
+		r.Close()
+	}
+	if c.Master != nil {
+		c.Master.Close()
+	}
+}
@@ -0,0 +1,320 @@
+package sentineltest
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Redis is a fake Redis instance which acts either as master or replica.
+// It keeps string keys in memory, supports pub/sub including keyspace
+// notifications, and rejects writes with READONLY error while it is a
+// replica.
+type Redis struct {
+	srv   *server
+	runID string
+
+	mu       sync.Mutex
+	master   string
+	username string
+	password string
+	offset   int64
+	acks     int
+	config   map[string]string
+	data     map[string]string
+	client   []string
+}
+
+// NewRedis starts fake Redis master listening on random local port.
+func NewRedis() (*Redis, error) {
+	r := &Redis{
+		config: map[string]string{"notify-keyspace-events": ""},
+		data:   make(map[string]string),
+	}
+	srv, err := newServer(r.handle)
+	if err != nil {
+		return nil, err
+	}
+	r.srv = srv
+	r.runID = newRunID()
+	return r, nil
+}
+
+// Addr returns address instance listens on.
+func (r *Redis) Addr() string {
+	return r.srv.addr()
+}
+
+// Close stops instance and closes its connections.
+func (r *Redis) Close() {
+	r.srv.close()
+}
+
+// RequirePass makes instance require AUTH with password.
+func (r *Redis) RequirePass(password string) {
+	r.mu.Lock()
+	r.password = password
+	r.mu.Unlock()
+}
+
+// RequireAuth makes instance require AUTH as ACL user username with
+// password.
+func (r *Redis) RequireAuth(username, password string) {
+	r.mu.Lock()
+	r.username = username
+	r.password = password
+	r.mu.Unlock()
+}
+
+// Role returns "master" or "slave".
+func (r *Redis) Role() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.master == "" {
+		return "master"
+	}
+	return "slave"
+}
+
+// ReplicaOf makes instance a replica of master, like SLAVEOF would.
+func (r *Redis) ReplicaOf(master *Redis) {
+	r.mu.Lock()
+	r.master = master.Addr()
+	r.mu.Unlock()
+}
+
+// Promote makes instance a master, like SLAVEOF NO ONE would.
+func (r *Redis) Promote() {
+	r.mu.Lock()
+	r.master = ""
+	r.mu.Unlock()
+}
+
+// SetReplOffset sets replication offset instance reports in ROLE and INFO.
+func (r *Redis) SetReplOffset(offset int64) {
+	r.mu.Lock()
+	r.offset = offset
+	r.mu.Unlock()
+}
+
+// SetWaitAcks sets number of replicas WAIT replies acknowledged writes.
+func (r *Redis) SetWaitAcks(acks int) {
+	r.mu.Lock()
+	r.acks = acks
+	r.mu.Unlock()
+}
+
+// SetConfig sets configuration parameter, like CONFIG SET would. Setting
+// notify-keyspace-events enables keyspace notifications on SET and DEL.
+func (r *Redis) SetConfig(name, value string) {
+	r.mu.Lock()
+	r.config[name] = value
+	r.mu.Unlock()
+}
+
+// Publish sends payload to clients subscribed to channel or to pattern
+// matching it.
+func (r *Redis) Publish(channel, payload string) {
+	r.srv.publish(channel, payload)
+}
+
+// Get returns value of key, empty if it is not set.
+func (r *Redis) Get(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.data[key]
+}
+
+// ClientCommands returns CLIENT commands instance received, like
+// "CLIENT NO-EVICT ON", in order.
+func (r *Redis) ClientCommands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.client...)
+}
+
+// DropConns closes all client connections, as if instance was restarted.
+func (r *Redis) DropConns() {
+	r.srv.dropConns()
+}
+
+func (r *Redis) handle(c *conn, args []string) {
+	if r.authorized(c) && c.pubsub(args) {
+		return
+	}
+	c.reply(r.exec(c, args))
+}
+
+// scan implements SCAN over keys in sorted order, cursor being index of the
+// next key.
+// Lock must be held by caller.
+func (r *Redis) scan(args []string) interface{} {
+	if len(args) == 0 || len(args)%2 == 0 {
+		return wrongArgs("SCAN")
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		return redisError("ERR invalid cursor")
+	}
+	match, count := "*", 10
+	for i := 1; i < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			match = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count <= 0 {
+				return redisError("ERR value is not an integer or out of range")
+			}
+		default:
+			return redisError("ERR syntax error")
+		}
+	}
+	keys := make([]string, 0, len(r.data))
+	for key := range r.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	batch := []interface{}{}
+	for ; cursor < len(keys) && count > 0; cursor, count = cursor+1, count-1 {
+		if ok, _ := path.Match(match, keys[cursor]); ok {
+			batch = append(batch, keys[cursor])
+		}
+	}
+	if cursor >= len(keys) {
+		cursor = 0
+	}
+	return []interface{}{strconv.Itoa(cursor), batch}
+}
+
+// authorized reports whether c may run commands.
+func (r *Redis) authorized(c *conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.password == "" || c.authed
+}
+
+// notify publishes keyspace notifications about event of class on key,
+// if notify-keyspace-events enables them.
+// Lock must be held by caller.
+func (r *Redis) notify(class, event, key string) {
+	flags := r.config["notify-keyspace-events"]
+	if !strings.Contains(flags, class) && !strings.Contains(flags, "A") {
+		return
+	}
+	if strings.Contains(flags, "K") {
+		r.srv.publish("__keyspace@0__:"+key, event)
+	}
+	if strings.Contains(flags, "E") {
+		r.srv.publish("__keyevent@0__:"+event, key)
+	}
+}
+
+func (r *Redis) exec(c *conn, args []string) interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cmd := args[0]
+	if cmd == "AUTH" {
+		username := ""
+		switch len(args) {
+		case 2:
+		case 3:
+			username = args[1]
+		default:
+			return wrongArgs(cmd)
+		}
+		if r.password == "" {
+			return redisError("ERR Client sent AUTH, but no password is set")
+		}
+		if username != r.username || args[len(args)-1] != r.password {
+			return redisError("WRONGPASS invalid username-password pair")
+		}
+		c.authed = true
+		return status("OK")
+	}
+	if r.password != "" && !c.authed {
+		return redisError("NOAUTH Authentication required.")
+	}
+	switch cmd {
+	case "PING":
+		return status("PONG")
+	case "SELECT", "READONLY":
+		return status("OK")
+	case "CLIENT":
+		r.client = append(r.client, strings.Join(args, " "))
+		return status("OK")
+	case "ROLE":
+		if r.master == "" {
+			return []interface{}{"master", r.offset, []interface{}{}}
+		}
+		host, port := splitAddr(r.master)
+		p, _ := strconv.ParseInt(port, 10, 64)
+		return []interface{}{"slave", host, p, "connected", r.offset}
+	case "INFO":
+		if r.master != "" {
+			return fmt.Sprintf("# Server\r\nrun_id:%s\r\n# Replication\r\nrole:slave\r\nslave_repl_offset:%d\r\n",
+				r.runID, r.offset)
+		}
+		return fmt.Sprintf("# Server\r\nrun_id:%s\r\n# Replication\r\nrole:master\r\nmaster_repl_offset:%d\r\n",
+			r.runID, r.offset)
+	case "WAIT":
+		if len(args) != 3 {
+			return wrongArgs(cmd)
+		}
+		return int64(r.acks)
+	case "CONFIG":
+		if len(args) < 3 {
+			return wrongArgs(cmd)
+		}
+		switch strings.ToUpper(args[1]) {
+		case "GET":
+			v, ok := r.config[args[2]]
+			if !ok {
+				return []interface{}{}
+			}
+			return []interface{}{args[2], v}
+		case "SET":
+			if len(args) != 4 {
+				return wrongArgs(cmd)
+			}
+			r.config[args[2]] = args[3]
+			return status("OK")
+		}
+		return redisError(fmt.Sprintf("ERR unknown CONFIG subcommand '%s'", args[1]))
+	case "GET":
+		if len(args) != 2 {
+			return wrongArgs(cmd)
+		}
+		v, ok := r.data[args[1]]
+		if !ok {
+			return nil
+		}
+		return v
+	case "SCAN":
+		return r.scan(args[1:])
+	case "SET", "DEL":
+		if r.master != "" {
+			return redisError("READONLY You can't write against a read only replica.")
+		}
+		if cmd == "SET" {
+			if len(args) != 3 {
+				return wrongArgs(cmd)
+			}
+			r.data[args[1]] = args[2]
+			r.notify("$", "set", args[1])
+			return status("OK")
+		}
+		n := int64(0)
+		for _, key := range args[1:] {
+			if _, ok := r.data[key]; ok {
+				delete(r.data, key)
+				r.notify("g", "del", key)
+				n++
+			}
+		}
+		return n
+	}
+	return redisError(fmt.Sprintf("ERR unknown command '%s'", cmd))
+}
@@ -0,0 +1,276 @@
+package sentineltest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sentinel is a fake Redis Sentinel monitoring a single master group. It
+// answers SENTINEL queries sentinel package makes and publishes events to
+// subscribed clients.
+type Sentinel struct {
+	srv   *server
+	runID string
+
+	mu         sync.Mutex
+	masterName string
+	master     *Redis
+	replicas   []*Redis
+	peers      []*Sentinel
+	epoch      int64
+	username   string
+	password   string
+	monitored  []string
+}
+
+// NewSentinel starts fake Sentinel listening on random local port, which
+// monitors master under masterName.
+func NewSentinel(masterName string, master *Redis, replicas ...*Redis) (*Sentinel, error) {
+	s := &Sentinel{
+		runID:      newRunID(),
+		masterName: masterName,
+		master:     master,
+		replicas:   append([]*Redis(nil), replicas...),
+	}
+	srv, err := newServer(s.handle)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = srv
+	return s, nil
+}
+
+// Addr returns address Sentinel listens on.
+func (s *Sentinel) Addr() string {
+	return s.srv.addr()
+}
+
+// Close stops Sentinel and closes its connections.
+func (s *Sentinel) Close() {
+	s.srv.close()
+}
+
+// DropConns closes all client connections, including subscriptions.
+func (s *Sentinel) DropConns() {
+	s.srv.dropConns()
+}
+
+// RequireAuth makes Sentinel require AUTH with password, and with username
+// unless it is empty.
+func (s *Sentinel) RequireAuth(username, password string) {
+	s.mu.Lock()
+	s.username = username
+	s.password = password
+	s.mu.Unlock()
+}
+
+// AddPeers makes Sentinel report peers in SENTINEL sentinels.
+func (s *Sentinel) AddPeers(peers ...*Sentinel) {
+	s.mu.Lock()
+	for _, p := range peers {
+		if p != s {
+			s.peers = append(s.peers, p)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// SwitchMaster makes Sentinel report newMaster as master, former master
+// as one of its replicas, and publishes +switch-master. Roles of instances
+// are not changed, see Cluster.Failover.
+func (s *Sentinel) SwitchMaster(newMaster *Redis) {
+	s.mu.Lock()
+	old := s.master
+	replicas := []*Redis{old}
+	for _, r := range s.replicas {
+		if r != newMaster {
+			replicas = append(replicas, r)
+		}
+	}
+	s.master = newMaster
+	s.replicas = replicas
+	s.epoch++
+	name := s.masterName
+	s.mu.Unlock()
+
+	oldHost, oldPort := splitAddr(old.Addr())
+	newHost, newPort := splitAddr(newMaster.Addr())
+	s.Publish("+switch-master", strings.Join([]string{name, oldHost, oldPort, newHost, newPort}, " "))
+}
+
+// Monitored returns arguments of SENTINEL MONITOR commands Sentinel
+// accepted, like "mymaster 127.0.0.1 6379 2", in order.
+func (s *Sentinel) Monitored() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.monitored...)
+}
+
+// Publish sends payload to clients subscribed to channel or to pattern
+// matching it.
+func (s *Sentinel) Publish(channel, payload string) {
+	s.srv.publish(channel, payload)
+}
+
+func (s *Sentinel) handle(c *conn, args []string) {
+	if args[0] == "AUTH" {
+		c.reply(s.auth(c, args[1:]))
+		return
+	}
+	s.mu.Lock()
+	authorized := s.password == "" || c.authed
+	s.mu.Unlock()
+	if !authorized {
+		c.reply(redisError("NOAUTH Authentication required."))
+		return
+	}
+	if c.pubsub(args) {
+		return
+	}
+	c.reply(s.exec(args))
+}
+
+// auth implements AUTH [username] password.
+func (s *Sentinel) auth(c *conn, args []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	username, password := "", ""
+	switch len(args) {
+	case 1:
+		password = args[0]
+	case 2:
+		username, password = args[0], args[1]
+	default:
+		return wrongArgs("AUTH")
+	}
+	if s.password == "" {
+		return redisError("ERR Client sent AUTH, but no password is set")
+	}
+	if username != s.username || password != s.password {
+		return redisError("WRONGPASS invalid username-password pair")
+	}
+	c.authed = true
+	return status("OK")
+}
+
+func (s *Sentinel) exec(args []string) interface{} {
+	switch args[0] {
+	case "PING":
+		return status("PONG")
+	case "CLIENT":
+		return status("OK")
+	case "ROLE":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return []interface{}{"sentinel", []interface{}{s.masterName}}
+	case "INFO":
+		return fmt.Sprintf("# Server\r\nrun_id:%s\r\nredis_mode:sentinel\r\n", s.runID)
+	case "SENTINEL":
+		if len(args) < 2 {
+			return wrongArgs(args[0])
+		}
+		return s.sentinel(strings.ToLower(args[1]), args[2:])
+	}
+	return redisError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+}
+
+func (s *Sentinel) sentinel(sub string, args []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sub == "masters" {
+		return []interface{}{s.masterState()}
+	}
+	if sub == "monitor" {
+		return s.monitor(args)
+	}
+	if len(args) < 1 {
+		return wrongArgs("sentinel " + sub)
+	}
+	known := args[0] == s.masterName
+	switch sub {
+	case "get-master-addr-by-name":
+		if !known {
+			return nil
+		}
+		host, port := splitAddr(s.master.Addr())
+		return []string{host, port}
+	case "master", "slaves", "replicas", "sentinels":
+		if !known {
+			return redisError("ERR No such master with that name")
+		}
+	default:
+		return redisError(fmt.Sprintf("ERR Unknown sentinel subcommand '%s'", sub))
+	}
+	switch sub {
+	case "master":
+		return s.masterState()
+	case "sentinels":
+		reply := make([]interface{}, 0, len(s.peers))
+		for _, p := range s.peers {
+			host, port := splitAddr(p.Addr())
+			reply = append(reply, []string{
+				"name", p.Addr(), "ip", host, "port", port,
+				"runid", p.runID, "flags", "sentinel",
+			})
+		}
+		return reply
+	}
+	reply := make([]interface{}, 0, len(s.replicas))
+	mhost, mport := splitAddr(s.master.Addr())
+	for _, r := range s.replicas {
+		host, port := splitAddr(r.Addr())
+		reply = append(reply, []string{
+			"name", r.Addr(), "ip", host, "port", port,
+			"runid", r.runID, "flags", "slave",
+			"master-host", mhost, "master-port", mport,
+			"master-link-status", "ok", "slave-priority", "100",
+			"slave-repl-offset", "0",
+		})
+	}
+	return reply
+}
+
+// monitor implements SENTINEL MONITOR. Sentinel starts monitoring its
+// master under new name if address is the one of its master.
+// Lock must be held by caller.
+func (s *Sentinel) monitor(args []string) interface{} {
+	if len(args) != 4 {
+		return wrongArgs("sentinel monitor")
+	}
+	if args[0] == s.masterName {
+		return redisError("ERR Duplicated master name")
+	}
+	s.monitored = append(s.monitored, strings.Join(args, " "))
+	if host, port := splitAddr(s.master.Addr()); args[1] == host && args[2] == port {
+		s.masterName = args[0]
+	}
+	return status("OK")
+}
+
+// masterState returns reply to SENTINEL master.
+// Lock must be held by caller.
+func (s *Sentinel) masterState() []string {
+	host, port := splitAddr(s.master.Addr())
+	return []string{
+		"name", s.masterName,
+		"ip", host,
+		"port", port,
+		"runid", s.master.runID,
+		"flags", "master",
+		"num-slaves", strconv.Itoa(len(s.replicas)),
+		"num-other-sentinels", strconv.Itoa(len(s.peers)),
+		"quorum", "2",
+		"config-epoch", strconv.FormatInt(s.epoch, 10),
+		"last-ok-ping", "0",
+	}
+}
+
+func newRunID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
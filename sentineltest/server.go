@@ -0,0 +1,314 @@
+// Package sentineltest provides in-process fake Redis and Sentinel servers
+// speaking RESP, so code using sentinel package, including failover
+// handling, can be tested with go test without running real servers.
+package sentineltest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// status is a RESP simple string reply.
+type status string
+
+// redisError is a RESP error reply.
+type redisError string
+
+// conn is a client connection to fake server.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+
+	// mu serializes replies, which can also be written by publishers
+	mu sync.Mutex
+	w  *bufio.Writer
+
+	authed   bool
+	channels map[string]bool
+	patterns map[string]bool
+}
+
+// server accepts connections and passes commands to handle.
+type server struct {
+	ln     net.Listener
+	handle func(c *conn, args []string)
+
+	mu    sync.Mutex
+	conns map[*conn]struct{}
+	wg    sync.WaitGroup
+}
+
+func newServer(handle func(c *conn, args []string)) (*server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	srv := &server{ln: ln, handle: handle, conns: make(map[*conn]struct{})}
+	srv.wg.Add(1)
+	go srv.serve()
+	return srv, nil
+}
+
+func (srv *server) addr() string {
+	return srv.ln.Addr().String()
+}
+
+func (srv *server) serve() {
+	defer srv.wg.Done()
+	for {
+		nc, err := srv.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+		srv.mu.Lock()
+		srv.conns[c] = struct{}{}
+		srv.mu.Unlock()
+		srv.wg.Add(1)
+		go srv.serveConn(c)
+	}
+}
+
+func (srv *server) serveConn(c *conn) {
+	defer srv.wg.Done()
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.conns, c)
+		srv.mu.Unlock()
+		c.Close()
+	}()
+	for {
+		args, err := readCommand(c.r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		args[0] = strings.ToUpper(args[0])
+		if args[0] == "QUIT" {
+			c.reply(status("OK"))
+			return
+		}
+		srv.handle(c, args)
+	}
+}
+
+// each calls f for every open connection.
+func (srv *server) each(f func(c *conn)) {
+	srv.mu.Lock()
+	conns := make([]*conn, 0, len(srv.conns))
+	for c := range srv.conns {
+		conns = append(conns, c)
+	}
+	srv.mu.Unlock()
+	for _, c := range conns {
+		f(c)
+	}
+}
+
+// dropConns closes all client connections, keeping listener open.
+func (srv *server) dropConns() {
+	srv.each(func(c *conn) { c.Close() })
+}
+
+func (srv *server) close() {
+	srv.ln.Close()
+	srv.dropConns()
+	srv.wg.Wait()
+}
+
+// publish sends payload to clients subscribed to channel or to pattern
+// matching it.
+func (srv *server) publish(channel, payload string) {
+	srv.each(func(c *conn) {
+		c.mu.Lock()
+		var replies []interface{}
+		if c.channels[channel] {
+			replies = append(replies, []interface{}{"message", channel, payload})
+		}
+		for pattern := range c.patterns {
+			if ok, _ := path.Match(pattern, channel); ok {
+				replies = append(replies, []interface{}{"pmessage", pattern, channel, payload})
+			}
+		}
+		for _, reply := range replies {
+			writeValue(c.w, reply)
+		}
+		c.w.Flush()
+		c.mu.Unlock()
+	})
+}
+
+// pubsub handles subscription commands and reports whether args was one.
+func (c *conn) pubsub(args []string) bool {
+	switch args[0] {
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		c.subscribe(args[0] == "PSUBSCRIBE", args[1:])
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		c.unsubscribe(args[0] == "PUNSUBSCRIBE", args[1:])
+	case "PING":
+		return c.pingSubscribed(args[1:])
+	default:
+		return false
+	}
+	return true
+}
+
+// pingSubscribed replies to PING with pong message if connection is
+// subscribed, as Redis does, and reports whether it was.
+func (c *conn) pingSubscribed(args []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.channels)+len(c.patterns) == 0 {
+		return false
+	}
+	data := ""
+	if len(args) > 0 {
+		data = args[0]
+	}
+	writeValue(c.w, []interface{}{"pong", data})
+	c.w.Flush()
+	return true
+}
+
+func (c *conn) subscribe(pattern bool, names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channels == nil {
+		c.channels = make(map[string]bool)
+		c.patterns = make(map[string]bool)
+	}
+	kind, set := "subscribe", c.channels
+	if pattern {
+		kind, set = "psubscribe", c.patterns
+	}
+	for _, name := range names {
+		set[name] = true
+		writeValue(c.w, []interface{}{kind, name, int64(len(c.channels) + len(c.patterns))})
+	}
+	c.w.Flush()
+}
+
+func (c *conn) unsubscribe(pattern bool, names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kind, set := "unsubscribe", c.channels
+	if pattern {
+		kind, set = "punsubscribe", c.patterns
+	}
+	if len(names) == 0 {
+		for name := range set {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		writeValue(c.w, []interface{}{kind, nil, int64(len(c.channels) + len(c.patterns))})
+	}
+	for _, name := range names {
+		delete(set, name)
+		writeValue(c.w, []interface{}{kind, name, int64(len(c.channels) + len(c.patterns))})
+	}
+	c.w.Flush()
+}
+
+// readCommand reads command sent as RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		// inline command
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errors.New("sentineltest: bulk string expected")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// reply writes v to client and flushes it.
+func (c *conn) reply(v interface{}) {
+	c.mu.Lock()
+	writeValue(c.w, v)
+	c.w.Flush()
+	c.mu.Unlock()
+}
+
+func writeValue(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case redisError:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []string:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, s := range v {
+			writeValue(w, s)
+		}
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeValue(w, item)
+		}
+	default:
+		panic(fmt.Sprintf("sentineltest: unsupported reply type %T", v))
+	}
+}
+
+// splitAddr returns host and port of addr.
+func splitAddr(addr string) (string, string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, ""
+	}
+	return host, port
+}
+
+func wrongArgs(cmd string) redisError {
+	return redisError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
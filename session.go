@@ -0,0 +1,117 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NotEnoughReplicas is returned by WaitForWrites when fewer replicas than
+// requested acknowledged writes before timeout.
+type NotEnoughReplicas struct {
+	Acked  int
+	Wanted int
+}
+
+func (ne NotEnoughReplicas) Error() string {
+	return fmt.Sprintf("redigo: %d of %d replicas acknowledged writes", ne.Acked, ne.Wanted)
+}
+
+// WaitForWrites issues WAIT over master connection c after writes made on
+// it, blocking until numReplicas replicas acknowledged them or timeout
+// passes, zero meaning forever. It returns master replication offset
+// including the writes, which replica has to reach to serve them, see
+// Session. NotEnoughReplicas is returned together with offset if fewer
+// replicas acknowledged writes.
+func WaitForWrites(c redis.Conn, numReplicas int, timeout time.Duration) (int64, error) {
+	offset, err := ReplicationOffset(c)
+	if err != nil {
+		return 0, err
+	}
+	acked, err := redis.Int(c.Do("WAIT", numReplicas, timeout.Milliseconds()))
+	if err != nil {
+		return offset, err
+	}
+	if acked < numReplicas {
+		return offset, NotEnoughReplicas{Acked: acked, Wanted: numReplicas}
+	}
+	return offset, nil
+}
+
+// Session gives read-your-writes consistency to a single client of
+// SentinelPool, e.g. a user session. Writes made through it are waited for
+// with WaitForWrites and replica reads go to master instead while replica
+// has not reached their offset yet. It is safe for concurrent use.
+type Session struct {
+	sp *SentinelPool
+
+	mu     sync.Mutex
+	offset int64
+}
+
+// Session returns new Session reading from pool replicas.
+func (sp *SentinelPool) Session() *Session {
+	return &Session{sp: sp}
+}
+
+// Wait calls WaitForWrites over master connection c after writes made on
+// it and records offset replica reads have to observe. Offset is recorded
+// even if NotEnoughReplicas is returned.
+func (s *Session) Wait(c redis.Conn, numReplicas int, timeout time.Duration) error {
+	offset, err := WaitForWrites(c, numReplicas, timeout)
+	if _, ok := err.(NotEnoughReplicas); err == nil || ok {
+		s.mu.Lock()
+		if offset > s.offset {
+			s.offset = offset
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// Offset returns replication offset of the latest write recorded with
+// Wait, zero if there is none.
+func (s *Session) Offset() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// GetReplica gets connection to replica which has reached offset of
+// writes recorded with Wait, verified with ROLE, or to master if the
+// replica picked has not, so reads observe the session's own writes.
+// redis.Conn must Close after use
+func (s *Session) GetReplica() redis.Conn {
+	c, _ := s.GetReplicaContext(context.Background())
+	return c
+}
+
+// GetReplicaContext is like GetReplica, but gives up once ctx is done,
+// which also aborts waiting for failover and dialing. Returned connection
+// must be closed after use if error is nil.
+func (s *Session) GetReplicaContext(ctx context.Context) (redis.Conn, error) {
+	target := s.Offset()
+	c, err := s.sp.GetReplicaContext(ctx)
+	if err != nil {
+		c.Close()
+		if ctx.Err() != nil {
+			return errorConn{ctx.Err()}, ctx.Err()
+		}
+		return s.sp.GetContext(ctx)
+	}
+	if target == 0 {
+		return c, nil
+	}
+	// bypass counting, ROLE is not a command issued by application
+	offset, err := ReplicationOffset(uncounted(ctx, c))
+	if err == nil && offset >= target {
+		return c, nil
+	}
+	logFields(s.sp.logger(), LogDebug, "replica behind session writes, reading from master",
+		"master", s.sp.sntl.masterName(), "offset", offset, "target", target, "err", err)
+	c.Close()
+	return s.sp.GetContext(ctx)
+}
@@ -0,0 +1,79 @@
+package sentinel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelPoolSession(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	cluster.Master.SetReplOffset(100)
+	cluster.Replicas[0].SetReplOffset(100)
+
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithFailoverWait(time.Minute))
+	defer sp.Close()
+	session := sp.Session()
+
+	c := sp.Get()
+	if _, err := c.Do("SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	cluster.Master.SetReplOffset(150)
+	err = session.Wait(c, 1, 10*time.Millisecond)
+	c.Close()
+	if ne, ok := err.(NotEnoughReplicas); !ok || ne.Acked != 0 || ne.Wanted != 1 {
+		t.Fatalf("expected NotEnoughReplicas, got %v", err)
+	}
+	if offset := session.Offset(); offset != 150 {
+		t.Fatalf("expected offset 150, got %d", offset)
+	}
+
+	// replica has not reached the write yet
+	c = session.GetReplica()
+	role, err := getRole(c)
+	c.Close()
+	if err != nil || role != "master" {
+		t.Fatalf("lagging replica must not be read, got %q, %v", role, err)
+	}
+
+	cluster.Master.SetWaitAcks(1)
+	cluster.Replicas[0].SetReplOffset(150)
+	c = sp.Get()
+	err = session.Wait(c, 1, 0)
+	c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := sp.Stats().ReplicaCommands
+	c = session.GetReplica()
+	// offset check is not a command of application
+	if n := sp.Stats().ReplicaCommands; n != before {
+		t.Fatalf("offset check was counted, %d replica commands", n-before)
+	}
+	role, err = getRole(c)
+	c.Close()
+	if err != nil || role != "slave" {
+		t.Fatalf("replica which reached the write must be read, got %q, %v", role, err)
+	}
+
+	// reading from master while failover is in progress gives up with ctx
+	cluster.Master.SetReplOffset(200)
+	c = sp.Get()
+	session.Wait(c, 1, 10*time.Millisecond)
+	c.Close()
+	sp.gate.close()
+	defer sp.gate.open()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := session.GetReplicaContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package sentinel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// SlaveInfo is an entry of SENTINEL SLAVES reply describing one replica of
+// master, so callers can route reads by their own policy. Numeric fields
+// missing from reply are left zero.
+type SlaveInfo struct {
+	Addr  string
+	RunID string
+	// Flags are comma separated flags of replica, e.g. "slave" or
+	// "s_down,slave".
+	Flags string
+	// MasterLinkStatus is "ok" while replica is connected to master and
+	// "err" otherwise.
+	MasterLinkStatus string
+	// MasterLinkDown is for how long link to master is down.
+	MasterLinkDown time.Duration
+	// ReplOffset is replication offset of replica.
+	ReplOffset int64
+	// Priority is replica priority, replicas with lower one are promoted
+	// first and 0 are never promoted.
+	Priority   int
+	LastOKPing time.Duration
+	// Fields are all fields of reply as they were received, including
+	// ones not parsed above.
+	Fields map[string]string
+}
+
+// Slaves returns all replicas of current master instance with their state,
+// including unhealthy ones. Known replicas are updated the same way
+// SlaveAddrs does.
+func (s *Sentinel) Slaves() ([]SlaveInfo, error) {
+	return s.SlavesContext(context.Background())
+}
+
+// SlavesContext is like Slaves, but gives up once ctx is done.
+func (s *Sentinel) SlavesContext(ctx context.Context) ([]SlaveInfo, error) {
+	masterName := s.masterName()
+	ctx, span := s.startSpan(ctx, TraceSlaveAddrs)
+	span.SetAttributes("master", masterName)
+	res, err := s.doUntilSuccess(ctx, func(c redis.Conn) (interface{}, error) {
+		return queryForSlaveInfos(c, masterName, s.Logger)
+	})
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+	infos := res.([]SlaveInfo)
+	s.mu.Lock()
+	s.updateSlaves(masterName, slaveAddrs(infos, s.AllSlaves))
+	s.mu.Unlock()
+	return infos, nil
+}
+
+// unhealthySlaveFlags are flags of replicas which must not be used.
+var unhealthySlaveFlags = []string{"s_down", "o_down", "disconnected"}
+
+// Healthy reports whether replica is not flagged down or disconnected.
+func (info SlaveInfo) Healthy() bool {
+	for _, flag := range strings.Split(info.Flags, ",") {
+		if stringInSlice(flag, unhealthySlaveFlags) {
+			return false
+		}
+	}
+	return true
+}
+
+// slaveAddrs returns addresses of replicas, only of healthy ones unless all
+// is set.
+func slaveAddrs(infos []SlaveInfo, all bool) []string {
+	addrs := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if all || info.Healthy() {
+			addrs = append(addrs, info.Addr)
+		}
+	}
+	return addrs
+}
+
+// queryForSlaveInfos returns replicas of masterName. Malformed entries are
+// logged and skipped, so one of them does not hide the others.
+func queryForSlaveInfos(conn redis.Conn, masterName string, logger Logger) ([]SlaveInfo, error) {
+	res, err := redis.Values(conn.Do("SENTINEL", "slaves", masterName))
+	if err != nil {
+		return nil, err
+	}
+	slaves := make([]SlaveInfo, 0, len(res))
+	for _, a := range res {
+		sm, err := redis.StringMap(a, nil)
+		if err != nil {
+			logFields(logger, LogWarn, "malformed replica entry skipped",
+				"master", masterName, "err", err)
+			continue
+		}
+		info, err := parseSlaveInfo(sm)
+		if err != nil {
+			logFields(logger, LogWarn, "malformed replica entry skipped",
+				"master", masterName, "err", err)
+			continue
+		}
+		slaves = append(slaves, info)
+	}
+	return slaves, nil
+}
+
+// parseSlaveInfo parses entry of SENTINEL SLAVES reply.
+func parseSlaveInfo(state map[string]string) (SlaveInfo, error) {
+	addr, err := joinAddr(state["ip"], state["port"])
+	if err != nil {
+		return SlaveInfo{}, err
+	}
+	p := stateParser{state: state}
+	info := SlaveInfo{
+		Addr:             addr,
+		RunID:            state["runid"],
+		Flags:            state["flags"],
+		MasterLinkStatus: state["master-link-status"],
+		MasterLinkDown:   p.millis("master-link-down-time"),
+		ReplOffset:       p.int("slave-repl-offset"),
+		Priority:         int(p.int("slave-priority")),
+		LastOKPing:       p.millis("last-ok-ping"),
+		Fields:           state,
+	}
+	if p.err != nil {
+		return SlaveInfo{}, p.err
+	}
+	return info, nil
+}
@@ -0,0 +1,109 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+func TestSentinelSlaves(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+	slaves, err := s.Slaves()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slaves) != 2 {
+		t.Fatalf("expected 2 replicas, got %+v", slaves)
+	}
+	for i, info := range slaves {
+		if info.Addr != cluster.Replicas[i].Addr() || info.Flags != "slave" ||
+			info.MasterLinkStatus != "ok" || info.Priority != 100 || info.RunID == "" {
+			t.Fatalf("unexpected replica %+v", info)
+		}
+	}
+	if known := s.Export().Replicas; len(known) != 2 {
+		t.Fatalf("known replicas must be updated, got %v", known)
+	}
+}
+
+func TestParseSlaveInfo(t *testing.T) {
+	info, err := parseSlaveInfo(map[string]string{
+		"ip": "10.0.0.2", "port": "6379", "flags": "s_down,slave",
+		"master-link-status": "err", "master-link-down-time": "1500",
+		"slave-repl-offset": "12345", "slave-priority": "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Addr != "10.0.0.2:6379" || info.MasterLinkDown != 1500*time.Millisecond ||
+		info.ReplOffset != 12345 || info.Priority != 0 || info.MasterLinkStatus != "err" {
+		t.Fatalf("unexpected replica %+v", info)
+	}
+	if _, err := parseSlaveInfo(map[string]string{
+		"ip": "10.0.0.2", "port": "6379", "slave-repl-offset": "?",
+	}); err == nil {
+		t.Fatal("malformed offset must fail")
+	}
+}
+
+func TestQueryForSlavesSkipsUnhealthy(t *testing.T) {
+	slave := func(ip, flags string) interface{} {
+		return []interface{}{
+			[]byte("ip"), []byte(ip), []byte("port"), []byte("6379"), []byte("flags"), []byte(flags),
+		}
+	}
+	reply := []interface{}{
+		slave("10.0.0.2", "slave"),
+		slave("10.0.0.3", "s_down,slave"),
+		slave("10.0.0.4", "o_down,slave"),
+		slave("10.0.0.5", "slave,disconnected"),
+	}
+	replies := []interface{}{reply, reply}
+	addrs, err := queryForSlaves(replyConn{replies: &replies}, "mymaster", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0] != "10.0.0.2:6379" {
+		t.Fatalf("expected only healthy replica, got %v", addrs)
+	}
+	if addrs, _ := queryForSlaves(replyConn{replies: &replies}, "mymaster", true, nil); len(addrs) != 4 {
+		t.Fatalf("expected all replicas, got %v", addrs)
+	}
+}
+
+func TestQueryForSlavesSkipsMalformed(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{[]byte("ip"), []byte("10.0.0.2"), []byte("port"), []byte("6379")},
+		[]interface{}{[]byte("ip"), []byte("10.0.0.3"), []byte("port"), []byte("?")},
+		[]interface{}{[]byte("ip"), []byte("10.0.0.4")},
+		[]byte("garbage"),
+		[]interface{}{[]byte("ip"), []byte("10.0.0.5"), []byte("port"), []byte("6379")},
+	}
+	replies := []interface{}{reply, reply}
+	logger := &leveledLogger{}
+	addrs, err := queryForSlaves(replyConn{replies: &replies}, "mymaster", true, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[0] != "10.0.0.2:6379" || addrs[1] != "10.0.0.5:6379" {
+		t.Fatalf("expected well-formed replicas, got %v", addrs)
+	}
+	if len(logger.lines) != 3 {
+		t.Fatalf("expected skipped entries logged, got %q", logger.lines)
+	}
+	sentinels, err := queryForSentinels(replyConn{replies: &replies}, "mymaster", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sentinels) != 2 {
+		t.Fatalf("expected well-formed sentinels, got %v", sentinels)
+	}
+}
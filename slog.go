@@ -0,0 +1,59 @@
+package sentinel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// slogKeys renames keys of logged fields to names used in slog records.
+var slogKeys = map[string]string{
+	"master":   "master_name",
+	"old":      "old_addr",
+	"new":      "new_addr",
+	"sentinel": "sentinel_addr",
+}
+
+// SlogLogger returns Logger emitting structured slog records with l, e.g.
+// for master switches with master_name, old_addr and new_addr attributes
+// and for sentinel failures with sentinel_addr attribute.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (sl slogLogger) Debugf(format string, args ...interface{}) {
+	sl.l.Debug(fmt.Sprintf(format, args...))
+}
+
+func (sl slogLogger) Errorf(format string, args ...interface{}) {
+	sl.l.Error(fmt.Sprintf(format, args...))
+}
+
+func (sl slogLogger) logFields(level LogLevel, msg string, keyvals ...interface{}) {
+	attrs := make([]slog.Attr, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		if name, ok := slogKeys[key]; ok {
+			key = name
+		}
+		attrs = append(attrs, slog.Any(key, keyvals[i+1]))
+	}
+	sl.l.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+// slogLevel returns slog level corresponding to level.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogInfo:
+		return slog.LevelInfo
+	case LogWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
@@ -0,0 +1,138 @@
+package sentinel
+
+import "time"
+
+// State is a health state of SentinelPool.
+type State int
+
+const (
+	// StateNoMaster means master address has not been resolved yet.
+	StateNoMaster State = iota
+	// StateHealthy means pool is connected to verified or reachable master.
+	StateHealthy
+	// StateDegraded means master or sentinels are failing to respond.
+	StateDegraded
+	// StateFailoverInProgress means pool switched to new master, but has
+	// not connected to it yet.
+	StateFailoverInProgress
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNoMaster:
+		return "no-master"
+	case StateHealthy:
+		return "healthy"
+	case StateDegraded:
+		return "degraded"
+	case StateFailoverInProgress:
+		return "failover-in-progress"
+	}
+	return "unknown"
+}
+
+// maxStateTransitions is how many recent transitions PoolState keeps.
+const maxStateTransitions = 16
+
+// StateTransition is a change of pool state.
+type StateTransition struct {
+	From   State
+	To     State
+	Reason string
+	Time   time.Time
+}
+
+// PoolState is a current state of pool together with recent transitions.
+type PoolState struct {
+	State State
+	// Since is when pool entered State.
+	Since time.Time
+	// Reason is why pool entered State.
+	Reason string
+	// Transitions are most recent transitions, oldest first.
+	Transitions []StateTransition
+}
+
+// PoolState returns current state of pool, driven by master switch monitor,
+// master dials and health checks. See WithHealthCheck.
+func (p *SentinelPool) PoolState() PoolState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	st := p.state
+	st.Transitions = append([]StateTransition(nil), st.Transitions...)
+	return st
+}
+
+// setState moves pool to state unless it is already in it.
+func (sp *SentinelPool) setState(state State, reason string) {
+	sp.mu.RLock()
+	same := sp.state.State == state
+	sp.mu.RUnlock()
+	if same {
+		return
+	}
+	sp.mu.Lock()
+	sp._setState(state, reason)
+	sp.mu.Unlock()
+}
+
+// _setState moves pool to state unless it is already in it.
+// Lock must be held by caller.
+func (sp *SentinelPool) _setState(state State, reason string) {
+	if sp.state.State == state {
+		return
+	}
+	t := StateTransition{
+		From:   sp.state.State,
+		To:     state,
+		Reason: reason,
+		Time:   sp.clock().Now(),
+	}
+	sp.state.State = state
+	sp.state.Since = t.Time
+	sp.state.Reason = reason
+	sp.state.Transitions = append(sp.state.Transitions, t)
+	if n := len(sp.state.Transitions); n > maxStateTransitions {
+		sp.state.Transitions = sp.state.Transitions[n-maxStateTransitions:]
+	}
+	logFields(sp.logger(), sp.opts.topologyLogLevel, "pool state changed",
+		"master", sp.sntl.masterName(), "from", t.From, "to", t.To, "reason", reason)
+	if len(sp.stateHandlers) == 0 {
+		return
+	}
+	sp.stateQueue = append(sp.stateQueue, t)
+	if !sp.notifying {
+		sp.notifying = true
+		go sp.notifyStateChanges()
+	}
+}
+
+// OnStateChange registers f to be called with every transition of PoolState,
+// e.g. to shed load when pool degrades. Handlers are called one at a time
+// from a separate goroutine in order transitions happened, so they must not
+// block for long.
+func (p *SentinelPool) OnStateChange(f func(StateTransition)) {
+	p.mu.Lock()
+	p.stateHandlers = append(p.stateHandlers, f)
+	p.mu.Unlock()
+}
+
+// notifyStateChanges calls state handlers with queued transitions until
+// queue is empty.
+func (sp *SentinelPool) notifyStateChanges() {
+	for {
+		sp.mu.Lock()
+		if len(sp.stateQueue) == 0 {
+			sp.notifying = false
+			sp.mu.Unlock()
+			return
+		}
+		t := sp.stateQueue[0]
+		sp.stateQueue = sp.stateQueue[1:]
+		handlers := sp.stateHandlers
+		sp.mu.Unlock()
+		for _, f := range handlers {
+			sp._runRecovered("state handler", func() { f(t) })
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package sentinel
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPoolState(t *testing.T) {
+	sp := &SentinelPool{
+		sntl: &Sentinel{MasterName: "mymaster"},
+		mu:   &sync.RWMutex{},
+		opts: poolOptions{topologyLogLevel: LogOff},
+	}
+	if st := sp.PoolState(); st.State != StateNoMaster {
+		t.Fatalf("unexpected initial state %v", st.State)
+	}
+
+	sp._adoptMaster("10.0.0.1:6379", "initial")
+	sp._adoptMaster("10.0.0.2:6379", "switch-master")
+	sp.setState(StateDegraded, "dial failed")
+	sp.setState(StateDegraded, "dial failed")
+	sp.setState(StateHealthy, "connected")
+
+	st := sp.PoolState()
+	if st.State != StateHealthy || st.Reason != "connected" || st.Since.IsZero() {
+		t.Fatalf("unexpected state %+v", st)
+	}
+	want := []State{StateHealthy, StateFailoverInProgress, StateDegraded, StateHealthy}
+	if len(st.Transitions) != len(want) {
+		t.Fatalf("unexpected transitions %+v", st.Transitions)
+	}
+	for i, tr := range st.Transitions {
+		if tr.To != want[i] {
+			t.Fatalf("unexpected transition %d: %+v", i, tr)
+		}
+	}
+
+	for i := 0; i < maxStateTransitions; i++ {
+		sp.setState(StateDegraded, "dial failed")
+		sp.setState(StateHealthy, "connected")
+	}
+	if n := len(sp.PoolState().Transitions); n != maxStateTransitions {
+		t.Fatalf("expected %d transitions kept, got %d", maxStateTransitions, n)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	sp := &SentinelPool{
+		sntl: &Sentinel{MasterName: "mymaster"},
+		mu:   &sync.RWMutex{},
+		opts: poolOptions{topologyLogLevel: LogOff},
+	}
+	transitions := make(chan StateTransition, 3)
+	sp.OnStateChange(func(t StateTransition) { transitions <- t })
+	sp.OnStateChange(func(StateTransition) { panic("handler failed") })
+
+	sp.setState(StateHealthy, "initial")
+	sp.setState(StateDegraded, "dial failed")
+	sp.setState(StateHealthy, "connected")
+
+	for _, want := range []State{StateHealthy, StateDegraded, StateHealthy} {
+		if tr := <-transitions; tr.To != want {
+			t.Fatalf("unexpected transition %+v, expected to %v", tr, want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	roleMaster  = "master"
+	roleReplica = "replica"
+)
+
+// PoolStats contains counters collected by SentinelPool. It can be
+// marshaled to JSON for debug endpoints, see also SentinelPool.Var.
+type PoolStats struct {
+	// MasterName is a name of master pool connects to.
+	MasterName string `json:"master_name"`
+	// MasterAddr is an address of current master, empty until it is
+	// resolved.
+	MasterAddr string `json:"master_addr"`
+	// MasterCommands is number of commands sent over master connections.
+	MasterCommands uint64 `json:"master_commands"`
+	// ReplicaCommands is number of commands sent over replica connections.
+	ReplicaCommands uint64 `json:"replica_commands"`
+	// AddrCommands is number of commands sent to each node address.
+	AddrCommands map[string]uint64 `json:"addr_commands"`
+	// MonitorPaused is true while master switching is paused, see Pause.
+	MonitorPaused bool `json:"monitor_paused"`
+	// DroppedEvents is a number of Sentinel notifications monitor discarded:
+	// master switches superseded by newer ones before they were processed
+	// and instance events dropped because their handling did not keep up.
+	DroppedEvents uint64 `json:"dropped_events"`
+	// Sentinels is a scoreboard of known sentinels in the order they are
+	// tried, see WithSentinelHealthCheck.
+	Sentinels []SentinelNode `json:"sentinels"`
+	// SentinelPools are counters of connection pools to sentinels, see
+	// Sentinel.PoolStats.
+	SentinelPools map[string]SentinelPoolStats `json:"sentinel_pools"`
+	// MasterSwitches is a number of times pool switched from one master to
+	// another.
+	MasterSwitches uint64 `json:"master_switches"`
+	// LastMasterSwitch is when pool last switched master, zero if it never
+	// did.
+	LastMasterSwitch time.Time `json:"last_master_switch"`
+	// SubscriptionReconnects is a number of times watch of master switch
+	// events was restarted after subscription was lost.
+	SubscriptionReconnects uint64 `json:"subscription_reconnects"`
+	// ActiveCount and IdleCount describe master connection pool as it is
+	// now.
+	ActiveCount int `json:"active_count"`
+	IdleCount   int `json:"idle_count"`
+}
+
+// commandCounter counts commands by node role and address.
+type commandCounter struct {
+	mu      sync.Mutex
+	master  uint64
+	replica uint64
+	byAddr  map[string]uint64
+}
+
+func (c *commandCounter) add(role, addr string) {
+	c.mu.Lock()
+	switch role {
+	case roleMaster:
+		c.master++
+	case roleReplica:
+		c.replica++
+	}
+	if c.byAddr == nil {
+		c.byAddr = make(map[string]uint64)
+	}
+	c.byAddr[addr]++
+	c.mu.Unlock()
+}
+
+func (c *commandCounter) fill(st *PoolStats) {
+	c.mu.Lock()
+	st.MasterCommands = c.master
+	st.ReplicaCommands = c.replica
+	st.AddrCommands = make(map[string]uint64, len(c.byAddr))
+	for addr, n := range c.byAddr {
+		st.AddrCommands[addr] = n
+	}
+	c.mu.Unlock()
+}
+
+// countingConn counts every command sent over wrapped connection. Master
+// connection which got READONLY reply reports errDemoted, so pool discards
+// it instead of handing it out again.
+type countingConn struct {
+	redis.Conn
+	counter *commandCounter
+	role    string
+	addr    string
+	demoted bool
+}
+
+// errDemoted is error of master connection to instance demoted to replica.
+var errDemoted = errors.New("redigo: connection to demoted master")
+
+func (c *countingConn) count(cmd string) {
+	// empty command only flushes pending replies
+	if cmd != "" {
+		c.counter.add(c.role, c.addr)
+	}
+}
+
+// checkDemoted remembers master connection got READONLY reply.
+func (c *countingConn) checkDemoted(reply interface{}, err error) (interface{}, error) {
+	if c.role == roleMaster && isReadOnly(err) {
+		c.demoted = true
+	}
+	return reply, err
+}
+
+func (c *countingConn) Err() error {
+	if c.demoted {
+		return errDemoted
+	}
+	return c.Conn.Err()
+}
+
+func (c *countingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.count(cmd)
+	return c.checkDemoted(c.Conn.Do(cmd, args...))
+}
+
+func (c *countingConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	c.count(cmd)
+	return c.checkDemoted(redis.DoWithTimeout(c.Conn, timeout, cmd, args...))
+}
+
+func (c *countingConn) Send(cmd string, args ...interface{}) error {
+	c.count(cmd)
+	return c.Conn.Send(cmd, args...)
+}
+
+func (c *countingConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func (c *countingConn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if ctx.Value(uncountedKey{}) == nil {
+		c.count(cmd)
+	}
+	return c.checkDemoted(redis.DoContext(c.Conn, ctx, cmd, args...))
+}
+
+func (c *countingConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return redis.ReceiveContext(c.Conn, ctx)
+}
+
+var (
+	_ redis.ConnWithTimeout = (*countingConn)(nil)
+	_ redis.ConnWithContext = (*countingConn)(nil)
+)
+
+// uncountedKey marks context of commands countingConn does not count.
+type uncountedKey struct{}
+
+// uncountedConn sends commands over pooled connection without counting
+// them, e.g. ROLE issued by pool rather than application. Commands are
+// aborted once ctx is done.
+type uncountedConn struct {
+	redis.Conn
+	ctx context.Context
+}
+
+// uncounted returns c with commands sent by Do not counted in Stats.
+func uncounted(ctx context.Context, c redis.Conn) redis.Conn {
+	return uncountedConn{Conn: c, ctx: context.WithValue(ctx, uncountedKey{}, true)}
+}
+
+func (c uncountedConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoContext(c.Conn, c.ctx, cmd, args...)
+}
+
+// Stats returns snapshot of pool counters.
+func (p *SentinelPool) Stats() PoolStats {
+	var st PoolStats
+	p.commands.fill(&st)
+	st.MasterName = p.sntl.masterName()
+	st.Sentinels = p.sntl.Nodes()
+	st.SentinelPools = p.sntl.PoolStats()
+	p.mu.RLock()
+	st.MonitorPaused = p.paused
+	st.MasterAddr = p.curAddr
+	st.MasterSwitches = p.switches
+	st.LastMasterSwitch = p.lastSwitch
+	st.SubscriptionReconnects = p.resubscribes
+	st.ActiveCount = p.pool.ActiveCount()
+	st.IdleCount = p.pool.IdleCount()
+	st.DroppedEvents = p.droppedEvents
+	if p.masterWatcher != nil {
+		st.DroppedEvents += p.masterWatcher.Dropped()
+	}
+	p.mu.RUnlock()
+	return st
+}
+
+// Var returns expvar variable reporting Stats of pool as JSON, e.g. to
+// publish it with expvar.Publish("redis", pool.Var()).
+func (p *SentinelPool) Var() expvar.Var {
+	return expvar.Func(func() interface{} {
+		return p.Stats()
+	})
+}
+
+// SentinelPoolStats contains counters of connection pool to single Sentinel.
+type SentinelPoolStats struct {
+	// Hits is a number of connections taken from pool without dialing.
+	Hits uint64 `json:"hits"`
+	// Misses is a number of connections which had to be dialed.
+	Misses uint64 `json:"misses"`
+	// Errors is a number of failed dials and requests.
+	Errors uint64 `json:"errors"`
+	// DialErrors is a number of failed dials.
+	DialErrors uint64 `json:"dial_errors"`
+	// Requests is a number of requests sent to Sentinel.
+	Requests uint64 `json:"requests"`
+	// Demotions is a number of times Sentinel was moved to the bottom of
+	// address list after it failed to answer.
+	Demotions uint64 `json:"demotions"`
+	// TotalLatency is a summary duration of requests sent to Sentinel.
+	TotalLatency time.Duration `json:"total_latency"`
+	// ActiveCount and IdleCount describe pool as it is now; both are zero if
+	// pool was closed after a failure and not yet recreated.
+	ActiveCount int `json:"active_count"`
+	IdleCount   int `json:"idle_count"`
+}
+
+// sentinelPoolMetrics collects counters of connection pool to single
+// Sentinel. They survive pool being recreated after failures.
+type sentinelPoolMetrics struct {
+	gets       uint64
+	dials      uint64
+	errors     uint64
+	dialErrors uint64
+	requests   uint64
+	demotions  uint64
+	latency    int64
+}
+
+func (m *sentinelPoolMetrics) countGet() {
+	atomic.AddUint64(&m.gets, 1)
+}
+
+func (m *sentinelPoolMetrics) countDial(err error) {
+	atomic.AddUint64(&m.dials, 1)
+	if err != nil {
+		atomic.AddUint64(&m.errors, 1)
+		atomic.AddUint64(&m.dialErrors, 1)
+	}
+}
+
+func (m *sentinelPoolMetrics) countRequest(latency time.Duration, err error) {
+	atomic.AddUint64(&m.requests, 1)
+	atomic.AddInt64(&m.latency, int64(latency))
+	if err != nil {
+		atomic.AddUint64(&m.errors, 1)
+	}
+}
+
+func (m *sentinelPoolMetrics) stats() SentinelPoolStats {
+	gets := atomic.LoadUint64(&m.gets)
+	dials := atomic.LoadUint64(&m.dials)
+	st := SentinelPoolStats{
+		Misses:       dials,
+		Errors:       atomic.LoadUint64(&m.errors),
+		DialErrors:   atomic.LoadUint64(&m.dialErrors),
+		Requests:     atomic.LoadUint64(&m.requests),
+		Demotions:    atomic.LoadUint64(&m.demotions),
+		TotalLatency: time.Duration(atomic.LoadInt64(&m.latency)),
+	}
+	if gets > dials {
+		st.Hits = gets - dials
+	}
+	return st
+}
+
+// metricsFor returns metrics of pool to Sentinel on addr, creating them if
+// needed.
+func (s *Sentinel) metricsFor(addr string) *sentinelPoolMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addrMetrics(addr)
+}
+
+// addrMetrics is like metricsFor.
+//
+// Lock must be held by caller.
+func (s *Sentinel) addrMetrics(addr string) *sentinelPoolMetrics {
+	if s.metrics == nil {
+		s.metrics = make(map[string]*sentinelPoolMetrics)
+	}
+	m, ok := s.metrics[addr]
+	if !ok {
+		m = &sentinelPoolMetrics{}
+		s.metrics[addr] = m
+	}
+	return m
+}
+
+// PoolStats returns counters of connection pools to every Sentinel client
+// has talked to, keyed by Sentinel address.
+func (s *Sentinel) PoolStats() map[string]SentinelPoolStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := make(map[string]SentinelPoolStats, len(s.metrics))
+	for addr, m := range s.metrics {
+		st := m.stats()
+		if pool, ok := s.pools[addr]; ok {
+			st.ActiveCount = pool.ActiveCount()
+			st.IdleCount = pool.IdleCount()
+		}
+		res[addr] = st
+	}
+	return res
+}
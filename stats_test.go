@@ -0,0 +1,110 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// nopConn is a redis.Conn which accepts every command and replies nil.
+type nopConn struct{}
+
+func (nopConn) Close() error                                   { return nil }
+func (nopConn) Err() error                                     { return nil }
+func (nopConn) Do(string, ...interface{}) (interface{}, error) { return nil, nil }
+func (nopConn) Send(string, ...interface{}) error              { return nil }
+func (nopConn) Flush() error                                   { return nil }
+func (nopConn) Receive() (interface{}, error)                  { return nil, nil }
+
+var _ redis.Conn = nopConn{}
+
+func TestCommandCounter(t *testing.T) {
+	counter := &commandCounter{}
+	master := &countingConn{Conn: nopConn{}, counter: counter, role: roleMaster, addr: "10.0.0.1:6379"}
+	replica := &countingConn{Conn: nopConn{}, counter: counter, role: roleReplica, addr: "10.0.0.2:6379"}
+
+	master.Do("SET", "k", "v")
+	master.Send("INCR", "k")
+	master.Do("")
+	replica.Do("GET", "k")
+
+	var st PoolStats
+	counter.fill(&st)
+	if st.MasterCommands != 2 || st.ReplicaCommands != 1 {
+		t.Fatalf("unexpected role counters: %+v", st)
+	}
+	if st.AddrCommands["10.0.0.1:6379"] != 2 || st.AddrCommands["10.0.0.2:6379"] != 1 {
+		t.Fatalf("unexpected address counters: %v", st.AddrCommands)
+	}
+}
+
+func TestSentinelPoolVar(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+
+	sp := NewSentinelPool(append([]string{deadAddr}, cluster.SentinelAddrs()...), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff))
+	defer sp.Close()
+
+	var st PoolStats
+	if err := json.Unmarshal([]byte(sp.Var().String()), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.MasterName != "mymaster" || st.MasterAddr != cluster.Master.Addr() {
+		t.Fatalf("unexpected master %s at %s", st.MasterName, st.MasterAddr)
+	}
+	if dead := st.SentinelPools[deadAddr]; dead.Demotions != 1 || dead.Requests != 1 {
+		t.Fatalf("unexpected stats of failed sentinel %+v", dead)
+	}
+	if live := st.SentinelPools[cluster.Sentinels[0].Addr()]; live.Demotions != 0 || live.Requests == 0 {
+		t.Fatalf("unexpected stats of live sentinel %+v", live)
+	}
+}
+
+func TestSentinelPoolStats(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+	liveAddr := cluster.Sentinels[0].Addr()
+
+	sntl := NewSentinel([]string{deadAddr, liveAddr}, "mymaster")
+	defer sntl.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := sntl.MasterAddr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st := sntl.PoolStats()
+	// failed sentinel is demoted, so it is only tried once
+	dead := st[deadAddr]
+	if dead.Misses != 1 || dead.Hits != 0 || dead.DialErrors != 1 || dead.Errors == 0 ||
+		dead.Demotions != 1 || dead.ActiveCount != 0 {
+		t.Fatalf("unexpected stats of failed sentinel %+v", dead)
+	}
+	// connection is dialed once and reused for following requests
+	live := st[liveAddr]
+	if live.Misses != 1 || live.Hits != 2 || live.Requests != 3 || live.Errors != 0 ||
+		live.DialErrors != 0 || live.TotalLatency <= 0 || live.IdleCount != 1 {
+		t.Fatalf("unexpected stats of live sentinel %+v", live)
+	}
+}
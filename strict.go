@@ -0,0 +1,94 @@
+package sentinel
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NotMaster is returned in strict consistency mode when connection about to
+// be handed out is not connected to current master.
+type NotMaster struct {
+	Addr string
+	// Role is a role instance reported, empty if Addr is no longer
+	// address pool dials.
+	Role string
+}
+
+func (nm NotMaster) Error() string {
+	if nm.Role == "" {
+		return fmt.Sprintf("redigo: %s is no longer current master", nm.Addr)
+	}
+	return fmt.Sprintf("redigo: %s is not a master, its role is %s", nm.Addr, nm.Role)
+}
+
+// roleCache remembers address whose master role was verified recently.
+type roleCache struct {
+	mu   sync.Mutex
+	addr string
+	at   time.Time
+}
+
+func (rc *roleCache) fresh(addr string, ttl time.Duration, now time.Time) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.addr == addr && now.Sub(rc.at) < ttl
+}
+
+func (rc *roleCache) store(addr string, now time.Time) {
+	rc.mu.Lock()
+	rc.addr = addr
+	rc.at = now
+	rc.mu.Unlock()
+}
+
+// verifyRole checks that c connected to addr talks to current master. ROLE
+// is queried unless it was verified for addr within strict consistency
+// interval.
+func (sp *SentinelPool) verifyRole(c redis.Conn, addr string) error {
+	sp.mu.RLock()
+	cur := sp.curAddr
+	sp.mu.RUnlock()
+	if addr != cur {
+		return NotMaster{Addr: addr}
+	}
+	now := sp.clock().Now()
+	if sp.roles.fresh(addr, sp.opts.strictInterval, now) {
+		return nil
+	}
+	role, err := getRole(c)
+	if err != nil {
+		return err
+	}
+	if role != "master" {
+		return NotMaster{Addr: addr, Role: role}
+	}
+	sp.roles.store(addr, now)
+	return nil
+}
+
+// testOnBorrow verifies role of idle connection before it is reused.
+func (sp *SentinelPool) testOnBorrow(c redis.Conn, _ time.Time) error {
+	cc, ok := c.(*countingConn)
+	if !ok {
+		return nil
+	}
+	// bypass counting, ROLE is not a command issued by application
+	return sp.verifyRole(cc.Conn, cc.addr)
+}
+
+// testRoleOnBorrow discards idle connection to instance which is no longer
+// a master, see WithRoleCheckOnBorrow.
+func testRoleOnBorrow(c redis.Conn, _ time.Time) error {
+	var addr string
+	if cc, ok := c.(*countingConn); ok {
+		// bypass counting, ROLE is not a command issued by application
+		c, addr = cc.Conn, cc.addr
+	}
+	if !TestRole(c, "master") {
+		return NotMaster{Addr: addr}
+	}
+	return nil
+}
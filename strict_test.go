@@ -0,0 +1,53 @@
+package sentinel
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerifyRole(t *testing.T) {
+	sp := &SentinelPool{
+		mu:      &sync.RWMutex{},
+		opts:    poolOptions{strict: true, strictInterval: time.Minute},
+		curAddr: "10.0.0.1:6379",
+	}
+	replies := []interface{}{
+		[]interface{}{[]byte("master"), int64(0), []interface{}{}},
+	}
+	c := &countingConn{Conn: replyConn{replies: &replies}, addr: "10.0.0.1:6379"}
+	if err := sp.testOnBorrow(c, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	// cached role is used, no reply is left to query
+	if err := sp.testOnBorrow(c, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := &countingConn{Conn: replyConn{replies: &replies}, addr: "10.0.0.2:6379"}
+	if err, ok := sp.testOnBorrow(stale, time.Time{}).(NotMaster); !ok || err.Role != "" {
+		t.Fatalf("unexpected error %v for stale address", err)
+	}
+
+	sp.roles = roleCache{}
+	replies = []interface{}{
+		[]interface{}{[]byte("slave"), []byte("10.0.0.2"), int64(6379), []byte("connected"), int64(0)},
+	}
+	if err, ok := sp.testOnBorrow(c, time.Time{}).(NotMaster); !ok || err.Role != "slave" {
+		t.Fatalf("unexpected error %v for demoted master", err)
+	}
+}
+
+func TestRoleOnBorrow(t *testing.T) {
+	replies := []interface{}{
+		[]interface{}{[]byte("master"), int64(0), []interface{}{}},
+		[]interface{}{[]byte("slave"), []byte("10.0.0.2"), int64(6379), []byte("connected"), int64(0)},
+	}
+	c := &countingConn{Conn: replyConn{replies: &replies}, addr: "10.0.0.1:6379"}
+	if err := testRoleOnBorrow(c, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err, ok := testRoleOnBorrow(c, time.Time{}).(NotMaster); !ok || err.Addr != "10.0.0.1:6379" {
+		t.Fatalf("unexpected error %v for demoted master", err)
+	}
+}
@@ -0,0 +1,124 @@
+package sentinel
+
+import "sync/atomic"
+
+// ParsedEvent is an event delivered by SubscribeEvents with payload parsed
+// according to its channel. At most one of Instance, Switch and Vote is
+// set; none is for channels without known payload format, e.g. +tilt.
+type ParsedEvent struct {
+	Event
+	// Instance is set for events about single instance, e.g. +sdown.
+	Instance *InstanceEvent
+	// Switch is set for +switch-master.
+	Switch *SwitchMasterEvent
+	// Vote is set for +vote-for-leader.
+	Vote *VoteForLeaderEvent
+}
+
+// masterName returns name of master event is about, empty if it does not
+// name one.
+func (ev ParsedEvent) masterName() string {
+	switch {
+	case ev.Instance != nil:
+		return ev.Instance.MasterName
+	case ev.Switch != nil:
+		return ev.Switch.MasterName
+	}
+	return ""
+}
+
+// parseEvent parses payload of ev. Malformed payload is reported as error.
+func parseEvent(ev Event) (ParsedEvent, error) {
+	pe := ParsedEvent{Event: ev}
+	switch ev.Channel {
+	case ChannelSwitchMaster:
+		sw, err := ParseSwitchMaster(ev.Payload)
+		if err != nil {
+			return pe, err
+		}
+		pe.Switch = &sw
+	case ChannelVoteForLeader:
+		vote, err := ParseVoteForLeader(ev.Payload)
+		if err != nil {
+			return pe, err
+		}
+		pe.Vote = &vote
+	case ChannelNewEpoch, ChannelTilt, ChannelTiltCleared:
+	default:
+		e, err := ParseInstanceEvent(ev.Payload)
+		if err != nil {
+			return pe, err
+		}
+		pe.Instance = &e
+	}
+	return pe, nil
+}
+
+// EventSubscription is a subscription made with SubscribeEvents.
+type EventSubscription struct {
+	// C receives subscribed events. Up to 64 events are buffered for slow
+	// receiver, further ones are dropped. It is closed when subscription
+	// is closed or fails.
+	C <-chan ParsedEvent
+
+	ms *MasterSentinel
+}
+
+// SubscribeEvents makes a new subscription to events of eventTypes, which
+// are Sentinel event channels such as +sdown or +switch-master, or to
+// every event if none is given. Events naming another master than
+// MasterName are filtered out, ones about Sentinel itself like +tilt are
+// not, and so is replay of known master Events delivers. Every call makes
+// independent subscription over its own connection, which must be closed
+// after use.
+func (s *Sentinel) SubscribeEvents(eventTypes ...string) (*EventSubscription, error) {
+	ms, err := s.subscribe(eventTypes, len(eventTypes) == 0)
+	if err != nil {
+		return nil, err
+	}
+	events, err := ms.Events()
+	if err != nil {
+		ms.Close()
+		return nil, err
+	}
+	ch := make(chan ParsedEvent, eventBufferSize)
+	go func() {
+		defer close(ch)
+		for ev := range events {
+			if ev.Replay {
+				continue
+			}
+			pe, err := parseEvent(ev)
+			if err != nil {
+				logFields(s.Logger, LogWarn, "malformed sentinel event",
+					"channel", ev.Channel, "payload", ev.Payload, "err", err)
+				continue
+			}
+			if name := pe.masterName(); name != "" && name != ms.masterName {
+				continue
+			}
+			select {
+			case ch <- pe:
+			default:
+				atomic.AddUint64(&ms.dropped, 1)
+			}
+		}
+	}()
+	return &EventSubscription{C: ch, ms: ms}, nil
+}
+
+// Close closes subscription.
+func (es *EventSubscription) Close() error {
+	return es.ms.Close()
+}
+
+// Err returns an error subscription failed with, see MasterSentinel.Err.
+func (es *EventSubscription) Err() error {
+	return es.ms.Err()
+}
+
+// Dropped returns number of events discarded because receiver did not keep
+// up.
+func (es *EventSubscription) Dropped() uint64 {
+	return es.ms.Dropped()
+}
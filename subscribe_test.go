@@ -0,0 +1,79 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+)
+
+// receiveParsed publishes on sentinel until subscription receives event,
+// since subscription may not be active yet when it is returned.
+func receiveParsed(t *testing.T, sntl *sentineltest.Sentinel, sub *EventSubscription, publish func()) ParsedEvent {
+	t.Helper()
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	timeout := time.After(5 * time.Second)
+	for {
+		publish()
+		select {
+		case ev := <-sub.C:
+			return ev
+		case <-tick.C:
+		case <-timeout:
+			t.Fatal("event was not received")
+		}
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	s := NewSentinel(cluster.SentinelAddrs(), "mymaster")
+	defer s.Close()
+
+	sdown, err := s.SubscribeEvents(ChannelSDown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdown.Close()
+	all, err := s.SubscribeEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer all.Close()
+
+	sntl := cluster.Sentinels[0]
+	ev := receiveParsed(t, sntl, sdown, func() {
+		sntl.Publish(ChannelSDown, "master othermaster 10.0.0.5 6379")
+		sntl.Publish(ChannelODown, "master mymaster 10.0.0.1 6379 #quorum 1/1")
+		sntl.Publish(ChannelSDown, "master mymaster 10.0.0.1 6379")
+	})
+	if ev.Channel != ChannelSDown || ev.Instance == nil || ev.Instance.MasterName != "mymaster" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	// events published above may be received too
+	for ev.Channel != ChannelVoteForLeader {
+		ev = receiveParsed(t, sntl, all, func() {
+			sntl.Publish(ChannelSwitchMaster, "othermaster 10.0.0.5 6379 10.0.0.6 6379")
+			sntl.Publish(ChannelVoteForLeader, "0123abcd 7")
+		})
+		if ev.masterName() != "" && ev.masterName() != "mymaster" {
+			t.Fatalf("event of another master %+v", ev)
+		}
+	}
+	if ev.Vote == nil || ev.Vote.Epoch != 7 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	sdown.Close()
+	for range sdown.C {
+	}
+	if sdown.Err() != nil {
+		t.Fatalf("unexpected error %v", sdown.Err())
+	}
+}
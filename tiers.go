@@ -0,0 +1,32 @@
+package sentinel
+
+import (
+	"sort"
+)
+
+// tieredAddrs returns Addrs ordered by tier, keeping order of addresses in
+// the same tier. Without Tiers it returns Addrs as is.
+//
+// Lock must be held by caller.
+func (s *Sentinel) tieredAddrs() []string {
+	if len(s.Tiers) == 0 {
+		return s.Addrs
+	}
+	addrs := make([]string, len(s.Addrs))
+	copy(addrs, s.Addrs)
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return s.tier(addrs[i]) < s.tier(addrs[j])
+	})
+	return addrs
+}
+
+// tier returns index of tier addr belongs to. Addresses not listed in any
+// tier go after all tiers.
+func (s *Sentinel) tier(addr string) int {
+	for i, tier := range s.Tiers {
+		if stringInSlice(addr, tier) {
+			return i
+		}
+	}
+	return len(s.Tiers)
+}
@@ -0,0 +1,105 @@
+package sentinel
+
+import (
+	"strconv"
+	"time"
+)
+
+// Topology is a snapshot of master group as known to client. It can be
+// marshaled, e.g. to JSON, and imported by restarting process or its
+// replacement to start with a warm view instead of rediscovering it.
+type Topology struct {
+	MasterName string `json:"master_name"`
+	// Master is an address of master, empty if not resolved yet.
+	Master string `json:"master,omitempty"`
+	// ConfigEpoch is a master configuration epoch, zero if not known.
+	ConfigEpoch int64          `json:"config_epoch,omitempty"`
+	Replicas    []string       `json:"replicas,omitempty"`
+	Sentinels   []SentinelNode `json:"sentinels"`
+	Time        time.Time      `json:"time"`
+}
+
+// Export returns topology client currently knows of, without contacting
+// sentinels.
+func (s *Sentinel) Export() Topology {
+	sentinels := s.Nodes()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Topology{
+		MasterName:  s.MasterName,
+		Master:      s.master,
+		ConfigEpoch: s.epoch,
+		Replicas:    append([]string(nil), s.slaves...),
+		Sentinels:   sentinels,
+		Time:        time.Now(),
+	}
+}
+
+// Import merges topology exported before into what client knows. Imported
+// sentinels are tried first, followed by sentinels known before. Master and
+// replicas are only imported if topology is of the same master name and not
+// older than what client knows.
+func (s *Sentinel) Import(t Topology) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addrs := make([]string, 0, len(t.Sentinels)+len(s.Addrs))
+	for _, node := range t.Sentinels {
+		if stringInSlice(node.Addr, addrs) {
+			continue
+		}
+		addrs = append(addrs, node.Addr)
+		known := s.node(node.Addr)
+		if known.RunID == "" {
+			known.RunID = node.RunID
+		}
+		if known.Flags == "" {
+			known.Flags = node.Flags
+		}
+	}
+	for _, addr := range s.Addrs {
+		if !stringInSlice(addr, addrs) {
+			addrs = append(addrs, addr)
+		}
+	}
+	s.Addrs = addrs
+	s.dedupeByRunID()
+
+	if t.MasterName != s.MasterName || t.ConfigEpoch < s.epoch {
+		return
+	}
+	if t.Master != "" {
+		s._setMaster(t.Master, "")
+	}
+	s.epoch = t.ConfigEpoch
+	s.slaves = append([]string(nil), t.Replicas...)
+}
+
+// Export returns topology of pool master group, see Sentinel.Export.
+func (p *SentinelPool) Export() Topology {
+	t := p.sntl.Export()
+	if addr := p.MasterAddr(); addr != "" {
+		t.Master = addr
+	}
+	return t
+}
+
+// configEpoch returns the newest configuration epoch of master seen so far,
+// zero if none was.
+func (s *Sentinel) configEpoch() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.epoch
+}
+
+// recordEpoch remembers configuration epoch from SENTINEL MASTER reply.
+func (s *Sentinel) recordEpoch(masterName string, state map[string]string) {
+	epoch, err := strconv.ParseInt(state["config-epoch"], 10, 64)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	if masterName == s.MasterName && epoch > s.epoch {
+		s.epoch = epoch
+	}
+	s.mu.Unlock()
+}
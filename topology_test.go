@@ -0,0 +1,42 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestTopologyExportImport(t *testing.T) {
+	st := &Sentinel{Addrs: []string{"s1:26379", "s2:26379"}, MasterName: "mymaster"}
+	st.setRunID("s1:26379", "aaa")
+	st.master = "10.0.0.1:6379"
+	st.epoch = 3
+	st.slaves = []string{"10.0.0.2:6379"}
+
+	data, err := json.Marshal(st.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var topology Topology
+	if err := json.Unmarshal(data, &topology); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := &Sentinel{Addrs: []string{"s3:26379"}, MasterName: "mymaster"}
+	restarted.Import(topology)
+	expected := []string{"s1:26379", "s2:26379", "s3:26379"}
+	if fmt.Sprint(restarted.Addrs) != fmt.Sprint(expected) {
+		t.Fatalf("expected sentinels %v, got %v", expected, restarted.Addrs)
+	}
+	if restarted.master != "10.0.0.1:6379" || restarted.epoch != 3 ||
+		restarted.runID("s1:26379") != "aaa" || len(restarted.slaves) != 1 {
+		t.Fatalf("topology not imported: %+v", restarted.Export())
+	}
+
+	topology.ConfigEpoch = 2
+	topology.Master = "10.0.0.9:6379"
+	restarted.Import(topology)
+	if restarted.master != "10.0.0.1:6379" {
+		t.Fatal("older topology must not override master")
+	}
+}
@@ -0,0 +1,108 @@
+package sentinel
+
+import (
+	"sync"
+	"time"
+)
+
+// Failover trace span names.
+const (
+	SpanEvent           = "event"
+	SpanVerification    = "verification"
+	SpanPoolInvalidate  = "pool-invalidation"
+	SpanFirstConnection = "first-connection"
+)
+
+// FailoverSpan is a single step of failover handling.
+type FailoverSpan struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns how long step took.
+func (s FailoverSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// FailoverTrace is a timeline of single failover incident as handled by
+// SentinelPool: from switch event received until first successful
+// connection to new master. Spans are children of the whole incident and
+// follow each other in time.
+type FailoverTrace struct {
+	Master  string
+	OldAddr string
+	NewAddr string
+	Start   time.Time
+	End     time.Time
+	Spans   []FailoverSpan
+}
+
+// Duration returns how long failover handling took.
+func (t FailoverTrace) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// failoverTracer records trace of failover in progress. All methods are
+// no-op on nil tracer, which is used when tracing is disabled.
+type failoverTracer struct {
+	mu     sync.Mutex
+	cur    *FailoverTrace
+	report func(FailoverTrace)
+}
+
+// begin starts tracing failover which began at start. Unfinished trace of
+// previous failover is dropped.
+func (t *failoverTracer) begin(master, oldAddr, newAddr string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.cur = &FailoverTrace{
+		Master:  master,
+		OldAddr: oldAddr,
+		NewAddr: newAddr,
+		Start:   start,
+	}
+	t.mu.Unlock()
+}
+
+// span records step of current failover which started at start and ends now.
+func (t *failoverTracer) span(name string, start time.Time) {
+	t.spanUntil(name, start, time.Now())
+}
+
+// spanUntil records step of current failover which lasted from start to end.
+func (t *failoverTracer) spanUntil(name string, start, end time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.cur != nil {
+		t.cur.Spans = append(t.cur.Spans, FailoverSpan{Name: name, Start: start, End: end})
+	}
+	t.mu.Unlock()
+}
+
+// connected finishes current failover trace if addr is a new master.
+func (t *failoverTracer) connected(addr string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	cur := t.cur
+	if cur == nil || cur.NewAddr != addr {
+		t.mu.Unlock()
+		return
+	}
+	t.cur = nil
+	t.mu.Unlock()
+
+	start := cur.Start
+	if n := len(cur.Spans); n > 0 {
+		start = cur.Spans[n-1].End
+	}
+	cur.End = time.Now()
+	cur.Spans = append(cur.Spans, FailoverSpan{Name: SpanFirstConnection, Start: start, End: cur.End})
+	t.report(*cur)
+}
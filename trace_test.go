@@ -0,0 +1,32 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailoverTracer(t *testing.T) {
+	var traces []FailoverTrace
+	tracer := &failoverTracer{report: func(tr FailoverTrace) {
+		traces = append(traces, tr)
+	}}
+
+	tracer.begin("mymaster", "10.0.0.1:6379", "10.0.0.2:6379", time.Now())
+	tracer.span(SpanEvent, time.Now())
+	tracer.connected("10.0.0.1:6379")
+	if len(traces) != 0 {
+		t.Fatal("connection to old master must not finish trace")
+	}
+	tracer.connected("10.0.0.2:6379")
+	if len(traces) != 1 {
+		t.Fatalf("expected one trace, got %d", len(traces))
+	}
+	spans := traces[0].Spans
+	if len(spans) != 2 || spans[0].Name != SpanEvent || spans[1].Name != SpanFirstConnection {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+
+	var disabled *failoverTracer
+	disabled.begin("mymaster", "", "10.0.0.2:6379", time.Now())
+	disabled.connected("10.0.0.2:6379")
+}
@@ -0,0 +1,105 @@
+package sentinel
+
+import (
+	"context"
+	"sync"
+)
+
+// Span names of operations traced with Tracer.
+const (
+	TraceMasterAddr = "sentinel.master_addr"
+	TraceSlaveAddrs = "sentinel.slave_addrs"
+	TraceDiscover   = "sentinel.discover"
+	// TraceQuery is a single attempt to ask one Sentinel, child of one of
+	// spans above.
+	TraceQuery = "sentinel.query"
+	// TraceFailover lasts from master switch event received until first
+	// successful connection to new master.
+	TraceFailover = "sentinel.failover"
+)
+
+// Tracer starts spans around Sentinel queries and failover handling, so
+// distributed traces show why request stalled during failover. See package
+// sentinelotel for OpenTelemetry implementation.
+type Tracer interface {
+	// Start starts span named name as a child of span in ctx, if any.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetAttributes sets attributes given as alternating keys and values,
+	// the same way log fields are given.
+	SetAttributes(kv ...interface{})
+	// End finishes span, marking it failed with err unless it is nil.
+	End(err error)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(kv ...interface{}) {}
+func (noopSpan) End(err error)                   {}
+
+type spanKey struct{}
+
+// startSpan starts span with Tracer, or returns no-op span if it is nil.
+// Span can be found in returned context with spanFromContext.
+func (s *Sentinel) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if s.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := s.Tracer.Start(ctx, name)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// spanFromContext returns span started by startSpan, or no-op span if ctx
+// has none.
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// failoverSpan is TraceFailover span of failover in progress.
+type failoverSpan struct {
+	mu       sync.Mutex
+	span     Span
+	addr     string
+	failures int
+}
+
+// begin starts tracing failover to addr. Span of previous failover, if it
+// is still unfinished, is ended as superseded.
+func (f *failoverSpan) begin(s *Sentinel, oldAddr, addr string) {
+	_, span := s.startSpan(context.Background(), TraceFailover)
+	span.SetAttributes("master", s.masterName(), "old", oldAddr, "new", addr)
+	f.mu.Lock()
+	prev := f.span
+	f.span, f.addr, f.failures = span, addr, 0
+	f.mu.Unlock()
+	if prev != nil {
+		prev.SetAttributes("superseded", true)
+		prev.End(nil)
+	}
+}
+
+// dialed finishes span of failover to addr once dial to it succeeds, and
+// counts failed dials before that.
+func (f *failoverSpan) dialed(addr string, err error) {
+	f.mu.Lock()
+	if f.span == nil || f.addr != addr {
+		f.mu.Unlock()
+		return
+	}
+	if err != nil {
+		f.failures++
+		f.mu.Unlock()
+		return
+	}
+	span, failures := f.span, f.failures
+	f.span = nil
+	f.mu.Unlock()
+	span.SetAttributes("retries", failures)
+	span.End(nil)
+}
@@ -0,0 +1,136 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+// recordedSpan is span recorded by recordingTracer.
+type recordedSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+// recordingTracer records every span it started.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &recordedSpan{name: name, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return ctx, &recordingSpan{t, s}
+}
+
+// named returns copies of spans named name.
+func (t *recordingTracer) named(name string) []recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []recordedSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			spans = append(spans, *s)
+		}
+	}
+	return spans
+}
+
+type recordingSpan struct {
+	t *recordingTracer
+	s *recordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(kv ...interface{}) {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		s.s.attrs[kv[i].(string)] = kv[i+1]
+	}
+}
+
+func (s *recordingSpan) End(err error) {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	s.s.err, s.s.ended = err, true
+}
+
+func TestSentinelTracing(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+
+	tracer := &recordingTracer{}
+	s := NewSentinel(append([]string{deadAddr}, cluster.SentinelAddrs()...), "mymaster")
+	s.Tracer = tracer
+	defer s.Close()
+	if _, err := s.MasterAddr(); err != nil {
+		t.Fatal(err)
+	}
+	spans := tracer.named(TraceMasterAddr)
+	if len(spans) != 1 || !spans[0].ended || spans[0].err != nil {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	attrs := spans[0].attrs
+	if attrs["addr"] != cluster.Master.Addr() || attrs["retries"] != 1 ||
+		attrs["sentinel"] != cluster.Sentinels[0].Addr() {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+	queries := tracer.named(TraceQuery)
+	if len(queries) != 2 || queries[0].err == nil || queries[0].attrs["sentinel"] != deadAddr ||
+		queries[1].err != nil || queries[1].attrs["attempt"] != 2 {
+		t.Fatalf("unexpected queries %+v", queries)
+	}
+}
+
+func TestSentinelPoolFailoverSpan(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	tracer := &recordingTracer{}
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithTracer(tracer))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+	waitWatching(t, sp)
+
+	oldAddr := cluster.Master.Addr()
+	cluster.Failover(cluster.Replicas[0])
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+	if spans := tracer.named(TraceFailover); len(spans) != 1 || spans[0].ended {
+		t.Fatalf("failover must be traced until connected, got %+v", spans)
+	}
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	spans := tracer.named(TraceFailover)
+	if len(spans) != 1 || !spans[0].ended || spans[0].attrs["old"] != oldAddr ||
+		spans[0].attrs["new"] != cluster.Master.Addr() || spans[0].attrs["retries"] != 0 {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+}
@@ -0,0 +1,132 @@
+package sentinel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// validatedFields are SENTINEL MASTER fields all sentinels must agree on.
+var validatedFields = []string{
+	"ip",
+	"port",
+	"quorum",
+	"down-after-milliseconds",
+	"failover-timeout",
+	"parallel-syncs",
+}
+
+// ConfigIssue describes a problem with one Sentinel found by Validate.
+type ConfigIssue struct {
+	// Addr is an address of Sentinel.
+	Addr string
+	// Problem is a human readable description of what is wrong.
+	Problem string
+}
+
+// InconsistentConfig is returned by Validate when some sentinels can not be
+// queried, do not monitor master or disagree with others on its settings.
+type InconsistentConfig struct {
+	MasterName string
+	Issues     []ConfigIssue
+}
+
+func (ic InconsistentConfig) Error() string {
+	problems := make([]string, 0, len(ic.Issues))
+	for _, issue := range ic.Issues {
+		problems = append(problems, fmt.Sprintf("%s: %s", issue.Addr, issue.Problem))
+	}
+	return fmt.Sprintf("redigo: inconsistent sentinel configuration for master %s: %s",
+		ic.MasterName, strings.Join(problems, "; "))
+}
+
+// Validate queries every known Sentinel for master settings and checks they
+// all monitor MasterName with the same address, quorum and timeouts. It is
+// meant to be called at startup to catch misconfigured sentinels early.
+// InconsistentConfig error lists every Sentinel with a problem.
+func (s *Sentinel) Validate() error {
+	masterName := s.masterName()
+	s.mu.RLock()
+	addrs := s.Addrs
+	s.mu.RUnlock()
+	if len(addrs) == 0 {
+		return NoSentinelsAvailable{}
+	}
+
+	var issues []ConfigIssue
+	states := make(map[string]map[string]string)
+	for _, addr := range addrs {
+		if _, err := NormalizeAddr(addr); err != nil {
+			issues = append(issues, ConfigIssue{Addr: addr, Problem: err.Error()})
+			continue
+		}
+		conn := s.get(addr)
+		state, err := queryForMasterState(conn, masterName)
+		conn.Close()
+		if err != nil {
+			problem := err.Error()
+			if isUnknownMaster(err) {
+				problem = "master is not monitored"
+			}
+			issues = append(issues, ConfigIssue{Addr: addr, Problem: problem})
+			continue
+		}
+		states[addr] = state
+		s.recordEpoch(masterName, state)
+	}
+	issues = append(issues, compareMasterStates(addrs, states)...)
+	if len(issues) > 0 {
+		return InconsistentConfig{MasterName: masterName, Issues: issues}
+	}
+	return nil
+}
+
+// compareMasterStates reports sentinels whose value of validated field
+// differs from the value most sentinels have.
+func compareMasterStates(addrs []string, states map[string]map[string]string) []ConfigIssue {
+	var issues []ConfigIssue
+	for _, field := range validatedFields {
+		counts := make(map[string]int)
+		expected, best := "", 0
+		for _, addr := range addrs {
+			state, ok := states[addr]
+			if !ok {
+				continue
+			}
+			v := state[field]
+			counts[v]++
+			if counts[v] > best {
+				expected, best = v, counts[v]
+			}
+		}
+		for _, addr := range addrs {
+			state, ok := states[addr]
+			if !ok || state[field] == expected {
+				continue
+			}
+			issues = append(issues, ConfigIssue{
+				Addr:    addr,
+				Problem: fmt.Sprintf("%s is %q, most sentinels have %q", field, state[field], expected),
+			})
+		}
+	}
+	return issues
+}
+
+func queryForMasterState(conn redis.Conn, masterName string) (map[string]string, error) {
+	return redis.StringMap(conn.Do("SENTINEL", "master", masterName))
+}
+
+// isUnknownMaster reports whether err is Sentinel reply to a query about
+// master it does not monitor.
+func isUnknownMaster(err error) bool {
+	if ns, ok := err.(NoSentinelsAvailable); ok {
+		err = ns.lastError
+	}
+	if err == ErrUnknownMaster {
+		return true
+	}
+	rerr, ok := err.(redis.Error)
+	return ok && strings.Contains(string(rerr), "No such master")
+}
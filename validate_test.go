@@ -0,0 +1,33 @@
+package sentinel
+
+import (
+	"testing"
+)
+
+func TestCompareMasterStates(t *testing.T) {
+	state := func(port, quorum string) map[string]string {
+		return map[string]string{
+			"ip":                      "10.0.0.1",
+			"port":                    port,
+			"quorum":                  quorum,
+			"down-after-milliseconds": "5000",
+			"failover-timeout":        "60000",
+			"parallel-syncs":          "1",
+		}
+	}
+	addrs := []string{"s1:26379", "s2:26379", "s3:26379"}
+	states := map[string]map[string]string{
+		"s1:26379": state("6379", "2"),
+		"s2:26379": state("6379", "3"),
+		"s3:26379": state("6379", "2"),
+	}
+	issues := compareMasterStates(addrs, states)
+	if len(issues) != 1 || issues[0].Addr != "s2:26379" {
+		t.Fatalf("expected single issue for s2, got %v", issues)
+	}
+
+	states["s2:26379"] = state("6379", "2")
+	if issues := compareMasterStates(addrs, states); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
@@ -0,0 +1,70 @@
+package sentinel
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// verifyRetryInterval is delay between ROLE queries of new master which is
+// not ready yet.
+const verifyRetryInterval = 100 * time.Millisecond
+
+// verifyMaster waits until instance on addr reports master role before pool
+// switches to it, querying ROLE until master verification timeout passes.
+// It returns when verification started, zero if it is disabled or addr is
+// already in use, and whether role was verified.
+func (sp *SentinelPool) verifyMaster(addr string) (time.Time, bool) {
+	timeout := sp.opts.verifyTimeout
+	sp.mu.RLock()
+	cur := sp.curAddr
+	sp.mu.RUnlock()
+	if timeout <= 0 || addr == cur {
+		return time.Time{}, false
+	}
+	sp.gate.close()
+	start := sp.clock().Now()
+	deadline := start.Add(timeout)
+	for attempt := 1; ; attempt++ {
+		remaining := deadline.Sub(sp.clock().Now())
+		role, err := sp.queryRole(addr, remaining)
+		if err == nil && role == "master" {
+			sp.roles.store(addr, sp.clock().Now())
+			return start, true
+		}
+		remaining = deadline.Sub(sp.clock().Now())
+		if remaining <= 0 {
+			logFields(sp.logger(), LogWarn, "new master role not verified",
+				"master", sp.sntl.masterName(), "addr", addr, "role", role,
+				"attempts", attempt, "err", err)
+			return start, false
+		}
+		logFields(sp.logger(), LogDebug, "new master not ready",
+			"master", sp.sntl.masterName(), "addr", addr, "role", role, "err", err)
+		delay := verifyRetryInterval
+		if remaining < delay {
+			delay = remaining
+		}
+		if !sp.sleep(delay) {
+			return start, false
+		}
+	}
+}
+
+// queryRole dials instance on addr bypassing the pool and asks it for its
+// role, giving up after timeout.
+func (sp *SentinelPool) queryRole(addr string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return "", context.DeadlineExceeded
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c, err := sp.dialMaster(ctx, addr,
+		redis.DialReadTimeout(timeout), redis.DialWriteTimeout(timeout))
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return getRole(c)
+}
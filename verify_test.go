@@ -0,0 +1,68 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RivenZoo/go-sentinel/sentineltest"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelPoolMasterVerification(t *testing.T) {
+	cluster, err := sentineltest.NewCluster("mymaster", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	traces := make(chan FailoverTrace, 2)
+	sp := NewSentinelPool(cluster.SentinelAddrs(), "mymaster", 0, "",
+		WithTopologyLogLevel(LogOff), WithMasterVerification(300*time.Millisecond),
+		WithFailoverTracing(func(tr FailoverTrace) { traces <- tr }))
+	defer sp.Close()
+	changes := sp.MasterChanges()
+	waitWatching(t, sp)
+
+	// sentinel reports replica which is not promoted yet
+	newMaster := cluster.Replicas[0]
+	cluster.Sentinels[0].SwitchMaster(newMaster)
+	time.Sleep(150 * time.Millisecond)
+	if info := sp.MasterAddrInfo(); info.Addr == newMaster.Addr() {
+		t.Fatal("pool switched to master before its role was verified")
+	}
+	newMaster.Promote()
+	select {
+	case change := <-changes:
+		if change.New != newMaster.Addr() {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not detected")
+	}
+	if info := sp.MasterAddrInfo(); !info.Verified {
+		t.Fatalf("new master must be verified, got %+v", info)
+	}
+	if _, err := Do(sp, redis.String, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	tr := <-traces
+	if len(tr.Spans) != 3 || tr.Spans[1].Name != SpanVerification ||
+		tr.Spans[1].Duration() < 100*time.Millisecond {
+		t.Fatalf("unexpected trace %+v", tr)
+	}
+
+	// replica which is never promoted is adopted after timeout
+	stuck := cluster.Replicas[1]
+	cluster.Sentinels[0].SwitchMaster(stuck)
+	select {
+	case change := <-changes:
+		if change.New != stuck.Addr() {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("master switch was not adopted after verification timeout")
+	}
+	if info := sp.MasterAddrInfo(); info.Verified {
+		t.Fatalf("stuck master must not be verified, got %+v", info)
+	}
+}